@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/password"
+	"github.com/yourusername/ums/backend/internal/session"
+)
+
+const adminUsage = `usage: ums admin [flags] <command>
+
+commands:
+  create-user -username u -email e [-password p] [-role r]...
+                                   create a user; a random password is
+                                   generated and printed if none is given
+  set-roles <username> <role>...   replace a user's roles
+  reset-password <username>        set a new random password and print it
+
+Changing roles or passwords signs the user out everywhere.`
+
+// roleFlags collects repeated -role flags
+type roleFlags []string
+
+func (f *roleFlags) String() string     { return strings.Join(*f, ",") }
+func (f *roleFlags) Set(v string) error { *f = append(*f, v); return nil }
+
+// admin runs `ums admin [flags] <command>`
+func admin(args []string) error {
+	cfg, args, err := loadConfig("admin", args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	users := models.NewPostgresUserRepository(db)
+	roles := models.NewPostgresRoleRepository(db)
+
+	switch args[0] {
+	case "create-user":
+		return adminCreateUser(ctx, users, roles, args[1:])
+	case "set-roles":
+		if len(args) < 3 {
+			return errors.New(adminUsage)
+		}
+		user, err := users.GetByUsername(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		if err := roles.SetUserRoles(ctx, user.ID, args[2:]); err != nil {
+			return err
+		}
+		if err := signOut(ctx, db, user.ID); err != nil {
+			return err
+		}
+		fmt.Printf("set roles of %s to %s\n", user.Username, strings.Join(args[2:], ", "))
+		return nil
+	case "reset-password":
+		if len(args) != 2 {
+			return errors.New(adminUsage)
+		}
+		user, err := users.GetByUsername(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		plain, err := randomPassword()
+		if err != nil {
+			return err
+		}
+		hashed, err := password.Hash(plain)
+		if err != nil {
+			return err
+		}
+		if err := users.UpdatePassword(ctx, user.ID, hashed); err != nil {
+			return err
+		}
+		if err := signOut(ctx, db, user.ID); err != nil {
+			return err
+		}
+		fmt.Printf("new password for %s: %s\n", user.Username, plain)
+		return nil
+	}
+	return errors.New(adminUsage)
+}
+
+func adminCreateUser(ctx context.Context, users models.UserRepository, roleRepo models.RoleRepository, args []string) error {
+	fs := flag.NewFlagSet("admin create-user", flag.ContinueOnError)
+	username := fs.String("username", "", "username")
+	email := fs.String("email", "", "email address")
+	plain := fs.String("password", "", "password (generated if empty)")
+	var roles roleFlags
+	fs.Var(&roles, "role", "role to assign (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" || *email == "" {
+		return errors.New(adminUsage)
+	}
+
+	generated := *plain == ""
+	if generated {
+		var err error
+		if *plain, err = randomPassword(); err != nil {
+			return err
+		}
+	}
+
+	user := models.User{Username: *username, Email: *email, Password: *plain}
+	if err := users.Create(ctx, &user); err != nil {
+		return err
+	}
+	if len(roles) > 0 {
+		if err := roleRepo.SetUserRoles(ctx, user.ID, roles); err != nil {
+			users.Delete(ctx, user.ID)
+			return err
+		}
+	}
+
+	fmt.Printf("created user %s (id %s)\n", user.Username, user.ID)
+	if generated {
+		fmt.Printf("password: %s\n", *plain)
+	}
+	return nil
+}
+
+// signOut ends every session and refresh token of a user
+func signOut(ctx context.Context, db *sql.DB, userID string) error {
+	if err := session.NewPostgresStore(db).RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	return models.NewPostgresRefreshTokenStore(db).RevokeUser(ctx, userID)
+}
+
+// randomPassword returns a random 128-bit password
+func randomPassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/yourusername/ums/backend/internal/config"
+)
+
+const configUsage = `usage: ums config print [-redacted] [flags]
+
+Prints the configuration resolved from defaults, the config file,
+environment and flags as YAML. -redacted masks secrets such as the
+database password.`
+
+// configCommand runs `ums config <command>`
+func configCommand(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New(configUsage)
+	}
+
+	fs := flag.NewFlagSet("ums config print", flag.ContinueOnError)
+	redacted := fs.Bool("redacted", false, "mask secrets")
+	cfg, err := config.Load(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if *redacted {
+		cfg = cfg.Redacted()
+	}
+
+	out, err := cfg.YAML()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
@@ -0,0 +1,95 @@
+// Command ums runs the User Management System.
+//
+//	ums serve                run the API server
+//	ums migrate <command>    manage the database schema
+//	ums admin <command>      manage users from the command line
+//	ums config print         show the resolved configuration
+//
+// Every command accepts the configuration flags listed by `ums serve -h`
+// before its arguments.
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	_ "github.com/lib/pq"
+
+	"github.com/yourusername/ums/backend/internal/config"
+	"github.com/yourusername/ums/backend/internal/logging"
+)
+
+const usage = `usage: ums <command> [arguments]
+
+commands:
+  serve              run the API server
+  migrate <command>  apply, roll back and inspect database migrations
+  admin <command>    create users, assign roles and reset passwords
+  config print       print the resolved configuration
+
+Settings come from defaults, the file named by -config or UMS_CONFIG,
+UMS_* environment variables and flags, in increasing precedence.
+Run "ums serve -h" to list them.`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "serve":
+		err = serve(os.Args[2:])
+	case "migrate":
+		err = migrate(os.Args[2:])
+	case "admin":
+		err = admin(os.Args[2:])
+	case "config":
+		err = configCommand(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// loadConfig resolves the configuration for command name from args and
+// returns it with the arguments left after the flags
+func loadConfig(name string, args []string) (*config.Config, []string, error) {
+	fs := flag.NewFlagSet("ums "+name, flag.ContinueOnError)
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+	logging.Setup(logger)
+	return cfg, fs.Args(), nil
+}
+
+// openDB connects to the configured database
+func openDB(cfg *config.Config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.Database.URL)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connecting to the database: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+
+	umsdb "github.com/yourusername/ums/backend/internal/db"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// foldKeysMigration added the username and email fold keys refoldUserKeys
+// rewrites
+const foldKeysMigration = 10
+
+// migrationHooks are the steps of migrations written in Go
+var migrationHooks = umsdb.Hooks{
+	foldKeysMigration: refoldUserKeys,
+}
+
+// migrate runs `ums migrate [flags] <command>`
+func migrate(args []string) error {
+	cfg, args, err := loadConfig("migrate", args)
+	if err != nil {
+		return err
+	}
+	open := func() (*sql.DB, error) { return openDB(cfg) }
+	return umsdb.MigrateCommand(context.Background(), args, open, migrationHooks, os.Stdout)
+}
+
+// refoldUserKeys rewrites the username and email keys that migration 0010
+// backfilled with SQL lower() instead of models.FoldKey, so every user can be
+// found by name
+func refoldUserKeys(ctx context.Context, tx *sql.Tx) error {
+	n, conflicts, err := models.RefoldKeys(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("rewriting username and email keys: %w", err)
+	}
+	if n > 0 {
+		slog.Info("rewrote username and email keys", "users", n)
+	}
+	for _, c := range conflicts {
+		slog.Warn("username or email clashes with another user after case folding; rename one of them",
+			"user_id", c.ID, "username", c.Username, "error", c.Err)
+	}
+	return nil
+}
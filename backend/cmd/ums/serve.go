@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+
+	umsdb "github.com/yourusername/ums/backend/internal/db"
+	"github.com/yourusername/ums/backend/internal/server"
+)
+
+// serve runs the API server until SIGINT or SIGTERM, then shuts it down
+// gracefully. A second signal during shutdown exits immediately.
+func serve(args []string) error {
+	cfg, _, err := loadConfig("serve", args)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// Restore the default signal handling once shutdown has begun
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := umsdb.MigrateOnStart(ctx, db, cfg.Database.AutoMigrate, migrationHooks); err != nil {
+		return err
+	}
+
+	srv, err := server.New(db, cfg)
+	if err != nil {
+		return err
+	}
+	// db is closed by the deferred Close only after Run has drained every
+	// request and stopped the workers
+	return srv.Run(ctx)
+}
@@ -0,0 +1,140 @@
+// Package apierror defines the typed errors returned by the API and renders
+// them as RFC 7807 problem details (application/problem+json).
+//
+// Every problem carries a stable machine-readable code, such as
+// username_taken or validation_failed, which clients should branch on
+// instead of the human-readable title and detail. Validation failures list
+// the offending fields in errors.
+package apierror
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Code identifies the kind of a problem. Codes are part of the API and do
+// not change once published.
+type Code string
+
+const (
+	CodeBadRequest         Code = "bad_request"
+	CodeValidationFailed   Code = "validation_failed"
+	CodeUnauthorized       Code = "unauthorized"
+	CodeInvalidCredentials Code = "invalid_credentials"
+	CodeInvalidToken       Code = "invalid_token"
+	CodeForbidden          Code = "forbidden"
+	CodeEmailNotVerified   Code = "email_not_verified"
+	CodePasskeyRequired    Code = "passkey_required"
+	CodeNotFound           Code = "not_found"
+	CodeMethodNotAllowed   Code = "method_not_allowed"
+	CodeConflict           Code = "conflict"
+	CodePayloadTooLarge    Code = "payload_too_large"
+	CodeUsernameTaken      Code = "username_taken"
+	CodeEmailTaken         Code = "email_taken"
+	CodeTooManyRequests    Code = "too_many_requests"
+	CodeUnavailable        Code = "service_unavailable"
+	CodeInternal           Code = "internal_error"
+)
+
+// Field-level codes used in FieldError.Code
+const (
+	FieldRequired = "required"
+	FieldInvalid  = "invalid"
+	FieldTooShort = "too_short"
+	FieldTooLong  = "too_long"
+	FieldTaken    = "taken"
+	FieldUnknown  = "unknown"
+)
+
+// FieldError describes why a single request field was rejected. Field is
+// the JSON name of the field or query parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an API error. Status is the HTTP status code and Detail the
+// message shown to the client; Err is the underlying cause, which is logged
+// but never sent.
+type Error struct {
+	Status int
+	Code   Code
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error with the given status, code and detail
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// BadRequest is a malformed request, such as a body that is not valid JSON
+func BadRequest(detail string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, detail)
+}
+
+// Validation is a well-formed request with invalid fields
+func Validation(fields ...FieldError) *Error {
+	return &Error{
+		Status: http.StatusBadRequest,
+		Code:   CodeValidationFailed,
+		Detail: "One or more fields are invalid.",
+		Fields: fields,
+	}
+}
+
+// Field returns a validation error for a single field
+func Field(field, code, message string) *Error {
+	return Validation(FieldError{Field: field, Code: code, Message: message})
+}
+
+// NotFound reports that the resource does not exist, e.g. "User not found."
+func NotFound(detail string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, detail)
+}
+
+// Unauthorized reports missing or invalid credentials
+func Unauthorized(code Code, detail string) *Error {
+	return New(http.StatusUnauthorized, code, detail)
+}
+
+// Forbidden reports that the caller may not perform the action
+func Forbidden(detail string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, detail)
+}
+
+// Conflict reports that the request conflicts with the current state
+func Conflict(code Code, detail string) *Error {
+	return New(http.StatusConflict, code, detail)
+}
+
+// Taken reports that a unique field, such as the username, is already in
+// use. It is a conflict carrying the field so forms can flag it.
+func Taken(code Code, field string) *Error {
+	e := Conflict(code, "The "+field+" is already taken.")
+	e.Fields = []FieldError{{Field: field, Code: FieldTaken, Message: "This " + field + " is already taken."}}
+	return e
+}
+
+// Internal wraps an unexpected error. The client only sees a generic detail.
+func Internal(err error) *Error {
+	return &Error{
+		Status: http.StatusInternalServerError,
+		Code:   CodeInternal,
+		Detail: "An unexpected error occurred.",
+		Err:    err,
+	}
+}
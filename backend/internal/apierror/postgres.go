@@ -0,0 +1,62 @@
+package apierror
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// constraintFields maps unique constraints to the field and code reported
+// when they are violated: the UNIQUE columns of the users table and the
+// case-insensitive keys added next to them.
+var constraintFields = map[string]struct {
+	field string
+	code  Code
+}{
+	"users_username_key":      {"username", CodeUsernameTaken},
+	"users_email_key":         {"email", CodeEmailTaken},
+	"users_username_fold_key": {"username", CodeUsernameTaken},
+	"users_email_fold_key":    {"email", CodeEmailTaken},
+}
+
+// fromPQ maps a Postgres error to an API error by its SQLSTATE, or returns
+// nil if err is not a Postgres error or has no client-facing meaning
+func fromPQ(err error) *Error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return nil
+	}
+
+	var apiErr *Error
+	switch pqErr.Code.Name() {
+	case "unique_violation":
+		if c, ok := constraintFields[pqErr.Constraint]; ok {
+			apiErr = Taken(c.code, c.field)
+		} else {
+			apiErr = Conflict(CodeConflict, "The resource already exists.")
+		}
+	case "foreign_key_violation":
+		apiErr = Conflict(CodeConflict, "The resource is referenced by or refers to a missing resource.")
+	case "not_null_violation":
+		apiErr = columnError(pqErr.Column, FieldRequired, "This field is required.")
+	case "check_violation":
+		apiErr = columnError(pqErr.Column, FieldInvalid, "This value is not allowed.")
+	case "string_data_right_truncation":
+		apiErr = columnError(pqErr.Column, FieldTooLong, "This value is too long.")
+	case "invalid_text_representation":
+		apiErr = BadRequest("A value has an invalid format.")
+	default:
+		return nil
+	}
+	apiErr.Err = err
+	return apiErr
+}
+
+// columnError is a validation error for column, which Postgres does not
+// report for every kind of violation
+func columnError(column, code, message string) *Error {
+	if column == "" {
+		return Validation()
+	}
+	return Field(column, code, message)
+}
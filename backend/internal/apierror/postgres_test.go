@@ -0,0 +1,66 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestFromPQ(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    *pq.Error
+		status int
+		code   Code
+		fields []FieldError
+	}{
+		{"username taken", &pq.Error{Code: "23505", Constraint: "users_username_fold_key"}, http.StatusConflict, CodeUsernameTaken,
+			[]FieldError{{Field: "username", Code: FieldTaken, Message: "This username is already taken."}}},
+		{"email taken", &pq.Error{Code: "23505", Constraint: "users_email_key"}, http.StatusConflict, CodeEmailTaken,
+			[]FieldError{{Field: "email", Code: FieldTaken, Message: "This email is already taken."}}},
+		{"other unique", &pq.Error{Code: "23505", Constraint: "roles_name_key"}, http.StatusConflict, CodeConflict, nil},
+		{"foreign key", &pq.Error{Code: "23503", Constraint: "user_roles_role_id_fkey"}, http.StatusConflict, CodeConflict, nil},
+		{"not null", &pq.Error{Code: "23502", Column: "email"}, http.StatusBadRequest, CodeValidationFailed,
+			[]FieldError{{Field: "email", Code: FieldRequired, Message: "This field is required."}}},
+		{"check", &pq.Error{Code: "23514", Column: "status"}, http.StatusBadRequest, CodeValidationFailed,
+			[]FieldError{{Field: "status", Code: FieldInvalid, Message: "This value is not allowed."}}},
+		{"check without column", &pq.Error{Code: "23514"}, http.StatusBadRequest, CodeValidationFailed, nil},
+		{"too long", &pq.Error{Code: "22001", Column: "username"}, http.StatusBadRequest, CodeValidationFailed,
+			[]FieldError{{Field: "username", Code: FieldTooLong, Message: "This value is too long."}}},
+		{"invalid text", &pq.Error{Code: "22P02"}, http.StatusBadRequest, CodeBadRequest, nil},
+		{"deadlock", &pq.Error{Code: "40P01"}, http.StatusInternalServerError, CodeInternal, nil},
+		{"connection", &pq.Error{Code: "08006"}, http.StatusInternalServerError, CodeInternal, nil},
+	}
+	for _, tt := range tests {
+		// Repositories wrap the driver error
+		wrapped := fmt.Errorf("creating user: %w", tt.err)
+		got := From(wrapped)
+
+		if got.Status != tt.status || got.Code != tt.code {
+			t.Errorf("%s: %d %s, want %d %s", tt.name, got.Status, got.Code, tt.status, tt.code)
+		}
+		if fmt.Sprint(got.Fields) != fmt.Sprint(tt.fields) {
+			t.Errorf("%s: fields = %+v, want %+v", tt.name, got.Fields, tt.fields)
+		}
+		if !errors.Is(got, wrapped) {
+			t.Errorf("%s: cause %v not kept", tt.name, got.Err)
+		}
+	}
+}
+
+func TestFromPQIgnoresOtherErrors(t *testing.T) {
+	if got := fromPQ(errors.New("connection refused")); got != nil {
+		t.Errorf("fromPQ mapped a non-Postgres error to %v", got)
+	}
+	if got := From(errors.New("connection refused")); got.Status != http.StatusInternalServerError || got.Code != CodeInternal {
+		t.Errorf("From = %v, want an internal error", got)
+	}
+
+	taken := Taken(CodeEmailTaken, "email")
+	if got := From(fmt.Errorf("wrapped: %w", taken)); got != taken {
+		t.Errorf("From = %v, want the wrapped API error as is", got)
+	}
+}
@@ -0,0 +1,92 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/yourusername/ums/backend/internal/logging"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// typePrefix turns a code into the problem type URI
+const typePrefix = "urn:ums:problem:"
+
+// Problem is the RFC 7807 body of an error response. Code, RequestID and
+// Errors are extension members.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// From converts err to an API error. *Error values are returned as is,
+// Postgres errors are mapped by SQLSTATE and anything else becomes an
+// internal error.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if apiErr := fromPQ(err); apiErr != nil {
+		return apiErr
+	}
+	return Internal(err)
+}
+
+// Write writes err to w as a problem. Internal errors are logged with their
+// cause, which the client never sees.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := From(err)
+	if apiErr.Status >= http.StatusInternalServerError && apiErr.Err != nil {
+		logging.FromContext(r.Context()).Error("request failed", "error", apiErr.Err, "code", string(apiErr.Code))
+	}
+	WriteProblem(w, apiErr.Problem(r))
+}
+
+// Problem returns the problem details of e for request r
+func (e *Error) Problem(r *http.Request) Problem {
+	p := Problem{
+		Type:   typePrefix + string(e.Code),
+		Title:  http.StatusText(e.Status),
+		Status: e.Status,
+		Detail: e.Detail,
+		Code:   e.Code,
+		Errors: e.Fields,
+	}
+	if r != nil {
+		p.Instance = r.URL.Path
+		p.RequestID = logging.RequestIDFromContext(r.Context())
+	}
+	return p
+}
+
+// WriteProblem writes p with its status code
+func WriteProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// NotFoundHandler answers requests that match no route
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, NotFound("No such endpoint."))
+	})
+}
+
+// MethodNotAllowedHandler answers requests to a route with a method it does
+// not support
+func MethodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed on this endpoint."))
+	})
+}
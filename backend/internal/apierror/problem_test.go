@@ -0,0 +1,106 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/ums/backend/internal/logging"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		problem string
+	}{
+		{"not found", NotFound("User not found."), http.StatusNotFound,
+			`{"type":"urn:ums:problem:not_found","title":"Not Found","status":404,"detail":"User not found.","instance":"/api/users/7","code":"not_found"}`},
+		{"taken", Taken(CodeUsernameTaken, "username"), http.StatusConflict,
+			`{"type":"urn:ums:problem:username_taken","title":"Conflict","status":409,"detail":"The username is already taken.","instance":"/api/users/7","code":"username_taken",` +
+				`"errors":[{"field":"username","code":"taken","message":"This username is already taken."}]}`},
+		{"internal", Internal(errors.New("pq: connection refused at 10.0.0.5")), http.StatusInternalServerError,
+			`{"type":"urn:ums:problem:internal_error","title":"Internal Server Error","status":500,"detail":"An unexpected error occurred.","instance":"/api/users/7","code":"internal_error"}`},
+		{"plain error", errors.New("boom"), http.StatusInternalServerError,
+			`{"type":"urn:ums:problem:internal_error","title":"Internal Server Error","status":500,"detail":"An unexpected error occurred.","instance":"/api/users/7","code":"internal_error"}`},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/users/7", nil)
+		w := httptest.NewRecorder()
+
+		Write(w, r, tt.err)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if got := w.Header().Get("Content-Type"); got != ContentType {
+			t.Errorf("%s: Content-Type = %q", tt.name, got)
+		}
+		if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("%s: X-Content-Type-Options = %q", tt.name, got)
+		}
+		if got := strings.TrimSpace(w.Body.String()); got != tt.problem {
+			t.Errorf("%s: body\n%s\nwant\n%s", tt.name, got, tt.problem)
+		}
+	}
+}
+
+func TestWriteRequestID(t *testing.T) {
+	// The logging middleware puts the request ID in the context
+	var p Problem
+	h := logging.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, Forbidden("Nope."))
+	}))
+	r := httptest.NewRequest(http.MethodDelete, "/api/users/7", nil)
+	r.Header.Set(logging.RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if p.RequestID != "req-42" || p.Code != CodeForbidden || p.Status != http.StatusForbidden {
+		t.Errorf("problem = %+v", p)
+	}
+}
+
+func TestValidationFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    *Error
+		fields []string
+	}{
+		{"single field", Field("email", FieldInvalid, "Enter a valid email address."), []string{"email"}},
+		{"several fields", Validation(
+			FieldError{Field: "username", Code: FieldTooShort, Message: "Use at least 3 characters."},
+			FieldError{Field: "email", Code: FieldRequired, Message: "This field is required."},
+			FieldError{Field: "password", Code: FieldTooShort, Message: "Use at least 8 characters."},
+		), []string{"username", "email", "password"}},
+		{"no fields", Validation(), nil},
+	}
+	for _, tt := range tests {
+		p := tt.err.Problem(nil)
+		if p.Status != http.StatusBadRequest || p.Code != CodeValidationFailed || p.Instance != "" {
+			t.Errorf("%s: problem = %+v", tt.name, p)
+		}
+		var got []string
+		for _, f := range p.Errors {
+			got = append(got, f.Field)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("%s: fields = %v, want %v in order", tt.name, got, tt.fields)
+		}
+
+		b, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hasErrors := strings.Contains(string(b), `"errors"`); hasErrors != (len(tt.fields) > 0) {
+			t.Errorf("%s: %s", tt.name, b)
+		}
+	}
+}
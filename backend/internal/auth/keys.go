@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MinHMACSecretLength is the shortest HS256 secret accepted (256 bits)
+const MinHMACSecretLength = 32
+
+// Key is a named signing key. Tokens carry the key ID in their "kid" header
+// so verification can pick the right key after a rotation.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+// NewHS256Key returns a symmetric HMAC-SHA256 key
+func NewHS256Key(id string, secret []byte) (Key, error) {
+	if len(secret) < MinHMACSecretLength {
+		return Key{}, fmt.Errorf("auth: HS256 secret for key %q must be at least %d bytes", id, MinHMACSecretLength)
+	}
+	return Key{ID: id, Method: jwt.SigningMethodHS256, sign: secret, verify: secret}, nil
+}
+
+// NewEdDSAKey returns an Ed25519 signing key
+func NewEdDSAKey(id string, priv ed25519.PrivateKey) Key {
+	return Key{ID: id, Method: jwt.SigningMethodEdDSA, sign: priv, verify: priv.Public()}
+}
+
+// NewRS256Key returns an RSA-SHA256 signing key
+func NewRS256Key(id string, priv *rsa.PrivateKey) Key {
+	return Key{ID: id, Method: jwt.SigningMethodRS256, sign: priv, verify: &priv.PublicKey}
+}
+
+// ParsePrivateKeyPEM builds an EdDSA or RS256 key from a PEM encoded
+// PKCS#8 or PKCS#1 private key
+func ParsePrivateKeyPEM(id string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("auth: key %q is not PEM encoded", id)
+	}
+
+	var parsed crypto.PrivateKey
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("auth: key %q has unsupported PEM type %q", id, block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("auth: parsing key %q: %w", id, err)
+	}
+
+	switch priv := parsed.(type) {
+	case ed25519.PrivateKey:
+		return NewEdDSAKey(id, priv), nil
+	case *rsa.PrivateKey:
+		if priv.N.BitLen() < 2048 {
+			return Key{}, fmt.Errorf("auth: RSA key %q must be at least 2048 bits", id)
+		}
+		return NewRS256Key(id, priv), nil
+	default:
+		return Key{}, fmt.Errorf("auth: key %q has unsupported type %T", id, parsed)
+	}
+}
+
+// GenerateEdDSAKey creates a random Ed25519 key. Tokens signed with it do not
+// survive a restart, so it is only meant for development.
+func GenerateEdDSAKey(id string) (Key, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Key{}, err
+	}
+	return NewEdDSAKey(id, priv), nil
+}
+
+// KeySet holds the active signing key plus older keys that are still
+// accepted for verification until the tokens they signed expire
+type KeySet struct {
+	mu     sync.RWMutex
+	keys   map[string]Key
+	active string
+}
+
+// NewKeySet returns a key set signing with active and verifying with all keys
+func NewKeySet(active Key, previous ...Key) *KeySet {
+	ks := &KeySet{keys: map[string]Key{}}
+	for _, k := range previous {
+		ks.keys[k.ID] = k
+	}
+	ks.keys[active.ID] = active
+	ks.active = active.ID
+	return ks
+}
+
+// Rotate makes k the signing key while keeping the previous keys for verification
+func (ks *KeySet) Rotate(k Key) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[k.ID] = k
+	ks.active = k.ID
+}
+
+// Retire removes a verification key. The active key cannot be retired.
+func (ks *KeySet) Retire(id string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if id == ks.active {
+		return errors.New("auth: cannot retire the active signing key")
+	}
+	delete(ks.keys, id)
+	return nil
+}
+
+// Active returns the current signing key
+func (ks *KeySet) Active() Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.keys[ks.active]
+}
+
+// Lookup returns the key with the given ID
+func (ks *KeySet) Lookup(id string) (Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	k, ok := ks.keys[id]
+	return k, ok
+}
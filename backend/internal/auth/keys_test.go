@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeyRotation(t *testing.T) {
+	m := newTestManager(t)
+	before, _, err := m.Issue(NewPrincipal("42", []string{RoleUser}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := GenerateEdDSAKey("k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Keys().Rotate(next)
+	after, _, err := m.Issue(NewPrincipal("42", []string{RoleUser}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := jwt.NewParser().ParseUnverified(after, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := token.Header["kid"]; kid != "k2" {
+		t.Errorf("kid = %v, want the rotated key", kid)
+	}
+	for _, raw := range []string{before, after} {
+		if _, err := m.Verify(raw); err != nil {
+			t.Errorf("after rotation: %v", err)
+		}
+	}
+
+	if err := m.Keys().Retire("k2"); err == nil {
+		t.Error("retired the active key")
+	}
+	if err := m.Keys().Retire("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(before); err != ErrInvalidToken {
+		t.Errorf("token of a retired key: error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestRS256(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKeyPEM("rsa", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Method != jwt.SigningMethodRS256 {
+		t.Fatalf("method = %s, want RS256", key.Method.Alg())
+	}
+
+	m := NewTokenManager(NewKeySet(key), time.Minute)
+	raw, _, err := m.Issue(NewPrincipal("42", []string{RoleUser}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(raw); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParsePrivateKeyPEMRejects(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string][]byte{
+		"not pem":    []byte("secret"),
+		"public key": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{1}}),
+		"garbage":    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1, 2, 3}}),
+		"weak rsa":   pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(weak)}),
+	}
+	for name, data := range tests {
+		if _, err := ParsePrivateKeyPEM("k", data); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+
+	if _, err := NewHS256Key("k", make([]byte, MinHMACSecretLength-1)); err == nil {
+		t.Error("short HS256 secret accepted")
+	}
+}
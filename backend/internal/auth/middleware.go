@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+)
+
+// Middleware rejects requests without a valid bearer token or session
+// cookie and stores the authenticated principal in the request context
+func (m *TokenManager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := bearerToken(r)
+		if !ok {
+			if m.sessions != nil {
+				if principal, err := m.sessions.CookiePrincipal(r); err == nil {
+					next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+					return
+				}
+			}
+			WriteError(w, r, ErrUnauthenticated)
+			return
+		}
+
+		principal, err := m.Verify(raw)
+		if err == nil && principal.SessionID != "" && m.sessions != nil {
+			err = m.sessions.CheckSession(r.Context(), principal.SessionID)
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ums", error="invalid_token"`)
+			apierror.Write(w, r, apierror.Unauthorized(apierror.CodeInvalidToken, "The access token is invalid or expired."))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
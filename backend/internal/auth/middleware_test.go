@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeSessions is a SessionBackend with a fixed set of active sessions and
+// an optional cookie principal
+type fakeSessions struct {
+	active map[string]bool
+	cookie *Principal
+}
+
+func (f fakeSessions) CheckSession(ctx context.Context, id string) error {
+	if !f.active[id] {
+		return errors.New("session ended")
+	}
+	return nil
+}
+
+func (f fakeSessions) CookiePrincipal(r *http.Request) (Principal, error) {
+	if f.cookie == nil {
+		return Principal{}, errors.New("no cookie")
+	}
+	return *f.cookie, nil
+}
+
+// call runs a request with the given Authorization header through the
+// middleware and returns the status and the principal the handler saw
+func call(m *TokenManager, authorization string) (int, Principal) {
+	var seen Principal
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = PrincipalFromContext(r.Context())
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code, seen
+}
+
+func TestMiddleware(t *testing.T) {
+	m := newTestManager(t)
+	raw, _, err := m.Issue(NewPrincipal("42", []string{RoleUser}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code, p := call(m, "Bearer "+raw); code != http.StatusOK || p.UserID != "42" {
+		t.Errorf("valid token: status %d, principal %+v", code, p)
+	}
+	if code, p := call(m, "bearer  "+raw); code != http.StatusOK || p.UserID != "42" {
+		t.Errorf("lower-case scheme: status %d, principal %+v", code, p)
+	}
+	for _, header := range []string{"", "Bearer", "Basic " + raw, "Bearer not-a-token"} {
+		if code, _ := call(m, header); code != http.StatusUnauthorized {
+			t.Errorf("%q: status %d, want 401", header, code)
+		}
+	}
+}
+
+func TestMiddlewareSessions(t *testing.T) {
+	m := newTestManager(t)
+	cookie := NewPrincipal("7", []string{RoleUser})
+	m.SetSessionBackend(fakeSessions{active: map[string]bool{"live": true}, cookie: &cookie})
+
+	issue := func(sessionID string) string {
+		p := NewPrincipal("42", []string{RoleUser})
+		p.SessionID = sessionID
+		raw, _, err := m.Issue(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	if code, _ := call(m, "Bearer "+issue("live")); code != http.StatusOK {
+		t.Errorf("token of an active session: status %d", code)
+	}
+	if code, _ := call(m, "Bearer "+issue("ended")); code != http.StatusUnauthorized {
+		t.Errorf("token of an ended session: status %d, want 401", code)
+	}
+	// Without a bearer token the session cookie authenticates
+	if code, p := call(m, ""); code != http.StatusOK || p.UserID != "7" {
+		t.Errorf("cookie: status %d, principal %+v", code, p)
+	}
+}
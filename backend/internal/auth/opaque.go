@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// Lifetimes of the single-use opaque tokens
+const (
+	// DefaultInviteTTL is how long an invitation can be redeemed
+	DefaultInviteTTL = 72 * time.Hour
+	// DefaultVerificationTTL is how long an email verification link can be used
+	DefaultVerificationTTL = 48 * time.Hour
+	// DefaultPasswordResetTTL is how long a password reset link can be used
+	DefaultPasswordResetTTL = time.Hour
+	// DefaultMFAChallengeTTL is how long the second step of a sign-in can be
+	// completed after the password was accepted
+	DefaultMFAChallengeTTL = 5 * time.Minute
+	// DefaultPasskeyCeremonyTTL is how long the authenticator's response to a
+	// passkey registration or sign-in can be submitted
+	DefaultPasskeyCeremonyTTL = 5 * time.Minute
+)
+
+// NewOpaqueToken returns a random opaque token and the hash to store. Refresh
+// tokens, invitations, email verifications, password resets, MFA challenges
+// and passkey ceremonies all use this format; only the hash is persisted.
+func NewOpaqueToken() (raw, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = base64.RawURLEncoding.EncodeToString(b)
+	return raw, HashOpaqueToken(raw), nil
+}
+
+// HashOpaqueToken returns the storage hash of a raw opaque token. Tokens
+// carry 256 bits of entropy, so an unsalted SHA-256 is sufficient.
+func HashOpaqueToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
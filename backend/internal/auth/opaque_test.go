@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestNewOpaqueToken(t *testing.T) {
+	raw, hash, err := NewOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil || len(b) != 32 {
+		t.Errorf("token %q is not 32 bytes of base64url: %v", raw, err)
+	}
+	if hash != HashOpaqueToken(raw) {
+		t.Error("returned hash differs from HashOpaqueToken")
+	}
+	if len(hash) != 64 || hash == raw {
+		t.Errorf("hash = %q, want 64 hex digits", hash)
+	}
+
+	other, otherHash, err := NewOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == raw || otherHash == hash {
+		t.Error("two tokens are equal")
+	}
+}
+
+func TestHashOpaqueToken(t *testing.T) {
+	// SHA-256 of "abc"
+	const want = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := HashOpaqueToken("abc"); got != want {
+		t.Errorf("HashOpaqueToken(abc) = %s, want %s", got, want)
+	}
+}
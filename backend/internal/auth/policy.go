@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+)
+
+var (
+	// ErrUnauthenticated means the request carries no valid credentials
+	ErrUnauthenticated = errors.New("auth: unauthenticated")
+	// ErrForbidden means the principal may not perform the action
+	ErrForbidden = errors.New("auth: forbidden")
+)
+
+// Permission names an operation guarded by the policy. A permission with the
+// OwnSuffix, e.g. "users:read:own", grants the operation only on resources
+// owned by the principal.
+type Permission string
+
+// OwnSuffix restricts a permission to the principal's own resources
+const OwnSuffix = ":own"
+
+const (
+	PermUsersList   Permission = "users:list"
+	PermUsersRead   Permission = "users:read"
+	PermUsersUpdate Permission = "users:update"
+	PermUsersCreate Permission = "users:create"
+	PermUsersDelete Permission = "users:delete"
+	PermRolesManage Permission = "roles:manage"
+	PermRolesAssign Permission = "roles:assign"
+	PermStatsRead   Permission = "stats:read"
+	PermMFAReset    Permission = "mfa:reset"
+)
+
+// Resource is the object an operation targets. OwnerID is the user owning
+// it; it is empty for collections.
+type Resource struct {
+	Type    string
+	ID      string
+	OwnerID string
+}
+
+// UserResource returns the resource for a user record, which the user owns
+func UserResource(id string) Resource {
+	return Resource{Type: "user", ID: id, OwnerID: id}
+}
+
+// Collection returns the resource for a whole collection, e.g. all users
+func Collection(typ string) Resource {
+	return Resource{Type: typ}
+}
+
+// GrantLoader returns the permissions granted by each role name
+type GrantLoader func() (map[string][]string, error)
+
+// Policy decides which permissions a principal holds. Grants are loaded
+// lazily and cached for a short time so role edits propagate quickly across
+// instances; Invalidate reloads them immediately.
+type Policy struct {
+	load GrantLoader
+	ttl  time.Duration
+
+	mu       sync.RWMutex
+	grants   map[string]map[Permission]bool
+	loadedAt time.Time
+}
+
+// DefaultGrants are used when no loader is configured or loading fails
+// before any grants were ever loaded
+var DefaultGrants = map[string][]string{
+	RoleUser: {string(PermUsersRead) + OwnSuffix, string(PermUsersUpdate) + OwnSuffix},
+}
+
+// NewPolicy returns a policy loading grants with load and caching them for ttl
+func NewPolicy(load GrantLoader, ttl time.Duration) *Policy {
+	return &Policy{load: load, ttl: ttl}
+}
+
+// Invalidate drops cached grants so the next check reloads them
+func (pl *Policy) Invalidate() {
+	pl.mu.Lock()
+	pl.loadedAt = time.Time{}
+	pl.mu.Unlock()
+}
+
+// Can reports whether p may perform perm on res. The built-in admin role
+// holds every permission.
+func (pl *Policy) Can(p Principal, perm Permission, res Resource) bool {
+	if p.UserID == "" {
+		return false
+	}
+	if p.IsAdmin() {
+		return true
+	}
+
+	grants := pl.current()
+	owned := res.OwnerID != "" && res.OwnerID == p.UserID
+	for _, role := range p.Roles {
+		granted := grants[role]
+		if granted[perm] || (owned && granted[perm+OwnSuffix]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (pl *Policy) current() map[string]map[Permission]bool {
+	pl.mu.RLock()
+	grants, fresh := pl.grants, time.Since(pl.loadedAt) < pl.ttl
+	pl.mu.RUnlock()
+	if fresh && grants != nil {
+		return grants
+	}
+
+	raw := DefaultGrants
+	if pl.load != nil {
+		loaded, err := pl.load()
+		if err != nil {
+			slog.Error("failed to load role permissions", "error", err)
+			if grants != nil {
+				return grants
+			}
+		} else {
+			raw = loaded
+		}
+	}
+
+	grants = map[string]map[Permission]bool{}
+	for role, perms := range raw {
+		grants[role] = map[Permission]bool{}
+		for _, perm := range perms {
+			grants[role][Permission(strings.TrimSpace(perm))] = true
+		}
+	}
+
+	pl.mu.Lock()
+	pl.grants = grants
+	pl.loadedAt = time.Now()
+	pl.mu.Unlock()
+	return grants
+}
+
+// Authorize returns ErrUnauthenticated for anonymous principals and
+// ErrForbidden when p may not perform perm on res
+func (pl *Policy) Authorize(p Principal, perm Permission, res Resource) error {
+	if p.UserID == "" {
+		return ErrUnauthenticated
+	}
+	if !pl.Can(p, perm, res) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Check authorizes the request's principal for perm on res. When access is
+// denied it writes the 401 or 403 response and returns false.
+func (pl *Policy) Check(w http.ResponseWriter, r *http.Request, perm Permission, res Resource) (Principal, bool) {
+	p, _ := PrincipalFromContext(r.Context())
+	if err := pl.Authorize(p, perm, res); err != nil {
+		WriteError(w, r, err)
+		return p, false
+	}
+	return p, true
+}
+
+// WriteError writes the problem response for ErrUnauthenticated or
+// ErrForbidden
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrUnauthenticated) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="ums"`)
+		apierror.Write(w, r, apierror.Unauthorized(apierror.CodeUnauthorized, "Authentication required."))
+		return
+	}
+	apierror.Write(w, r, apierror.Forbidden("You are not allowed to perform this action."))
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPolicyCan(t *testing.T) {
+	pl := NewPolicy(func() (map[string][]string, error) {
+		return map[string][]string{
+			RoleUser:  {"users:read:own", "users:update:own"},
+			"support": {"users:list", " users:read "},
+		}, nil
+	}, time.Minute)
+
+	user := NewPrincipal("1", []string{RoleUser})
+	support := NewPrincipal("2", []string{RoleUser, "support"})
+	admin := NewPrincipal("3", []string{RoleAdmin})
+
+	tests := []struct {
+		name string
+		p    Principal
+		perm Permission
+		res  Resource
+		want bool
+	}{
+		{"own record", user, PermUsersRead, UserResource("1"), true},
+		{"other record", user, PermUsersRead, UserResource("2"), false},
+		{"own update", user, PermUsersUpdate, UserResource("1"), true},
+		{"own delete", user, PermUsersDelete, UserResource("1"), false},
+		{"collection", user, PermUsersList, Collection("users"), false},
+		{"granted list", support, PermUsersList, Collection("users"), true},
+		{"granted read", support, PermUsersRead, UserResource("1"), true},
+		{"ungranted", support, PermUsersDelete, UserResource("1"), false},
+		{"admin", admin, PermRolesManage, Collection("roles"), true},
+		{"anonymous", Principal{Roles: []string{RoleAdmin}}, PermUsersList, Collection("users"), false},
+		{"unknown role", NewPrincipal("4", []string{"ghost"}), PermUsersRead, UserResource("4"), false},
+	}
+	for _, tt := range tests {
+		if got := pl.Can(tt.p, tt.perm, tt.res); got != tt.want {
+			t.Errorf("%s: Can = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyCaching(t *testing.T) {
+	grants := map[string][]string{"support": {"users:list"}}
+	loads := 0
+	fail := false
+	pl := NewPolicy(func() (map[string][]string, error) {
+		loads++
+		if fail {
+			return nil, errors.New("database down")
+		}
+		return grants, nil
+	}, time.Hour)
+	support := NewPrincipal("2", []string{"support"})
+
+	if !pl.Can(support, PermUsersList, Collection("users")) {
+		t.Fatal("granted permission refused")
+	}
+	grants = map[string][]string{}
+	if !pl.Can(support, PermUsersList, Collection("users")) || loads != 1 {
+		t.Errorf("cached grants not used: %d loads", loads)
+	}
+
+	// Invalidate picks up the revoked grant
+	pl.Invalidate()
+	if pl.Can(support, PermUsersList, Collection("users")) || loads != 2 {
+		t.Errorf("revoked grant still honoured after Invalidate: %d loads", loads)
+	}
+
+	// A failed reload keeps the last grants
+	grants = map[string][]string{"support": {"users:list"}}
+	pl.Invalidate()
+	pl.Can(support, PermUsersList, Collection("users"))
+	fail = true
+	pl.Invalidate()
+	if !pl.Can(support, PermUsersList, Collection("users")) {
+		t.Error("failed reload dropped the cached grants")
+	}
+}
+
+func TestPolicyDefaultGrants(t *testing.T) {
+	pl := NewPolicy(func() (map[string][]string, error) { return nil, errors.New("database down") }, time.Hour)
+	user := NewPrincipal("1", []string{RoleUser})
+	if !pl.Can(user, PermUsersRead, UserResource("1")) {
+		t.Error("default grants not used when loading fails")
+	}
+	if pl.Can(user, PermUsersList, Collection("users")) {
+		t.Error("default grants allow listing users")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	pl := NewPolicy(nil, time.Hour)
+
+	check := func(p *Principal) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+		if p != nil {
+			r = r.WithContext(WithPrincipal(r.Context(), *p))
+		}
+		w := httptest.NewRecorder()
+		if _, ok := pl.Check(w, r, PermUsersList, Collection("users")); ok {
+			return http.StatusOK
+		}
+		return w.Code
+	}
+
+	user := NewPrincipal("1", []string{RoleUser})
+	admin := NewPrincipal("2", []string{RoleAdmin})
+	if code := check(nil); code != http.StatusUnauthorized {
+		t.Errorf("anonymous: status %d, want 401", code)
+	}
+	if code := check(&user); code != http.StatusForbidden {
+		t.Errorf("user: status %d, want 403", code)
+	}
+	if code := check(&admin); code != http.StatusOK {
+		t.Errorf("admin: status %d, want 200", code)
+	}
+
+	if err := pl.Authorize(Principal{}, PermUsersList, Collection("users")); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Authorize(anonymous) = %v", err)
+	}
+	if err := pl.Authorize(user, PermUsersList, Collection("users")); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Authorize(user) = %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package auth
+
+import "context"
+
+// Role names carried in access tokens. Other roles can be created at runtime.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserID string `json:"id"`
+	// Role is "admin" or "user", kept for clients of the original API
+	Role      string   `json:"role"`
+	Roles     []string `json:"roles"`
+	SessionID string   `json:"session_id,omitempty"`
+}
+
+// NewPrincipal returns the principal for a user holding roles
+func NewPrincipal(userID string, roles []string) Principal {
+	p := Principal{UserID: userID, Role: RoleUser, Roles: roles}
+	if p.HasRole(RoleAdmin) {
+		p.Role = RoleAdmin
+	}
+	return p
+}
+
+// HasRole reports whether the principal holds the named role
+func (p Principal) HasRole(name string) bool {
+	for _, r := range p.Roles {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the principal holds the admin role
+func (p Principal) IsAdmin() bool {
+	return p.HasRole(RoleAdmin)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored by the auth middleware
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
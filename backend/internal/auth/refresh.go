@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// DefaultRefreshTokenTTL is how long a refresh token can be redeemed
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+// NewTokenFamily returns a random ID grouping the refresh tokens of one login
+func NewTokenFamily() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer is the "iss" claim of every token minted by the service
+const Issuer = "ums"
+
+// DefaultAccessTokenTTL is how long an access token stays valid. It is kept
+// short because clients renew it with a refresh token.
+const DefaultAccessTokenTTL = 15 * time.Minute
+
+// ErrInvalidToken is returned for tokens that are malformed, expired or badly signed
+var ErrInvalidToken = errors.New("auth: invalid token")
+
+// Claims is the JWT payload of an access token
+type Claims struct {
+	Role      string   `json:"role"`
+	Roles     []string `json:"roles,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// SessionBackend lets the token middleware consult server-side sessions
+type SessionBackend interface {
+	// CheckSession returns an error unless the session is still active
+	CheckSession(ctx context.Context, id string) error
+	// CookiePrincipal authenticates a request by its session cookie
+	CookiePrincipal(r *http.Request) (Principal, error)
+}
+
+// TokenManager issues and verifies signed access tokens
+type TokenManager struct {
+	keys     *KeySet
+	ttl      time.Duration
+	now      func() time.Time
+	sessions SessionBackend
+}
+
+// NewTokenManager returns a manager signing with keys' active key
+func NewTokenManager(keys *KeySet, ttl time.Duration) *TokenManager {
+	if ttl <= 0 {
+		ttl = DefaultAccessTokenTTL
+	}
+	return &TokenManager{keys: keys, ttl: ttl, now: time.Now}
+}
+
+// Keys returns the key set so callers can rotate keys at runtime
+func (m *TokenManager) Keys() *KeySet {
+	return m.keys
+}
+
+// SetSessionBackend makes the middleware reject tokens whose session has
+// ended and accept session cookies in place of a bearer token
+func (m *TokenManager) SetSessionBackend(b SessionBackend) {
+	m.sessions = b
+}
+
+// Issue returns a signed access token for p and its expiry time
+func (m *TokenManager) Issue(p Principal) (string, time.Time, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := m.now()
+	expires := now.Add(m.ttl)
+	claims := Claims{
+		Role:      p.Role,
+		Roles:     p.Roles,
+		SessionID: p.SessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   p.UserID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+			ID:        hex.EncodeToString(jti),
+		},
+	}
+
+	key := m.keys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	signed, err := token.SignedString(key.sign)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("auth: signing token: %w", err)
+	}
+	return signed, expires, nil
+}
+
+// Verify checks the signature and registered claims of a token and returns
+// the principal it was issued to
+func (m *TokenManager) Verify(raw string) (Principal, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(raw, &claims, m.keyFunc,
+		jwt.WithIssuer(Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(30*time.Second),
+		jwt.WithTimeFunc(m.now),
+	)
+	if err != nil || claims.Subject == "" {
+		return Principal{}, ErrInvalidToken
+	}
+
+	// Tokens issued before roles were introduced only carry the role claim
+	roles := claims.Roles
+	if len(roles) == 0 && claims.Role != "" {
+		roles = []string{claims.Role}
+	}
+
+	p := NewPrincipal(claims.Subject, roles)
+	p.SessionID = claims.SessionID
+	return p, nil
+}
+
+// keyFunc resolves the verification key from the "kid" header and refuses
+// tokens whose algorithm differs from the key's, which rules out algorithm
+// substitution attacks such as signing with an RSA public key as HMAC secret
+func (m *TokenManager) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := m.keys.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("auth: unknown key %q", kid)
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("auth: unexpected signing method %q for key %q", t.Method.Alg(), kid)
+	}
+	return key.verify, nil
+}
+
+// LoadTokenManager builds a token manager from kid=path key entries.
+//
+// Each file holds either a PEM private key (Ed25519 for EdDSA, RSA for RS256)
+// or a raw HS256 secret. The first entry signs new tokens; the rest are only
+// used to verify tokens issued before a rotation. Without entries an
+// ephemeral Ed25519 key is generated.
+func LoadTokenManager(entries []string, ttl time.Duration) (*TokenManager, error) {
+	if len(entries) == 0 {
+		key, err := GenerateEdDSAKey("ephemeral")
+		if err != nil {
+			return nil, err
+		}
+		return NewTokenManager(NewKeySet(key), ttl), nil
+	}
+
+	var keys []Key
+	for _, entry := range entries {
+		id, path, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || id == "" || path == "" {
+			return nil, fmt.Errorf("auth: invalid key entry %q, expected kid=path", entry)
+		}
+		key, err := LoadKeyFile(id, path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewTokenManager(NewKeySet(keys[0], keys[1:]...), ttl), nil
+}
+
+// LoadKeyFile reads a PEM private key or raw HS256 secret from path
+func LoadKeyFile(id, path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, fmt.Errorf("auth: reading key %q: %w", id, err)
+	}
+	if strings.Contains(string(data), "-----BEGIN") {
+		return ParsePrivateKeyPEM(id, data)
+	}
+	return NewHS256Key(id, []byte(strings.TrimSpace(string(data))))
+}
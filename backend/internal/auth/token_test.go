@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newTestManager returns a manager signing with a new EdDSA key
+func newTestManager(t *testing.T) *TokenManager {
+	t.Helper()
+	key, err := GenerateEdDSAKey("k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTokenManager(NewKeySet(key), time.Minute)
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	m := newTestManager(t)
+	p := NewPrincipal("42", []string{RoleAdmin, "support"})
+	p.SessionID = "s1"
+
+	raw, expires, err := m.Issue(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(expires); d <= 0 || d > time.Minute {
+		t.Errorf("expires in %v, want within the TTL", d)
+	}
+
+	got, err := m.Verify(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != "42" || got.Role != RoleAdmin || len(got.Roles) != 2 || got.SessionID != "s1" {
+		t.Errorf("principal = %+v, want %+v", got, p)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	m := newTestManager(t)
+	valid, _, err := m.Issue(NewPrincipal("42", []string{RoleUser}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Expired beyond the leeway
+	expired := newTestManager(t)
+	expired.keys = m.keys
+	expired.now = func() time.Time { return time.Now().Add(-2 * time.Minute) }
+	old, _, err := expired.Issue(NewPrincipal("42", []string{RoleUser}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Signed by a key of another service under the same key ID
+	other := newTestManager(t)
+	forged, _, err := other.Issue(NewPrincipal("42", []string{RoleAdmin}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+
+	tests := map[string]string{
+		"expired":   old,
+		"forged":    forged,
+		"tampered":  tampered,
+		"malformed": "not-a-token",
+		"empty":     "",
+		"wrong issuer": sign(t, m.keys.Active(), jwt.MapClaims{
+			"iss": "someone-else", "sub": "42", "exp": time.Now().Add(time.Minute).Unix(), "iat": time.Now().Unix(),
+		}),
+		"no subject": sign(t, m.keys.Active(), jwt.MapClaims{
+			"iss": Issuer, "exp": time.Now().Add(time.Minute).Unix(), "iat": time.Now().Unix(),
+		}),
+		"no expiry": sign(t, m.keys.Active(), jwt.MapClaims{
+			"iss": Issuer, "sub": "42", "iat": time.Now().Unix(),
+		}),
+	}
+	for name, raw := range tests {
+		if _, err := m.Verify(raw); err != ErrInvalidToken {
+			t.Errorf("%s: error = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+// sign signs claims with key, setting its key ID
+func sign(t *testing.T, key Key, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	raw, err := token.SignedString(key.sign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerifyRejectsAlgorithmSubstitution(t *testing.T) {
+	m := newTestManager(t)
+	// An HMAC token naming the EdDSA key must not be checked against it
+	hmac, err := NewHS256Key("k1", make([]byte, MinHMACSecretLength))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := sign(t, hmac, jwt.MapClaims{"iss": Issuer, "sub": "42", "exp": time.Now().Add(time.Minute).Unix(), "iat": time.Now().Unix()})
+	if _, err := m.Verify(raw); err != ErrInvalidToken {
+		t.Errorf("error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestVerifyLegacyRoleClaim(t *testing.T) {
+	m := newTestManager(t)
+	raw := sign(t, m.keys.Active(), jwt.MapClaims{
+		"iss": Issuer, "sub": "42", "role": RoleAdmin, "exp": time.Now().Add(time.Minute).Unix(), "iat": time.Now().Unix(),
+	})
+	p, err := m.Verify(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsAdmin() || len(p.Roles) != 1 {
+		t.Errorf("principal = %+v, want the admin role from the role claim", p)
+	}
+}
+
+func TestLoadTokenManager(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "hs256")
+	if err := os.WriteFile(secret, []byte(strings.Repeat("s", MinHMACSecretLength)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eddsa := filepath.Join(dir, "eddsa.pem")
+	if err := os.WriteFile(eddsa, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadTokenManager([]string{"new=" + eddsa, " old=" + secret}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active := m.Keys().Active(); active.ID != "new" || active.Method != jwt.SigningMethodEdDSA {
+		t.Errorf("active key = %s %s, want new EdDSA", active.ID, active.Method.Alg())
+	}
+	old, ok := m.Keys().Lookup("old")
+	if !ok || old.Method != jwt.SigningMethodHS256 {
+		t.Fatal("HS256 key old not loaded for verification")
+	}
+
+	// Tokens signed with the previous key still verify
+	raw := sign(t, old, jwt.MapClaims{"iss": Issuer, "sub": "42", "exp": time.Now().Add(time.Minute).Unix(), "iat": time.Now().Unix()})
+	if _, err := m.Verify(raw); err != nil {
+		t.Errorf("token of the previous key: %v", err)
+	}
+
+	short := filepath.Join(dir, "short")
+	if err := os.WriteFile(short, []byte("too short"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, entries := range [][]string{
+		{"missing-path"},
+		{"=" + secret},
+		{"k=" + filepath.Join(dir, "absent")},
+		{"k=" + short},
+	} {
+		if _, err := LoadTokenManager(entries, time.Minute); err == nil {
+			t.Errorf("LoadTokenManager(%q) succeeded", entries)
+		}
+	}
+
+	// Without keys an ephemeral key is generated
+	m, err = LoadTokenManager(nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ttl != DefaultAccessTokenTTL {
+		t.Errorf("ttl = %v, want the default", m.ttl)
+	}
+}
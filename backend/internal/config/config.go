@@ -0,0 +1,291 @@
+// Package config loads the UMS configuration.
+//
+// Settings are resolved from, in increasing precedence: built-in defaults, a
+// YAML or TOML file, UMS_* environment variables and command line flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete UMS configuration
+type Config struct {
+	Server   Server   `yaml:"server" toml:"server"`
+	Database Database `yaml:"database" toml:"database"`
+	Auth     Auth     `yaml:"auth" toml:"auth"`
+	Health   Health   `yaml:"health" toml:"health"`
+	Metrics  Metrics  `yaml:"metrics" toml:"metrics"`
+	Log      Log      `yaml:"log" toml:"log"`
+	Mail     Mail     `yaml:"mail" toml:"mail"`
+}
+
+// Server configures the HTTP API
+type Server struct {
+	// Addr is the address to listen on, e.g. ":8080"
+	Addr string `yaml:"addr" toml:"addr"`
+	// CookieSecure marks the session cookie Secure (HTTPS only)
+	CookieSecure bool `yaml:"cookie_secure" toml:"cookie_secure"`
+	// CORSOrigins may send credentialed cross-origin requests; other
+	// origins get a wildcard CORS response without credentials
+	CORSOrigins []string `yaml:"cors_origins" toml:"cors_origins"`
+	// ReadTimeout bounds reading a whole request, including the body
+	ReadTimeout time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	// WriteTimeout bounds handling a request and writing the response
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	// IdleTimeout closes keep-alive connections without requests for this long
+	IdleTimeout time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// DrainDelay is how long the server keeps serving after reporting not
+	// ready on shutdown, so load balancers stop sending new requests first
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+	// ShutdownTimeout bounds how long shutdown waits for in-flight requests
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// CleanupInterval is how often expired sessions, refresh tokens and
+	// invitations are deleted; zero disables the cleanup
+	CleanupInterval time.Duration `yaml:"cleanup_interval" toml:"cleanup_interval"`
+	// MaxBodyBytes is the largest request body accepted; larger requests
+	// are answered with 413
+	MaxBodyBytes int `yaml:"max_body_bytes" toml:"max_body_bytes"`
+}
+
+// Database configures the Postgres connection
+type Database struct {
+	// URL is a postgres:// URL or a key=value connection string
+	URL string `yaml:"url" toml:"url"`
+	// AutoMigrate applies pending migrations when the server starts
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+	// MaxOpenConns limits the connection pool; zero means unlimited
+	MaxOpenConns int `yaml:"max_open_conns" toml:"max_open_conns"`
+	// MaxIdleConns is how many unused connections the pool keeps open
+	MaxIdleConns int `yaml:"max_idle_conns" toml:"max_idle_conns"`
+}
+
+// Auth configures access tokens
+type Auth struct {
+	// JWTKeys are kid=path entries; the first signs new tokens and the rest
+	// only verify tokens issued before a rotation. Empty means an ephemeral
+	// key is generated at startup.
+	JWTKeys []string `yaml:"jwt_keys" toml:"jwt_keys"`
+	// TokenTTL is the access token lifetime
+	TokenTTL time.Duration `yaml:"token_ttl" toml:"token_ttl"`
+	// RequireVerifiedEmail refuses to sign in users who have not verified
+	// their email address
+	RequireVerifiedEmail bool `yaml:"require_verified_email" toml:"require_verified_email"`
+	// VerificationTTL is how long an email verification link can be used
+	VerificationTTL time.Duration `yaml:"verification_ttl" toml:"verification_ttl"`
+	// PasswordResetTTL is how long a password reset link can be used
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl" toml:"password_reset_ttl"`
+	// EncryptionKey is the base64 AES-256 key encrypting TOTP secrets.
+	// Two-factor authentication is unavailable without it.
+	EncryptionKey string `yaml:"encryption_key" toml:"encryption_key"`
+	// MFAIssuer names the service in authenticator apps and passkey prompts
+	MFAIssuer string `yaml:"mfa_issuer" toml:"mfa_issuer"`
+	// PasskeyRPID is the WebAuthn relying party ID: the domain passkeys are
+	// bound to, e.g. "example.com". It cannot change without invalidating
+	// every passkey.
+	PasskeyRPID string `yaml:"passkey_rp_id" toml:"passkey_rp_id"`
+	// PasskeyOrigins are the frontend origins passkey ceremonies may run on;
+	// their hosts must be PasskeyRPID or a subdomain of it
+	PasskeyOrigins []string `yaml:"passkey_origins" toml:"passkey_origins"`
+	// RequireAdminPasskey makes every admin sign in with a passkey; admins
+	// without one must register a passkey before they get tokens
+	RequireAdminPasskey bool `yaml:"require_admin_passkey" toml:"require_admin_passkey"`
+}
+
+// Health configures the readiness checks
+type Health struct {
+	// Timeout bounds each readiness check
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+	// MaxPoolUsage is the fraction of database connections in use above
+	// which the server reports not ready
+	MaxPoolUsage float64 `yaml:"max_pool_usage" toml:"max_pool_usage"`
+}
+
+// Metrics configures the Prometheus endpoint
+type Metrics struct {
+	// Enabled exposes /metrics and instruments the API
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Addr serves /metrics on a separate admin listener, e.g. "127.0.0.1:9090",
+	// instead of the API address
+	Addr string `yaml:"addr" toml:"addr"`
+}
+
+// Log configures logging to stderr
+type Log struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level" toml:"level"`
+	// Format is json or text
+	Format string `yaml:"format" toml:"format"`
+}
+
+// Mail configures outgoing email
+type Mail struct {
+	// Driver is smtp, file (write .eml files to Dir) or memory (discard
+	// after keeping them in memory)
+	Driver string `yaml:"driver" toml:"driver"`
+	// From is the sender, e.g. "UMS <no-reply@example.com>"
+	From string `yaml:"from" toml:"from"`
+	// AppURL is the frontend URL links in emails point to
+	AppURL string `yaml:"app_url" toml:"app_url"`
+	// SMTPAddr is the host:port of the SMTP server
+	SMTPAddr string `yaml:"smtp_addr" toml:"smtp_addr"`
+	// SMTPUsername and SMTPPassword enable SMTP authentication when set
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password"`
+	// Dir is where the file driver writes messages
+	Dir string `yaml:"dir" toml:"dir"`
+}
+
+// Default returns the built-in configuration, suitable for local development
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Addr:            ":8080",
+			CORSOrigins:     []string{"http://localhost:5173", "http://localhost:5174"},
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 10 * time.Second,
+			CleanupInterval: time.Hour,
+			MaxBodyBytes:    1 << 20,
+		},
+		Database: Database{
+			URL:          "host=localhost port=5432 user=postgres password=postgres dbname=ums_db sslmode=disable",
+			MaxOpenConns: 25,
+			MaxIdleConns: 5,
+		},
+		Auth: Auth{
+			TokenTTL:         15 * time.Minute,
+			VerificationTTL:  48 * time.Hour,
+			PasswordResetTTL: time.Hour,
+			MFAIssuer:        "UMS",
+			PasskeyRPID:      "localhost",
+			PasskeyOrigins:   []string{"http://localhost:5173", "http://localhost:5174"},
+		},
+		Health: Health{
+			Timeout:      2 * time.Second,
+			MaxPoolUsage: 0.9,
+		},
+		Metrics: Metrics{
+			Enabled: true,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+		Mail: Mail{
+			Driver:   "file",
+			From:     "UMS <no-reply@localhost>",
+			AppURL:   "http://localhost:5173",
+			SMTPAddr: "localhost:1025",
+			Dir:      "mail",
+		},
+	}
+}
+
+// Load registers -config and a flag per setting on fs, parses args and
+// returns the resolved configuration. The file named by -config or
+// UMS_CONFIG is read if set. Bad environment variables, flags and values are
+// reported together as a ValidationError.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	path := fs.String("config", os.Getenv("UMS_CONFIG"), "configuration `file` (.yaml, .yml or .toml) (UMS_CONFIG)")
+	for _, f := range fields {
+		fs.Var(&rawFlag{isBool: isBoolValue(f.value(&Config{}))}, f.flag, f.usage+" ("+f.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if *path != "" {
+		if err := cfg.LoadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs ValidationError
+	for _, f := range fields {
+		if v := os.Getenv(f.env); v != "" {
+			if err := f.value(cfg).Set(v); err != nil {
+				errs = append(errs, FieldError{Field: f.key, Message: fmt.Sprintf("%s: %v", f.env, err)})
+			}
+		}
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		f, ok := fieldByFlag(fl.Name)
+		if !ok {
+			return
+		}
+		if err := f.value(cfg).Set(fl.Value.String()); err != nil {
+			errs = append(errs, FieldError{Field: f.key, Message: fmt.Sprintf("-%s: %v", f.flag, err)})
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err.(ValidationError)...)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return cfg, nil
+}
+
+// LoadFile overlays the settings in a YAML or TOML file, chosen by its
+// extension. Unknown keys are rejected so typos do not go unnoticed.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parsing %s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Redacted returns a copy of c with secrets masked, for printing and logging
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.Server.CORSOrigins = append([]string(nil), c.Server.CORSOrigins...)
+	r.Auth.JWTKeys = append([]string(nil), c.Auth.JWTKeys...)
+	r.Auth.PasskeyOrigins = append([]string(nil), c.Auth.PasskeyOrigins...)
+	r.Database.URL = redactDatabaseURL(c.Database.URL)
+	if r.Auth.EncryptionKey != "" {
+		r.Auth.EncryptionKey = "xxxxx"
+	}
+	if r.Mail.SMTPPassword != "" {
+		r.Mail.SMTPPassword = "xxxxx"
+	}
+	return &r
+}
+
+// YAML returns c in the config file format
+func (c *Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
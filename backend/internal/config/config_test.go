@@ -0,0 +1,297 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv hides any UMS_* variables set where the tests run
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("UMS_CONFIG", "")
+	for _, f := range fields {
+		t.Setenv(f.env, "")
+	}
+}
+
+func load(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("ums", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return Load(fs, args)
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fieldErrors returns the fields named in err, which must be a
+// ValidationError
+func fieldErrors(t *testing.T, err error) []string {
+	t.Helper()
+	var verr ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error = %v, want a ValidationError", err)
+	}
+	var names []string
+	for _, f := range verr {
+		names = append(names, f.Field)
+	}
+	return names
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Load() = %+v, want the defaults", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "ums.yaml", `
+server:
+  addr: ":9000"
+  read_timeout: 20s
+log:
+  level: debug
+  format: text
+mail:
+  driver: memory
+`)
+	t.Setenv("UMS_READ_TIMEOUT", "25s")
+	t.Setenv("UMS_LOG_LEVEL", "warn")
+
+	cfg, err := load(t, "-config", path, "-log-level", "error", "-cors-origins", "https://a.example.com, ,https://b.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Addr != ":9000" {
+		t.Errorf("Addr = %q, want the file's :9000", cfg.Server.Addr)
+	}
+	if cfg.Log.Format != "text" || cfg.Mail.Driver != "memory" {
+		t.Errorf("Log.Format, Mail.Driver = %q, %q, want the file's text, memory", cfg.Log.Format, cfg.Mail.Driver)
+	}
+	if cfg.Server.ReadTimeout != 25*time.Second {
+		t.Errorf("ReadTimeout = %s, want UMS_READ_TIMEOUT's 25s over the file", cfg.Server.ReadTimeout)
+	}
+	if cfg.Log.Level != "error" {
+		t.Errorf("Log.Level = %q, want the flag's error over the environment", cfg.Log.Level)
+	}
+	if want := []string{"https://a.example.com", "https://b.example.com"}; !reflect.DeepEqual(cfg.Server.CORSOrigins, want) {
+		t.Errorf("CORSOrigins = %q, want %q", cfg.Server.CORSOrigins, want)
+	}
+	if cfg.Server.WriteTimeout != Default().Server.WriteTimeout {
+		t.Errorf("WriteTimeout = %s, want the default", cfg.Server.WriteTimeout)
+	}
+}
+
+func TestLoadConfigFromEnvironment(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("UMS_CONFIG", writeFile(t, "ums.yml", "server:\n  addr: \":9001\"\n"))
+
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Addr != ":9001" {
+		t.Errorf("Addr = %q, want :9001 from UMS_CONFIG", cfg.Server.Addr)
+	}
+}
+
+func TestLoadBoolFlag(t *testing.T) {
+	clearEnv(t)
+	cfg, err := load(t, "-cookie-secure", "-metrics=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Server.CookieSecure {
+		t.Error("-cookie-secure without a value did not enable CookieSecure")
+	}
+	if cfg.Metrics.Enabled {
+		t.Error("-metrics=false did not disable metrics")
+	}
+}
+
+func TestLoadCollectsErrors(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("UMS_READ_TIMEOUT", "soon")
+	t.Setenv("UMS_HEALTH_MAX_POOL_USAGE", "2")
+
+	_, err := load(t, "-max-body-bytes", "lots", "-log-format", "xml")
+	got := fieldErrors(t, err)
+	want := []string{"server.read_timeout", "server.max_body_bytes", "health.max_pool_usage", "log.format"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %q, want %q", got, want)
+	}
+	for _, name := range []string{"UMS_READ_TIMEOUT", "-max-body-bytes"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not name %s", err, name)
+		}
+	}
+}
+
+func TestLoadUnknownFlag(t *testing.T) {
+	clearEnv(t)
+	if _, err := load(t, "-no-such-flag"); err == nil {
+		t.Fatal("Load accepted an unknown flag")
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	clearEnv(t)
+	if _, err := load(t, "-config", filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("Load accepted a missing config file")
+	}
+}
+
+func TestLoadFileTOML(t *testing.T) {
+	cfg := Default()
+	err := cfg.LoadFile(writeFile(t, "ums.toml", `
+[server]
+addr = ":9002"
+idle_timeout = "5m"
+cors_origins = ["https://example.com"]
+
+[auth]
+require_admin_passkey = true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Addr != ":9002" || cfg.Server.IdleTimeout != 5*time.Minute {
+		t.Errorf("server = %q, %s, want :9002, 5m", cfg.Server.Addr, cfg.Server.IdleTimeout)
+	}
+	if !reflect.DeepEqual(cfg.Server.CORSOrigins, []string{"https://example.com"}) {
+		t.Errorf("CORSOrigins = %q", cfg.Server.CORSOrigins)
+	}
+	if !cfg.Auth.RequireAdminPasskey {
+		t.Error("RequireAdminPasskey not read")
+	}
+	if cfg.Auth.TokenTTL != Default().Auth.TokenTTL {
+		t.Errorf("TokenTTL = %s, want the default kept", cfg.Auth.TokenTTL)
+	}
+}
+
+func TestLoadFileRejects(t *testing.T) {
+	tests := []struct {
+		name, file, content string
+	}{
+		{"unknown yaml key", "ums.yaml", "server:\n  adr: \":9000\"\n"},
+		{"unknown toml key", "ums.toml", "[server]\nadr = \":9000\"\n"},
+		{"malformed yaml", "ums.yaml", "server: [\n"},
+		{"malformed toml", "ums.toml", "[server\n"},
+		{"wrong type", "ums.yaml", "server:\n  max_body_bytes: lots\n"},
+		{"unsupported extension", "ums.json", `{"server": {"addr": ":9000"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Default().LoadFile(writeFile(t, tt.file, tt.content)); err == nil {
+				t.Error("LoadFile succeeded")
+			}
+		})
+	}
+}
+
+func TestLoadFileEmpty(t *testing.T) {
+	cfg := Default()
+	if err := cfg.LoadFile(writeFile(t, "ums.yaml", "")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Error("an empty file changed the configuration")
+	}
+}
+
+func TestExampleConfig(t *testing.T) {
+	cfg := Default()
+	if err := cfg.LoadFile(filepath.Join("..", "..", "config.example.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	want := Default()
+	want.Auth.JWTKeys = []string{"k1=/keys/k1.pem"}
+	want.Auth.RequireAdminPasskey = true
+	data, err := want.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := &Config{}
+	if err := got.LoadFile(writeFile(t, "ums.yaml", string(data))); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.EncryptionKey = "c2VjcmV0"
+	cfg.Mail.SMTPPassword = "hunter2"
+
+	r := cfg.Redacted()
+	if r.Auth.EncryptionKey != "xxxxx" || r.Mail.SMTPPassword != "xxxxx" {
+		t.Errorf("secrets = %q, %q, want masked", r.Auth.EncryptionKey, r.Mail.SMTPPassword)
+	}
+	if strings.Contains(r.Database.URL, "password=postgres") {
+		t.Errorf("Database.URL = %q, password not masked", r.Database.URL)
+	}
+	if cfg.Auth.EncryptionKey != "c2VjcmV0" || cfg.Database.URL != Default().Database.URL {
+		t.Error("Redacted changed the original")
+	}
+
+	r.Server.CORSOrigins[0] = "changed"
+	if cfg.Server.CORSOrigins[0] == "changed" {
+		t.Error("Redacted shares CORSOrigins with the original")
+	}
+
+	cfg.Auth.EncryptionKey, cfg.Mail.SMTPPassword = "", ""
+	if r := cfg.Redacted(); r.Auth.EncryptionKey != "" || r.Mail.SMTPPassword != "" {
+		t.Error("unset secrets should stay empty")
+	}
+}
+
+func TestRedactDatabaseURL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"postgres://ums:s3cret@db:5432/ums?sslmode=disable", "postgres://ums:xxxxx@db:5432/ums?sslmode=disable"},
+		{"postgresql://ums:s3cret@db/ums", "postgresql://ums:xxxxx@db/ums"},
+		{"postgres://db/ums", "postgres://db/ums"},
+		{"host=db password=s3cret dbname=ums", "host=db password=xxxxx dbname=ums"},
+		{"host=db password = 's3 cr\\'et' dbname=ums", "host=db password = xxxxx dbname=ums"},
+		{"host=db dbname=ums", "host=db dbname=ums"},
+	}
+	for _, tt := range tests {
+		if got := redactDatabaseURL(tt.in); got != tt.want {
+			t.Errorf("redactDatabaseURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
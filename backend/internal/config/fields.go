@@ -0,0 +1,205 @@
+package config
+
+import (
+	"flag"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field describes a setting that can be overridden by an environment
+// variable and a flag. key is its dotted path in the config file.
+type field struct {
+	key   string
+	env   string
+	flag  string
+	usage string
+	value func(c *Config) flag.Value
+}
+
+// fields lists every overridable setting
+var fields = []field{
+	{"server.addr", "UMS_ADDR", "addr", "`address` to listen on",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Server.Addr) }},
+	{"server.cookie_secure", "UMS_COOKIE_SECURE", "cookie-secure", "send the session cookie over HTTPS only",
+		func(c *Config) flag.Value { return (*boolValue)(&c.Server.CookieSecure) }},
+	{"server.cors_origins", "UMS_CORS_ORIGINS", "cors-origins", "comma separated `origins` allowed to send credentials",
+		func(c *Config) flag.Value { return (*listValue)(&c.Server.CORSOrigins) }},
+	{"server.read_timeout", "UMS_READ_TIMEOUT", "read-timeout", "maximum time to read a request (`duration`)",
+		func(c *Config) flag.Value { return (*durationValue)(&c.Server.ReadTimeout) }},
+	{"server.write_timeout", "UMS_WRITE_TIMEOUT", "write-timeout", "maximum time to handle a request and write the response (`duration`)",
+		func(c *Config) flag.Value { return (*durationValue)(&c.Server.WriteTimeout) }},
+	{"server.idle_timeout", "UMS_IDLE_TIMEOUT", "idle-timeout", "how long idle keep-alive connections stay open (`duration`)",
+		func(c *Config) flag.Value { return (*durationValue)(&c.Server.IdleTimeout) }},
+	{"server.drain_delay", "UMS_DRAIN_DELAY", "drain-delay", "how long to keep serving after reporting not ready on shutdown (`duration`)",
+		func(c *Config) flag.Value { return (*durationValue)(&c.Server.DrainDelay) }},
+	{"server.shutdown_timeout", "UMS_SHUTDOWN_TIMEOUT", "shutdown-timeout", "how long to wait for in-flight requests on shutdown (`duration`)",
+		func(c *Config) flag.Value { return (*durationValue)(&c.Server.ShutdownTimeout) }},
+	{"server.cleanup_interval", "UMS_CLEANUP_INTERVAL", "cleanup-interval", "how often to delete expired sessions and tokens, 0 to disable (`duration`)",
+		func(c *Config) flag.Value { return (*durationValue)(&c.Server.CleanupInterval) }},
+	{"server.max_body_bytes", "UMS_MAX_BODY_BYTES", "max-body-bytes", "largest request body accepted, in `bytes`",
+		func(c *Config) flag.Value { return (*intValue)(&c.Server.MaxBodyBytes) }},
+	{"database.url", "UMS_DATABASE_URL", "database-url", "Postgres connection `url` or key=value string",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Database.URL) }},
+	{"database.auto_migrate", "UMS_AUTO_MIGRATE", "auto-migrate", "apply pending migrations on startup",
+		func(c *Config) flag.Value { return (*boolValue)(&c.Database.AutoMigrate) }},
+	{"database.max_open_conns", "UMS_DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum open database connections, 0 for unlimited",
+		func(c *Config) flag.Value { return (*intValue)(&c.Database.MaxOpenConns) }},
+	{"database.max_idle_conns", "UMS_DB_MAX_IDLE_CONNS", "db-max-idle-conns", "idle database connections kept open",
+		func(c *Config) flag.Value { return (*intValue)(&c.Database.MaxIdleConns) }},
+	{"auth.jwt_keys", "UMS_JWT_KEYS", "jwt-keys", "comma separated kid=path signing `keys`, newest first",
+		func(c *Config) flag.Value { return (*listValue)(&c.Auth.JWTKeys) }},
+	{"auth.token_ttl", "UMS_JWT_TTL", "jwt-ttl", "access token lifetime (`duration`)",
+		func(c *Config) flag.Value { return (*durationValue)(&c.Auth.TokenTTL) }},
+	{"auth.require_verified_email", "UMS_REQUIRE_VERIFIED_EMAIL", "require-verified-email", "refuse to sign in users with an unverified email address",
+		func(c *Config) flag.Value { return (*boolValue)(&c.Auth.RequireVerifiedEmail) }},
+	{"auth.verification_ttl", "UMS_VERIFICATION_TTL", "verification-ttl", "how long email verification links can be used (`duration`)",
+		func(c *Config) flag.Value { return (*durationValue)(&c.Auth.VerificationTTL) }},
+	{"auth.password_reset_ttl", "UMS_PASSWORD_RESET_TTL", "password-reset-ttl", "how long password reset links can be used (`duration`)",
+		func(c *Config) flag.Value { return (*durationValue)(&c.Auth.PasswordResetTTL) }},
+	{"auth.encryption_key", "UMS_ENCRYPTION_KEY", "encryption-key", "base64 AES-256 `key` encrypting two-factor secrets",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Auth.EncryptionKey) }},
+	{"auth.mfa_issuer", "UMS_MFA_ISSUER", "mfa-issuer", "service `name` shown in authenticator apps",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Auth.MFAIssuer) }},
+	{"auth.passkey_rp_id", "UMS_PASSKEY_RP_ID", "passkey-rp-id", "`domain` passkeys are bound to",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Auth.PasskeyRPID) }},
+	{"auth.passkey_origins", "UMS_PASSKEY_ORIGINS", "passkey-origins", "comma separated frontend `origins` allowed to use passkeys",
+		func(c *Config) flag.Value { return (*listValue)(&c.Auth.PasskeyOrigins) }},
+	{"auth.require_admin_passkey", "UMS_REQUIRE_ADMIN_PASSKEY", "require-admin-passkey", "require every admin to sign in with a passkey",
+		func(c *Config) flag.Value { return (*boolValue)(&c.Auth.RequireAdminPasskey) }},
+	{"health.timeout", "UMS_HEALTH_TIMEOUT", "health-timeout", "time limit for each readiness check (`duration`)",
+		func(c *Config) flag.Value { return (*durationValue)(&c.Health.Timeout) }},
+	{"health.max_pool_usage", "UMS_HEALTH_MAX_POOL_USAGE", "health-max-pool-usage", "database pool `fraction` in use above which the server is not ready",
+		func(c *Config) flag.Value { return (*floatValue)(&c.Health.MaxPoolUsage) }},
+	{"metrics.enabled", "UMS_METRICS_ENABLED", "metrics", "expose Prometheus metrics",
+		func(c *Config) flag.Value { return (*boolValue)(&c.Metrics.Enabled) }},
+	{"metrics.addr", "UMS_METRICS_ADDR", "metrics-addr", "separate `address` for /metrics instead of the API address",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Metrics.Addr) }},
+	{"log.level", "UMS_LOG_LEVEL", "log-level", "minimum `level` to log: debug, info, warn or error",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Log.Level) }},
+	{"log.format", "UMS_LOG_FORMAT", "log-format", "log `format`: json or text",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Log.Format) }},
+	{"mail.driver", "UMS_MAIL_DRIVER", "mail-driver", "how to send email: smtp, file or memory",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Mail.Driver) }},
+	{"mail.from", "UMS_MAIL_FROM", "mail-from", "sender `address` of outgoing email",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Mail.From) }},
+	{"mail.app_url", "UMS_APP_URL", "app-url", "frontend `url` that links in emails point to",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Mail.AppURL) }},
+	{"mail.smtp_addr", "UMS_SMTP_ADDR", "smtp-addr", "SMTP server host:port `address`",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Mail.SMTPAddr) }},
+	{"mail.smtp_username", "UMS_SMTP_USERNAME", "smtp-username", "SMTP `username`, empty to send without authentication",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Mail.SMTPUsername) }},
+	{"mail.smtp_password", "UMS_SMTP_PASSWORD", "smtp-password", "SMTP `password`",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Mail.SMTPPassword) }},
+	{"mail.dir", "UMS_MAIL_DIR", "mail-dir", "`directory` the file driver writes .eml files to",
+		func(c *Config) flag.Value { return (*stringValue)(&c.Mail.Dir) }},
+}
+
+func fieldByFlag(name string) (field, bool) {
+	for _, f := range fields {
+		if f.flag == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func isBoolValue(v flag.Value) bool {
+	b, ok := v.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// rawFlag records a flag's text so it can be applied after the config file
+// and environment, whatever order they are read in
+type rawFlag struct {
+	s      string
+	isBool bool
+}
+
+func (f *rawFlag) String() string     { return f.s }
+func (f *rawFlag) Set(s string) error { f.s = s; return nil }
+func (f *rawFlag) IsBoolFlag() bool   { return f.isBool }
+
+type stringValue string
+
+func (v *stringValue) String() string     { return string(*v) }
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+
+type boolValue bool
+
+func (v *boolValue) String() string   { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) IsBoolFlag() bool { return true }
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+type intValue int
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v = intValue(n)
+	return nil
+}
+
+type floatValue float64
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+type durationValue time.Duration
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+// listValue is a comma separated list; blank entries are dropped
+type listValue []string
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+func (v *listValue) Set(s string) error {
+	*v = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*v = append(*v, item)
+		}
+	}
+	return nil
+}
+
+// dsnPassword matches the password in a key=value connection string
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactDatabaseURL masks the password in a postgres:// URL or key=value
+// connection string
+func redactDatabaseURL(s string) string {
+	if strings.HasPrefix(s, "postgres://") || strings.HasPrefix(s, "postgresql://") {
+		if u, err := url.Parse(s); err == nil {
+			return u.Redacted()
+		}
+	}
+	return dsnPassword.ReplaceAllString(s, "${1}xxxxx")
+}
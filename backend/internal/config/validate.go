@@ -0,0 +1,203 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/secretbox"
+)
+
+// MaxTokenTTL caps the access token lifetime; longer sessions should rely on
+// refresh tokens, which can be revoked
+const MaxTokenTTL = 24 * time.Hour
+
+// FieldError is an invalid setting
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError lists every invalid setting
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid configuration:")
+	for _, f := range e {
+		fmt.Fprintf(&b, "\n  %s: %s", f.Field, f.Message)
+	}
+	return b.String()
+}
+
+// Validate checks every setting and returns a ValidationError listing all
+// problems, or nil
+func (c *Config) Validate() error {
+	var errs ValidationError
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if msg := checkAddr(c.Server.Addr); msg != "" {
+		add("server.addr", "%s", msg)
+	}
+	for _, origin := range c.Server.CORSOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			add("server.cors_origins", "%q is not an origin such as https://example.com", origin)
+		}
+	}
+	for _, d := range []struct {
+		field string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	} {
+		if d.value <= 0 {
+			add(d.field, "must be positive")
+		}
+	}
+	if c.Server.DrainDelay < 0 {
+		add("server.drain_delay", "must not be negative")
+	}
+	if c.Server.CleanupInterval < 0 {
+		add("server.cleanup_interval", "must not be negative")
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		add("server.max_body_bytes", "must be positive")
+	}
+
+	if c.Database.URL == "" {
+		add("database.url", "is required")
+	} else if strings.Contains(c.Database.URL, "://") {
+		if _, err := url.Parse(c.Database.URL); err != nil {
+			add("database.url", "is not a valid URL")
+		}
+	}
+	if c.Database.MaxOpenConns < 0 {
+		add("database.max_open_conns", "must not be negative")
+	}
+	if c.Database.MaxIdleConns < 0 {
+		add("database.max_idle_conns", "must not be negative")
+	}
+
+	kids := map[string]bool{}
+	for _, entry := range c.Auth.JWTKeys {
+		kid, path, ok := strings.Cut(entry, "=")
+		switch {
+		case !ok || kid == "" || path == "":
+			add("auth.jwt_keys", "entry %q must be kid=path", entry)
+		case kids[kid]:
+			add("auth.jwt_keys", "key id %q is used twice", kid)
+		default:
+			if _, err := os.Stat(path); err != nil {
+				add("auth.jwt_keys", "key %q: %v", kid, err)
+			}
+		}
+		kids[kid] = true
+	}
+	if c.Auth.TokenTTL <= 0 || c.Auth.TokenTTL > MaxTokenTTL {
+		add("auth.token_ttl", "must be between 0 and %s", MaxTokenTTL)
+	}
+	if c.Auth.VerificationTTL <= 0 {
+		add("auth.verification_ttl", "must be positive")
+	}
+	if c.Auth.PasswordResetTTL <= 0 {
+		add("auth.password_reset_ttl", "must be positive")
+	}
+	if c.Auth.EncryptionKey != "" {
+		if _, err := secretbox.ParseKey(c.Auth.EncryptionKey); err != nil {
+			add("auth.encryption_key", "must be %d bytes in base64, e.g. from `openssl rand -base64 %d`", secretbox.KeySize, secretbox.KeySize)
+		}
+	}
+	if c.Auth.MFAIssuer == "" || strings.Contains(c.Auth.MFAIssuer, ":") {
+		add("auth.mfa_issuer", "is required and may not contain a colon")
+	}
+	if u, err := url.Parse("https://" + c.Auth.PasskeyRPID); c.Auth.PasskeyRPID == "" || err != nil || u.Host != c.Auth.PasskeyRPID || u.Port() != "" {
+		add("auth.passkey_rp_id", "%q is not a domain such as example.com", c.Auth.PasskeyRPID)
+	}
+	if len(c.Auth.PasskeyOrigins) == 0 {
+		add("auth.passkey_origins", "is required")
+	}
+	for _, origin := range c.Auth.PasskeyOrigins {
+		u, err := url.Parse(origin)
+		switch {
+		case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/"):
+			add("auth.passkey_origins", "%q is not an origin such as https://example.com", origin)
+		case u.Hostname() != c.Auth.PasskeyRPID && !strings.HasSuffix(u.Hostname(), "."+c.Auth.PasskeyRPID):
+			add("auth.passkey_origins", "%q is not on auth.passkey_rp_id or a subdomain of it", origin)
+		}
+	}
+
+	if c.Health.Timeout <= 0 {
+		add("health.timeout", "must be positive")
+	}
+	if c.Health.MaxPoolUsage <= 0 || c.Health.MaxPoolUsage > 1 {
+		add("health.max_pool_usage", "must be greater than 0 and at most 1")
+	}
+
+	if c.Metrics.Addr != "" {
+		if msg := checkAddr(c.Metrics.Addr); msg != "" {
+			add("metrics.addr", "%s", msg)
+		} else if c.Metrics.Addr == c.Server.Addr {
+			add("metrics.addr", "must differ from server.addr; leave it empty to serve /metrics on the API address")
+		}
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		add("log.level", "%q is not one of debug, info, warn or error", c.Log.Level)
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "json", "text":
+	default:
+		add("log.format", "%q is not json or text", c.Log.Format)
+	}
+
+	switch c.Mail.Driver {
+	case "smtp":
+		if msg := checkAddr(c.Mail.SMTPAddr); msg != "" {
+			add("mail.smtp_addr", "%s", msg)
+		}
+	case "file":
+		if c.Mail.Dir == "" {
+			add("mail.dir", "is required by the file driver")
+		}
+	case "memory":
+	default:
+		add("mail.driver", "%q is not one of smtp, file or memory", c.Mail.Driver)
+	}
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		add("mail.from", "%q is not an email address", c.Mail.From)
+	}
+	if u, err := url.Parse(c.Mail.AppURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		add("mail.app_url", "%q is not a URL such as https://example.com", c.Mail.AppURL)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkAddr describes what is wrong with a host:port listen address, or
+// returns ""
+func checkAddr(addr string) string {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Sprintf("%q is not a host:port address", addr)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Sprintf("invalid port %q", port)
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	key := filepath.Join(t.TempDir(), "k1.pem")
+	if err := os.WriteFile(key, []byte("key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   []string
+	}{
+		{"defaults", func(c *Config) {}, nil},
+		{"bad addr", func(c *Config) { c.Server.Addr = "8080" }, []string{"server.addr"}},
+		{"bad port", func(c *Config) { c.Server.Addr = ":http" }, []string{"server.addr"}},
+		{"port out of range", func(c *Config) { c.Server.Addr = ":70000" }, []string{"server.addr"}},
+		{"cors origin with path", func(c *Config) { c.Server.CORSOrigins = []string{"https://example.com/app"} }, []string{"server.cors_origins"}},
+		{"cors origin without scheme", func(c *Config) { c.Server.CORSOrigins = []string{"example.com"} }, []string{"server.cors_origins"}},
+		{"zero timeouts", func(c *Config) {
+			c.Server.ReadTimeout, c.Server.WriteTimeout, c.Server.IdleTimeout, c.Server.ShutdownTimeout = 0, 0, 0, 0
+		}, []string{"server.read_timeout", "server.write_timeout", "server.idle_timeout", "server.shutdown_timeout"}},
+		{"negative delays", func(c *Config) { c.Server.DrainDelay, c.Server.CleanupInterval = -1, -1 }, []string{"server.drain_delay", "server.cleanup_interval"}},
+		{"zero cleanup interval", func(c *Config) { c.Server.CleanupInterval = 0 }, nil},
+		{"zero body size", func(c *Config) { c.Server.MaxBodyBytes = 0 }, []string{"server.max_body_bytes"}},
+		{"no database", func(c *Config) { c.Database.URL = "" }, []string{"database.url"}},
+		{"bad database url", func(c *Config) { c.Database.URL = "postgres://db:port/ums" }, []string{"database.url"}},
+		{"negative pool", func(c *Config) { c.Database.MaxOpenConns, c.Database.MaxIdleConns = -1, -1 }, []string{"database.max_open_conns", "database.max_idle_conns"}},
+		{"jwt key", func(c *Config) { c.Auth.JWTKeys = []string{"k1=" + key} }, nil},
+		{"jwt key without kid", func(c *Config) { c.Auth.JWTKeys = []string{key} }, []string{"auth.jwt_keys"}},
+		{"jwt key used twice", func(c *Config) { c.Auth.JWTKeys = []string{"k1=" + key, "k1=" + key} }, []string{"auth.jwt_keys"}},
+		{"missing jwt key file", func(c *Config) { c.Auth.JWTKeys = []string{"k1=" + key + ".missing"} }, []string{"auth.jwt_keys"}},
+		{"token ttl too long", func(c *Config) { c.Auth.TokenTTL = MaxTokenTTL + time.Second }, []string{"auth.token_ttl"}},
+		{"zero link ttls", func(c *Config) { c.Auth.VerificationTTL, c.Auth.PasswordResetTTL = 0, 0 }, []string{"auth.verification_ttl", "auth.password_reset_ttl"}},
+		{"encryption key", func(c *Config) { c.Auth.EncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" }, nil},
+		{"short encryption key", func(c *Config) { c.Auth.EncryptionKey = "c2hvcnQ=" }, []string{"auth.encryption_key"}},
+		{"issuer with colon", func(c *Config) { c.Auth.MFAIssuer = "UMS:prod" }, []string{"auth.mfa_issuer"}},
+		{"rp id with port", func(c *Config) { c.Auth.PasskeyRPID = "example.com:443" }, []string{"auth.passkey_rp_id", "auth.passkey_origins", "auth.passkey_origins"}},
+		{"passkey origin on subdomain", func(c *Config) {
+			c.Auth.PasskeyRPID = "example.com"
+			c.Auth.PasskeyOrigins = []string{"https://app.example.com"}
+		}, nil},
+		{"passkey origin off the rp id", func(c *Config) {
+			c.Auth.PasskeyRPID = "example.com"
+			c.Auth.PasskeyOrigins = []string{"https://notexample.com"}
+		}, []string{"auth.passkey_origins"}},
+		{"no passkey origins", func(c *Config) { c.Auth.PasskeyOrigins = nil }, []string{"auth.passkey_origins"}},
+		{"health", func(c *Config) { c.Health.Timeout, c.Health.MaxPoolUsage = 0, 1.5 }, []string{"health.timeout", "health.max_pool_usage"}},
+		{"metrics addr", func(c *Config) { c.Metrics.Addr = ":9090" }, nil},
+		{"metrics on the api addr", func(c *Config) { c.Metrics.Addr = c.Server.Addr }, []string{"metrics.addr"}},
+		{"log level case", func(c *Config) { c.Log.Level, c.Log.Format = "DEBUG", "Text" }, nil},
+		{"bad log settings", func(c *Config) { c.Log.Level, c.Log.Format = "trace", "xml" }, []string{"log.level", "log.format"}},
+		{"smtp without addr", func(c *Config) { c.Mail.Driver, c.Mail.SMTPAddr = "smtp", "" }, []string{"mail.smtp_addr"}},
+		{"file without dir", func(c *Config) { c.Mail.Dir = "" }, []string{"mail.dir"}},
+		{"unknown driver", func(c *Config) { c.Mail.Driver = "sendmail" }, []string{"mail.driver"}},
+		{"bad mail addresses", func(c *Config) { c.Mail.From, c.Mail.AppURL = "nobody", "localhost:5173" }, []string{"mail.from", "mail.app_url"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.change(cfg)
+			err := cfg.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() = %v", err)
+				}
+				return
+			}
+			if got := fieldErrors(t, err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorListsEveryField(t *testing.T) {
+	err := ValidationError{
+		{Field: "server.addr", Message: `"x" is not a host:port address`},
+		{Field: "log.level", Message: `"trace" is not one of debug, info, warn or error`},
+	}
+	want := "invalid configuration:\n  server.addr: \"x\" is not a host:port address\n  log.level: \"trace\" is not one of debug, info, warn or error"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
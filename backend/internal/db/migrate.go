@@ -0,0 +1,439 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// embeddedMigrations holds the migrations shipped with the binary
+//
+//go:embed migrations/*.sql
+var embeddedMigrations embed.FS
+
+// migrationLockKey identifies the Postgres advisory lock held while migrating
+const migrationLockKey int64 = 0x756d735f6d6967 // "ums_mig"
+
+// migrationFile matches migration file names such as 0003_create_sessions.up.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrChecksumMismatch is returned when an applied migration was edited afterwards
+var ErrChecksumMismatch = errors.New("applied migration has been modified")
+
+// ErrIrreversible is returned when rolling back a migration without a down script
+var ErrIrreversible = errors.New("migration cannot be rolled back")
+
+// Migration states reported by Status
+const (
+	MigrationApplied  = "applied"
+	MigrationPending  = "pending"
+	MigrationModified = "modified"
+	MigrationMissing  = "missing"
+)
+
+// Migration is a numbered schema change. Down is empty for irreversible
+// migrations. Checksum is the SHA-256 of Up.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Reversible reports whether the migration has a down script
+func (m Migration) Reversible() bool {
+	return m.Down != ""
+}
+
+// String returns the migration's file name stem, e.g. 0003_create_sessions
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus describes a known or applied migration. Missing migrations
+// were applied by a newer build and have no file in this one.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+// Hook is a step of a migration written in Go, for data changes SQL cannot
+// express. It runs in the transaction of the migration, right after its up
+// script, so it runs exactly once.
+type Hook func(ctx context.Context, tx *sql.Tx) error
+
+// Hooks maps migration versions to the hooks run when they are applied
+type Hooks map[int64]Hook
+
+// Migrator applies and rolls back migrations, recording them in the
+// schema_migrations table. Runs are serialized across processes with a
+// Postgres advisory lock, and every migration runs in its own transaction.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	hooks      Hooks
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// NewMigrator returns a migrator for the migrations embedded in the binary
+func NewMigrator(database *sql.DB) (*Migrator, error) {
+	return NewMigratorFS(database, embeddedMigrations, "migrations")
+}
+
+// NewMigratorFS returns a migrator for the migrations in dir of fsys
+func NewMigratorFS(database *sql.DB, fsys fs.FS, dir string) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: database, migrations: migrations}, nil
+}
+
+// LoadMigrations reads the migrations in dir of fsys, ordered by version.
+// Every version needs an up script; the down script is optional.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+			m.Checksum = checksum(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %s has no up script", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// SetHooks sets the hooks run when migrations are applied
+func (m *Migrator) SetHooks(hooks Hooks) {
+	m.hooks = hooks
+}
+
+// Migrations returns the known migrations, oldest first
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+// It refuses to run if an applied migration has been modified.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := apply(ctx, conn, mig, true, m.hooks[mig.Version]); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the latest steps applied migrations, newest first, and
+// returns the ones rolled back. If any of them is irreversible nothing is
+// rolled back and the error wraps ErrIrreversible.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		var todo []Migration
+		for i := len(m.migrations) - 1; i >= 0 && len(todo) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if !mig.Reversible() {
+				return fmt.Errorf("%s: %w", mig, ErrIrreversible)
+			}
+			todo = append(todo, mig)
+		}
+
+		for _, mig := range todo {
+			if err := apply(ctx, conn, mig, false, nil); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Pending returns the migrations that have not been applied yet
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, s := range statuses {
+		if s.State == MigrationPending {
+			pending = append(pending, m.byVersion(s.Version))
+		}
+	}
+	return pending, nil
+}
+
+// Status reports the state of every known or applied migration, ordered by
+// version. It only reads schema_migrations, so readiness probes can call it;
+// every migration is pending while the table does not exist.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	applied, err := readApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Version: mig.Version, Name: mig.Name, State: MigrationPending}
+		if a, ok := applied[mig.Version]; ok {
+			appliedAt := a.appliedAt
+			s.AppliedAt = &appliedAt
+			s.State = MigrationApplied
+			if a.checksum != mig.Checksum {
+				s.State = MigrationModified
+			}
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for version, a := range applied {
+		appliedAt := a.appliedAt
+		statuses = append(statuses, MigrationStatus{Version: version, Name: a.name, State: MigrationMissing, AppliedAt: &appliedAt})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// verify returns ErrChecksumMismatch if an applied migration differs from its file
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+	for _, mig := range m.migrations {
+		if a, ok := applied[mig.Version]; ok && a.checksum != mig.Checksum {
+			return fmt.Errorf("%s: %w", mig, ErrChecksumMismatch)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) byVersion(version int64) Migration {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig
+		}
+	}
+	return Migration{Version: version}
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory
+// lock, waiting for other runners to finish first
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	return fn(conn)
+}
+
+// loadApplied creates schema_migrations if needed and returns its rows by version
+func loadApplied(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return readApplied(ctx, conn)
+}
+
+// readApplied returns the rows of schema_migrations by version, or none if
+// the table does not exist
+func readApplied(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	var exists bool
+	err := conn.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int64]appliedMigration{}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// apply runs the up or down script of mig, then hook if set, and records the
+// result in one transaction
+func apply(ctx context.Context, conn *sql.Conn, mig Migration, up bool, hook Hook) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	script := mig.Up
+	if !up {
+		script = mig.Down
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("%s: %w", mig, err)
+	}
+	if hook != nil {
+		if err := hook(ctx, tx); err != nil {
+			return fmt.Errorf("%s: %w", mig, err)
+		}
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`,
+			mig.Version, mig.Name, mig.Checksum, time.Now(),
+		)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateMigration writes empty up and down scripts for a new migration named
+// name into dir, numbered after the newest existing one, and returns their paths
+func CreateMigration(dir, name string) (up, down string, err error) {
+	if !regexp.MustCompile(`^[a-z0-9_]+$`).MatchString(name) {
+		return "", "", errors.New("migration name may only contain lower-case letters, digits and underscores")
+	}
+
+	migrations, err := LoadMigrations(os.DirFS(dir), ".")
+	if err != nil {
+		return "", "", fmt.Errorf("reading migrations in %s: %w", dir, err)
+	}
+	next := Migration{Version: 1, Name: name}
+	if len(migrations) > 0 {
+		next.Version = migrations[len(migrations)-1].Version + 1
+	}
+
+	up = filepath.Join(dir, next.String()+".up.sql")
+	down = filepath.Join(dir, next.String()+".down.sql")
+	if err := os.WriteFile(up, []byte("-- "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte("-- Revert "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
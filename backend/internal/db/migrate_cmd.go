@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultMigrationsDir is where `migrate create` writes new migrations,
+// relative to the backend directory
+const DefaultMigrationsDir = "internal/db/migrations"
+
+// MigrateUsage describes the migrate subcommand
+const MigrateUsage = `usage: migrate <command>
+
+commands:
+  up                 apply all pending migrations
+  down [n]           roll back the last n migrations (default 1); fails
+                     without changes if one of them is irreversible
+  status             show applied and pending migrations
+  create [-dir d] n  add empty up/down scripts for migration n`
+
+// MigrateCommand runs the migrate subcommand with args. open is called to
+// connect to the database for commands that need it. hooks run when their
+// migrations are applied.
+func MigrateCommand(ctx context.Context, args []string, open func() (*sql.DB, error), hooks Hooks, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(MigrateUsage)
+	}
+
+	if args[0] == "create" {
+		fs := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+		fs.SetOutput(out)
+		dir := fs.String("dir", DefaultMigrationsDir, "migrations directory")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New(MigrateUsage)
+		}
+		up, down, err := CreateMigration(*dir, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "created %s\ncreated %s\n", up, down)
+		return nil
+	}
+
+	database, err := open()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	m, err := NewMigrator(database)
+	if err != nil {
+		return err
+	}
+	m.SetHooks(hooks)
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			fmt.Fprintf(out, "applied %s\n", mig)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New("down takes a positive number of migrations")
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, mig := range reverted {
+			fmt.Fprintf(out, "rolled back %s\n", mig)
+		}
+		return err
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED AT\tREVERSIBLE")
+		for _, s := range statuses {
+			appliedAt := "-"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			reversible := "yes"
+			if s.State == MigrationMissing {
+				reversible = "-"
+			} else if !m.byVersion(s.Version).Reversible() {
+				reversible = "no"
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\t%s\n", s.Version, s.Name, s.State, appliedAt, reversible)
+		}
+		return tw.Flush()
+	}
+
+	return errors.New(MigrateUsage)
+}
+
+// MigrateOnStart applies pending migrations, running hooks, when apply is set
+// and otherwise only warns about them
+func MigrateOnStart(ctx context.Context, database *sql.DB, apply bool, hooks Hooks) error {
+	m, err := NewMigrator(database)
+	if err != nil {
+		return err
+	}
+	m.SetHooks(hooks)
+
+	if apply {
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			slog.Info("applied migration", "migration", mig.String())
+		}
+		return err
+	}
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		slog.Warn("pending migrations; run `ums migrate up` or set database.auto_migrate", "count", len(pending))
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_add_b.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"m/0001_add_a.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+		"m/0001_add_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"m/README.md":           {Data: []byte("not a migration")},
+		"m/0003_Bad.up.sql":     {Data: []byte("ignored: upper-case name")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("loaded %d migrations, want 2", len(migrations))
+	}
+
+	a, b := migrations[0], migrations[1]
+	if a.String() != "0001_add_a" || b.String() != "0002_add_b" {
+		t.Errorf("migrations = %s, %s, want them ordered by version", a, b)
+	}
+	if !a.Reversible() || b.Reversible() {
+		t.Errorf("Reversible = %v, %v, want true, false", a.Reversible(), b.Reversible())
+	}
+
+	sum := sha256.Sum256([]byte("CREATE TABLE a ();"))
+	if a.Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("checksum = %s, want SHA-256 of the up script", a.Checksum)
+	}
+}
+
+func TestLoadMigrationsChecksumIgnoresDown(t *testing.T) {
+	load := func(down string) string {
+		fsys := fstest.MapFS{
+			"0001_a.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+			"0001_a.down.sql": {Data: []byte(down)},
+		}
+		migrations, err := LoadMigrations(fsys, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return migrations[0].Checksum
+	}
+
+	if load("DROP TABLE a;") != load("DROP TABLE IF EXISTS a;") {
+		t.Error("editing a down script changed the checksum")
+	}
+}
+
+func TestLoadMigrationsChecksumDetectsEdits(t *testing.T) {
+	load := func(up string) string {
+		migrations, err := LoadMigrations(fstest.MapFS{"0001_a.up.sql": {Data: []byte(up)}}, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return migrations[0].Checksum
+	}
+
+	if load("CREATE TABLE a ();") == load("CREATE TABLE a (id INT);") {
+		t.Error("editing an up script kept the checksum")
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing up": {
+			"0001_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		},
+		"two names": {
+			"0001_a.up.sql": {Data: []byte("CREATE TABLE a ();")},
+			"0001_b.up.sql": {Data: []byte("CREATE TABLE b ();")},
+		},
+	}
+	for name, fsys := range tests {
+		if _, err := LoadMigrations(fsys, "."); err == nil {
+			t.Errorf("%s: LoadMigrations succeeded, want an error", name)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(embeddedMigrations, "migrations")
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+
+	for i, mig := range migrations {
+		if mig.Version != int64(i+1) {
+			t.Errorf("migration %s: want version %d, versions must have no gaps", mig, i+1)
+		}
+		if !mig.Reversible() {
+			t.Errorf("migration %s has no down script", mig)
+		}
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "0007_existing.up.sql"), []byte("SELECT 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	up, down, err := CreateMigration(dir, "add_things")
+	if err != nil {
+		t.Fatalf("CreateMigration: %v", err)
+	}
+	if filepath.Base(up) != "0008_add_things.up.sql" || filepath.Base(down) != "0008_add_things.down.sql" {
+		t.Errorf("CreateMigration wrote %s and %s", up, down)
+	}
+
+	migrations, err := LoadMigrations(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || !migrations[1].Reversible() {
+		t.Errorf("created migration did not load as a reversible migration: %+v", migrations)
+	}
+
+	if _, _, err := CreateMigration(dir, "Bad Name"); err == nil || !strings.Contains(err.Error(), "lower-case") {
+		t.Errorf("CreateMigration with a bad name: err = %v", err)
+	}
+}
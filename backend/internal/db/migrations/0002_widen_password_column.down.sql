@@ -0,0 +1,2 @@
+-- Revert widen_password_column: the column keeps its width, since stored
+-- hashes would no longer fit a narrower one
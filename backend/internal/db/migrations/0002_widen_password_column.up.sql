@@ -0,0 +1,4 @@
+-- Widen the password column on existing databases so encoded hashes fit.
+-- Plaintext passwords left by the old schema are not hashed here: the
+-- application accepts them and rehashes them on the next successful login.
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR(255);
@@ -0,0 +1,18 @@
+-- Server-side login sessions. id is the SHA-256 of the cookie token.
+CREATE TABLE IF NOT EXISTS sessions (
+    id CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    roles TEXT[] NOT NULL DEFAULT '{}',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+
+-- Sessions used to store a single role name
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE sessions DROP COLUMN IF EXISTS role;
@@ -0,0 +1,61 @@
+-- Roles and permissions. users.is_admin is kept in sync with membership of
+-- the built-in admin role, which holds every permission.
+CREATE TABLE IF NOT EXISTS roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(100) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_roles_role_id_idx ON user_roles (role_id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:list', 'List all users'),
+    ('users:read', 'Read any user'),
+    ('users:read:own', 'Read your own user record'),
+    ('users:update', 'Update any user'),
+    ('users:update:own', 'Update your own user record'),
+    ('users:delete', 'Delete any user'),
+    ('roles:manage', 'Create, update and delete roles'),
+    ('roles:assign', 'Assign roles to users')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description, built_in, created_at, updated_at) VALUES
+    ('admin', 'Full access to every resource', TRUE, NOW(), NOW()),
+    ('user', 'Default role of registered users', TRUE, NOW(), NOW())
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.name FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.name FROM roles r
+JOIN permissions p ON p.name IN ('users:read:own', 'users:update:own')
+WHERE r.name = 'user'
+ON CONFLICT DO NOTHING;
+
+-- Map existing is_admin users to the built-in admin role
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id FROM users u JOIN roles r ON r.name = 'admin'
+WHERE u.is_admin
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DELETE FROM permissions WHERE name = 'stats:read';
+DROP TABLE IF EXISTS login_history;
@@ -0,0 +1,18 @@
+-- One row per successful login, used for the dashboard's active user count
+CREATE TABLE IF NOT EXISTS login_history (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS login_history_created_at_idx ON login_history (created_at);
+
+INSERT INTO permissions (name, description) VALUES
+    ('stats:read', 'View dashboard statistics')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, 'stats:read' FROM roles r WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
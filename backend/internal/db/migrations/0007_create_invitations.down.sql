@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE name = 'users:create';
+DROP TABLE IF EXISTS invitations;
+DELETE FROM users WHERE status = 'pending';
+ALTER TABLE users DROP COLUMN IF EXISTS status;
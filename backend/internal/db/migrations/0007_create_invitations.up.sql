@@ -0,0 +1,24 @@
+-- Invited users are pending until they redeem their invitation and set a
+-- password; until then their password is empty
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active';
+
+-- Single-use invitations for pending users. Only SHA-256 hashes of the
+-- tokens are stored.
+CREATE TABLE IF NOT EXISTS invitations (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS invitations_user_id_idx ON invitations (user_id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:create', 'Create and invite users')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, 'users:create' FROM roles r WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_created_at_idx;
+DROP INDEX IF EXISTS users_email_domain_idx;
+DROP INDEX IF EXISTS users_username_lower_idx;
@@ -0,0 +1,4 @@
+-- Indexes backing the filters and sort orders of GET /api/users
+CREATE INDEX IF NOT EXISTS users_username_lower_idx ON users (lower(username) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS users_email_domain_idx ON users (lower(split_part(email, '@', 2)));
+CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at, id);
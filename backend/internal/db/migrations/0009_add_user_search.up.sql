@@ -0,0 +1,25 @@
+-- pg_trgm provides trigram similarity for fuzzy user search. Where it cannot
+-- be installed, e.g. without the privilege to create extensions, the
+-- trigram indexes are skipped and the server ranks searches in process.
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION WHEN OTHERS THEN
+    RAISE NOTICE 'pg_trgm is not available: %', SQLERRM;
+END
+$$;
+
+-- Full-text and trigram indexes for GET /api/users/search. Email parts are
+-- indexed as separate words.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', username || ' ' || translate(email, '@.', '  '))) STORED;
+CREATE INDEX IF NOT EXISTS users_search_vector_idx ON users USING gin (search_vector);
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        CREATE INDEX IF NOT EXISTS users_username_trgm_idx ON users USING gin (username gin_trgm_ops);
+        CREATE INDEX IF NOT EXISTS users_email_trgm_idx ON users USING gin (email gin_trgm_ops);
+    END IF;
+END
+$$;
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_fold_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_fold_key;
+ALTER TABLE users DROP COLUMN IF EXISTS email_fold;
+ALTER TABLE users DROP COLUMN IF EXISTS username_fold;
@@ -0,0 +1,12 @@
+-- Usernames and emails are unique regardless of case. The keys are written
+-- by the application with Unicode case folding; lower() backfills existing
+-- rows, which matches for all but a few non-ASCII characters. Existing users
+-- whose names differ only in case must be renamed before this migration.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_fold TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_fold TEXT;
+UPDATE users SET username_fold = lower(username), email_fold = lower(email)
+    WHERE username_fold IS NULL OR email_fold IS NULL;
+ALTER TABLE users ALTER COLUMN username_fold SET NOT NULL;
+ALTER TABLE users ALTER COLUMN email_fold SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_username_fold_key UNIQUE (username_fold);
+ALTER TABLE users ADD CONSTRAINT users_email_fold_key UNIQUE (email_fold);
@@ -0,0 +1,22 @@
+-- When the user proved they own their email address; NULL until then and
+-- again after the address changes
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+-- Existing accounts predate verification. Their addresses count as verified,
+-- so that turning on auth.require_verified_email does not lock them out;
+-- invited users are verified when they accept their invitation.
+UPDATE users SET email_verified_at = CURRENT_TIMESTAMP
+WHERE email_verified_at IS NULL AND status = 'active';
+
+-- Single-use email verification tokens. Only SHA-256 hashes of the tokens
+-- are stored, and a token only verifies the address it was sent to.
+CREATE TABLE IF NOT EXISTS email_verifications (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(100) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS email_verifications_user_id_idx ON email_verifications (user_id);
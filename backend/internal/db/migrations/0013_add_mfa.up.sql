@@ -0,0 +1,39 @@
+-- TOTP second factor. The secret is encrypted with auth.encryption_key;
+-- confirmed_at is NULL until the user proves their app produces valid codes.
+-- last_step is the time step of the last accepted code, so codes cannot be
+-- replayed.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Single-use recovery codes, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- Sign-ins waiting for their second factor. Only SHA-256 hashes of the
+-- challenge tokens are stored.
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS mfa_challenges_user_id_idx ON mfa_challenges (user_id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('mfa:reset', 'Remove the two-factor authentication of other users')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, 'mfa:reset' FROM roles r WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,34 @@
+-- Passkeys (WebAuthn credentials). id is the credential ID chosen by the
+-- authenticator. sign_count is the last signature counter seen; a counter
+-- that does not increase suggests a cloned authenticator and sets
+-- clone_warning, after which the credential is refused.
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id BYTEA PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    public_key BYTEA NOT NULL,
+    attestation_type VARCHAR(50) NOT NULL DEFAULT '',
+    transports TEXT[] NOT NULL DEFAULT '{}',
+    aaguid BYTEA,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    clone_warning BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+-- Registration and sign-in ceremonies waiting for the authenticator's
+-- response. data holds the challenge the response must sign. user_id is
+-- NULL for sign-ins, where the authenticator names the user. Only SHA-256
+-- hashes of the ceremony tokens are stored.
+CREATE TABLE IF NOT EXISTS webauthn_ceremonies (
+    token_hash CHAR(64) PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    data TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
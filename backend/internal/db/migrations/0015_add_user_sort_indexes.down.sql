@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_email_id_idx;
+DROP INDEX IF EXISTS users_username_id_idx;
@@ -0,0 +1,5 @@
+-- GET /api/users sorts and pages by (column, id). The unique constraints on
+-- username and email index the column alone, which cannot serve the keyset
+-- comparison of the cursor; lower(username) only serves the prefix filter.
+CREATE INDEX IF NOT EXISTS users_username_id_idx ON users (username, id);
+CREATE INDEX IF NOT EXISTS users_email_id_idx ON users (email, id);
@@ -0,0 +1,5 @@
+-- Wrong second factor codes of a user since their last accepted one, across
+-- sign-in challenges and profile endpoints. MaxMFAFailures of them lock the
+-- second factor until locked_until.
+ALTER TABLE user_mfa ADD COLUMN IF NOT EXISTS failed_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE user_mfa ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
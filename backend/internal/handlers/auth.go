@@ -2,82 +2,184 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/metrics"
 	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/password"
 )
 
+// AuthRequest is the body of POST /api/register. The length limits match the
+// columns of the users table; passwords are 8 to 128 characters.
 type AuthRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Email    string `json:"email"`
+	Username string `json:"username" validate:"required,min=3,max=50,username"`
+	Password string `json:"password" validate:"required,min=8,max=128"`
+	Email    string `json:"email" validate:"required,max=100,email"`
 }
 
+// Normalize prepares the username and email for validation
+func (req *AuthRequest) Normalize() {
+	req.Username = models.NormalizeUsername(req.Username)
+	req.Email = models.NormalizeEmail(req.Email)
+}
+
+// LoginRequest is the body of POST /api/login. Only the lengths are limited,
+// so that users created under older rules can still sign in.
+type LoginRequest struct {
+	Username string `json:"username" validate:"required,max=50"`
+	Password string `json:"password" validate:"required,max=128"`
+}
+
+// AuthResponse is returned by every endpoint that signs a user in or renews
+// their tokens: the user with their effective roles, and the issued tokens
 type AuthResponse struct {
-	User  struct {
-		ID       string `json:"id"`
-		Username string `json:"username"`
-		Role     string `json:"role"`
-	} `json:"user"`
-	Token string `json:"token"`
+	User AuthUser `json:"user"`
+	Tokens
+}
+
+// AuthUser is a user record with its roles. Role is "admin" for
+// administrators and "user" otherwise.
+type AuthUser struct {
+	models.User
+	Role  string   `json:"role"`
+	Roles []string `json:"roles"`
+}
+
+// PendingVerificationResponse is returned by POST /api/register instead of an
+// AuthResponse when users must verify their email before signing in
+type PendingVerificationResponse struct {
+	User                 models.User `json:"user"`
+	VerificationRequired bool        `json:"verification_required"`
 }
 
-func Register(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) Register(w http.ResponseWriter, r *http.Request) {
 	var req AuthRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
 	user := models.User{
 		Username: req.Username,
-		Password: req.Password, // You should hash the password before saving
+		Password: req.Password, // hashed by User.BeforeSave
 		Email:    req.Email,
 	}
 
-	if err := models.CreateUser(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	err := h.users.Create(r.Context(), &user)
+	if errors.Is(err, models.ErrUserExists) {
+		apierror.Write(w, r, userConflict(err))
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to create user", "failed to register user", err)
 		return
 	}
 
-	// Respond with AuthResponse (simulate token)
-	resp := AuthResponse{}
-	resp.User.ID = user.ID
-	resp.User.Username = user.Username
-	resp.User.Role = boolToRole(user.IsAdmin)
-	resp.Token = "dummy-token"
+	metrics.Registered(metrics.RegisterSelf)
+	h.sendVerification(r, user)
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(resp)
+	if h.emailVerification.Required {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(PendingVerificationResponse{User: user, VerificationRequired: true})
+		return
+	}
+	h.writeAuthResponse(w, r, http.StatusCreated, user)
 }
 
-func Login(w http.ResponseWriter, r *http.Request) {
-	var req AuthRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if !decodeJSON(w, r, &req) {
+		metrics.LoginFailed(metrics.LoginInvalidRequest)
+		return
+	}
+
+	// Pending users have not set a password yet and cannot sign in
+	user, err := h.users.GetByUsername(r.Context(), req.Username)
+	if err != nil || user.Status != models.UserStatusActive {
+		switch {
+		case errors.Is(err, models.ErrUserNotFound):
+			metrics.LoginFailed(metrics.LoginUnknownUser)
+		case err != nil:
+			logger(r).Error("failed to load user", "error", err, "username", req.Username)
+			metrics.LoginFailed(metrics.LoginError)
+		default:
+			metrics.LoginFailed(metrics.LoginInactive)
+		}
+		password.SimulateVerify(req.Password)
+		apierror.Write(w, r, apierror.Unauthorized(apierror.CodeInvalidCredentials, "Invalid username or password."))
 		return
 	}
 
-	user, err := models.GetUserByUsername(req.Username)
-	if err != nil || user.Password != req.Password { // Validate password properly
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	ok, rehash, err := password.Verify(user.Password, req.Password)
+	if err != nil || !ok {
+		metrics.LoginFailed(metrics.LoginBadPassword)
+		apierror.Write(w, r, apierror.Unauthorized(apierror.CodeInvalidCredentials, "Invalid username or password."))
 		return
 	}
 
-	// Respond with AuthResponse (simulate token)
-	resp := AuthResponse{}
-	resp.User.ID = user.ID
-	resp.User.Username = user.Username
-	resp.User.Role = boolToRole(user.IsAdmin)
-	resp.Token = "dummy-token"
+	if !h.requireVerifiedEmail(w, r, user) {
+		return
+	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(resp)
+	// Upgrade plaintext and outdated hashes now that we know the password
+	if rehash {
+		if hashed, err := password.Hash(req.Password); err != nil {
+			logger(r).Error("failed to rehash password", "error", err, "user_id", user.ID)
+		} else if err := h.users.UpdatePassword(r.Context(), user.ID, hashed); err != nil {
+			logger(r).Error("failed to store rehashed password", "error", err, "user_id", user.ID)
+		}
+	}
+
+	if h.requirePasskey(w, r, user) {
+		return
+	}
+
+	// Users with two-factor authentication get a challenge instead of tokens
+	m, err := h.mfaStore.Get(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, models.ErrMFANotFound) {
+		metrics.LoginFailed(metrics.LoginError)
+		serverError(w, r, "Failed to sign in", "failed to load mfa", err, "user_id", user.ID)
+		return
+	}
+	if err == nil && m.Enabled() {
+		h.startMFAChallenge(w, r, user)
+		return
+	}
+
+	if h.requirePasskeyEnrollment(w, r, user) {
+		return
+	}
+	if h.writeAuthResponse(w, r, http.StatusOK, user) {
+		metrics.LoginSucceeded()
+	} else {
+		metrics.LoginFailed(metrics.LoginError)
+	}
 }
 
-func boolToRole(isAdmin bool) string {
-	if isAdmin {
-		return "admin"
+// writeAuthResponse starts a session for user and writes the AuthResponse.
+// It reports whether the session was started.
+func (h *Handlers) writeAuthResponse(w http.ResponseWriter, r *http.Request, status int, user models.User) bool {
+	principal, creds, err := h.StartSession(w, r, user.ID)
+	if err != nil {
+		serverError(w, r, "Failed to issue token", "failed to start session", err, "user_id", user.ID)
+		return false
 	}
-	return "user"
+
+	writeTokens(w, status, user, principal, creds)
+	return true
+}
+
+// writeTokens writes an AuthResponse for user carrying creds
+func writeTokens(w http.ResponseWriter, status int, user models.User, principal auth.Principal, creds Tokens) {
+	resp := AuthResponse{
+		User:   AuthUser{User: user, Role: principal.Role, Roles: principal.Roles},
+		Tokens: creds,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
 }
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+func TestRegister(t *testing.T) {
+	env := newTestEnv(t)
+
+	w := serve(t, env.h.Register, http.MethodPost, "/api/register", AuthRequest{
+		Username: " Alice ", Password: "correct horse", Email: "Alice@Example.com",
+	})
+	var resp AuthResponse
+	decode(t, w, http.StatusCreated, &resp)
+
+	if resp.User.Username != "Alice" || resp.User.Email != "Alice@example.com" {
+		t.Errorf("registered user = %q <%s>, want normalized username and email", resp.User.Username, resp.User.Email)
+	}
+	if resp.User.Role != "user" || len(resp.User.Roles) != 1 || resp.User.Roles[0] != models.UserRole {
+		t.Errorf("roles = %q %v, want the user role", resp.User.Role, resp.User.Roles)
+	}
+	if resp.Token == "" || resp.RefreshToken == "" {
+		t.Fatal("no tokens issued")
+	}
+
+	p, err := env.tokens.Verify(resp.Token)
+	if err != nil {
+		t.Fatalf("access token does not verify: %v", err)
+	}
+	if p.UserID != resp.User.ID || p.SessionID == "" {
+		t.Errorf("access token principal = %+v", p)
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 1 {
+		t.Errorf("set %d cookies, want the session cookie", len(cookies))
+	}
+	if logins := env.logins.Logins(); len(logins) != 1 || logins[0].UserID != resp.User.ID {
+		t.Errorf("login history = %+v, want the registration", logins)
+	}
+}
+
+func TestRegisterConflict(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "alice", "password1")
+
+	w := serve(t, env.h.Register, http.MethodPost, "/api/register", AuthRequest{
+		Username: "ALICE", Password: "password2", Email: "other@example.com",
+	})
+	if p := problem(t, w, http.StatusConflict); p.Code != apierror.CodeUsernameTaken {
+		t.Errorf("code = %s, want %s", p.Code, apierror.CodeUsernameTaken)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	env := newTestEnv(t)
+
+	w := serve(t, env.h.Register, http.MethodPost, "/api/register", AuthRequest{
+		Username: "al", Password: "short", Email: "not-an-email",
+	})
+	p := problem(t, w, http.StatusBadRequest)
+	if p.Code != apierror.CodeValidationFailed {
+		t.Fatalf("code = %s, want %s", p.Code, apierror.CodeValidationFailed)
+	}
+	fields := map[string]bool{}
+	for _, e := range p.Errors {
+		fields[e.Field] = true
+	}
+	for _, f := range []string{"username", "password", "email"} {
+		if !fields[f] {
+			t.Errorf("no error reported for %s: %+v", f, p.Errors)
+		}
+	}
+}
+
+func TestRegisterPendingVerification(t *testing.T) {
+	env := newTestEnv(t)
+	env.configure(t, func(d *Deps) { d.EmailVerification = EmailVerification{Required: true} })
+
+	w := serve(t, env.h.Register, http.MethodPost, "/api/register", AuthRequest{
+		Username: "alice", Password: "password1", Email: "alice@example.com",
+	})
+	var resp PendingVerificationResponse
+	decode(t, w, http.StatusCreated, &resp)
+	if !resp.VerificationRequired {
+		t.Error("verification_required is not set")
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("an unverified user was signed in")
+	}
+
+	w = serve(t, env.h.Login, http.MethodPost, "/api/login", LoginRequest{Username: "alice", Password: "password1"})
+	if p := problem(t, w, http.StatusForbidden); p.Code != apierror.CodeEmailNotVerified {
+		t.Errorf("code = %s, want %s", p.Code, apierror.CodeEmailNotVerified)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+
+	w := serve(t, env.h.Login, http.MethodPost, "/api/login", LoginRequest{Username: "Alice", Password: "password1"})
+	var resp AuthResponse
+	decode(t, w, http.StatusOK, &resp)
+	if resp.User.ID != user.ID || resp.Token == "" || resp.RefreshToken == "" {
+		t.Errorf("login response = %+v", resp)
+	}
+	if resp.User.Password != "" {
+		t.Error("login response exposes the password hash")
+	}
+}
+
+func TestLoginRejected(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "alice", "password1")
+	pending := models.User{Username: "bob", Email: "bob@example.com", Status: models.UserStatusPending}
+	if err := env.users.Create(context.Background(), &pending); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]LoginRequest{
+		"wrong password": {Username: "alice", Password: "password2"},
+		"unknown user":   {Username: "carol", Password: "password1"},
+		"pending user":   {Username: "bob", Password: "anything1"},
+	}
+	for name, req := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := serve(t, env.h.Login, http.MethodPost, "/api/login", req)
+			if p := problem(t, w, http.StatusUnauthorized); p.Code != apierror.CodeInvalidCredentials {
+				t.Errorf("code = %s, want %s", p.Code, apierror.CodeInvalidCredentials)
+			}
+		})
+	}
+	if n := len(env.logins.Logins()); n != 0 {
+		t.Errorf("recorded %d logins for rejected sign-ins", n)
+	}
+}
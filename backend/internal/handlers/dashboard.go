@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+const (
+	// DefaultStatsWindowDays is the signup and activity window when ?days is omitted
+	DefaultStatsWindowDays = 30
+	// MaxStatsWindowDays bounds ?days
+	MaxStatsWindowDays = 365
+	// statsCacheTTL is how long computed stats are reused
+	statsCacheTTL = 30 * time.Second
+)
+
+// statsEntry is the computation of the stats of a window. done is closed
+// once stats and err are set.
+type statsEntry struct {
+	done  chan struct{}
+	stats models.DashboardStats
+	err   error
+}
+
+// statsCache keeps recently computed stats per window. Concurrent dashboard
+// loads of a window wait for a single computation, which runs without the
+// lock so other windows are not held up.
+type statsCache struct {
+	sync.Mutex
+	entries map[int]*statsEntry
+}
+
+// GetDashboardStats returns aggregate user statistics for the admin dashboard
+func (h *Handlers) GetDashboardStats(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.policy.Check(w, r, auth.PermStatsRead, auth.Collection("stats")); !ok {
+		return
+	}
+
+	days := DefaultStatsWindowDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxStatsWindowDays {
+			apierror.Write(w, r, apierror.Field("days", apierror.FieldInvalid, fmt.Sprintf("days must be between 1 and %d.", MaxStatsWindowDays)))
+			return
+		}
+		days = n
+	}
+
+	stats, err := h.cachedStats(r.Context(), days)
+	if err != nil {
+		serverError(w, r, "Database error", "failed to compute dashboard stats", err, "days", days)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age=30")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// cachedStats returns the stats of the window, computing them if the cached
+// ones are older than statsCacheTTL. Failed computations are not cached;
+// requests that waited for one try again.
+func (h *Handlers) cachedStats(ctx context.Context, days int) (models.DashboardStats, error) {
+	for {
+		h.statsCache.Lock()
+		e, ok := h.statsCache.entries[days]
+		if ok {
+			select {
+			case <-e.done:
+				if time.Since(e.stats.GeneratedAt) < statsCacheTTL {
+					h.statsCache.Unlock()
+					return e.stats, nil
+				}
+			default:
+				h.statsCache.Unlock()
+				select {
+				case <-e.done:
+				case <-ctx.Done():
+					return models.DashboardStats{}, ctx.Err()
+				}
+				if e.err == nil {
+					return e.stats, nil
+				}
+				continue
+			}
+		}
+
+		e = &statsEntry{done: make(chan struct{})}
+		h.statsCache.entries[days] = e
+		h.statsCache.Unlock()
+
+		e.stats, e.err = h.statsRepository.DashboardStats(ctx, days)
+		if e.err != nil {
+			h.statsCache.Lock()
+			if h.statsCache.entries[days] == e {
+				delete(h.statsCache.entries, days)
+			}
+			h.statsCache.Unlock()
+		}
+		close(e.done)
+		return e.stats, e.err
+	}
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// fakeStats counts the computations of the dashboard stats. Stats are
+// generated age ago; compute, if set, runs first and may block or fail.
+type fakeStats struct {
+	models.StatsRepository
+	calls   atomic.Int32
+	age     time.Duration
+	compute func(ctx context.Context, days int) error
+}
+
+func (f *fakeStats) DashboardStats(ctx context.Context, days int) (models.DashboardStats, error) {
+	f.calls.Add(1)
+	if f.compute != nil {
+		if err := f.compute(ctx, days); err != nil {
+			return models.DashboardStats{}, err
+		}
+	}
+	stats, err := f.StatsRepository.DashboardStats(ctx, days)
+	stats.GeneratedAt = stats.GeneratedAt.Add(-f.age)
+	return stats, err
+}
+
+// useFakeStats counts the stats computations of env
+func (env *testEnv) useFakeStats(t *testing.T) *fakeStats {
+	t.Helper()
+	f := &fakeStats{StatsRepository: models.NewMemoryStatsRepository(env.users, env.logins)}
+	env.configure(t, func(d *Deps) { d.Stats = f })
+	return f
+}
+
+func (env *testEnv) getStats(t *testing.T, path string, opts ...func(*http.Request) *http.Request) models.DashboardStats {
+	t.Helper()
+	var stats models.DashboardStats
+	decode(t, serve(t, env.h.GetDashboardStats, http.MethodGet, path, nil, append([]func(*http.Request) *http.Request{asAdmin}, opts...)...), http.StatusOK, &stats)
+	return stats
+}
+
+func TestGetDashboardStats(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser(t, "admin", "password1")
+	if err := env.roles.SetUserRoles(context.Background(), admin.ID, []string{auth.RoleAdmin}); err != nil {
+		t.Fatal(err)
+	}
+	env.createUser(t, "alice", "password1")
+	env.login(t, "alice", "password1")
+
+	w := serve(t, env.h.GetDashboardStats, http.MethodGet, "/api/dashboard/stats?days=7", nil, asAdmin)
+	if got := w.Header().Get("Cache-Control"); got != "private, max-age=30" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	var stats models.DashboardStats
+	decode(t, w, http.StatusOK, &stats)
+	if stats.TotalUsers != 2 || stats.AdminUsers != 1 || stats.RegularUsers != 1 || stats.ActiveUsers != 1 {
+		t.Errorf("stats = %+v, want 2 users, 1 admin, 1 regular and 1 active", stats)
+	}
+	if stats.WindowDays != 7 || len(stats.SignupsPerDay) != 7 || len(stats.SignupsPerWeek) != 1 {
+		t.Errorf("window = %d days, %d daily and %d weekly counts, want 7, 7, 1", stats.WindowDays, len(stats.SignupsPerDay), len(stats.SignupsPerWeek))
+	}
+
+	if stats := env.getStats(t, "/api/dashboard/stats"); stats.WindowDays != DefaultStatsWindowDays {
+		t.Errorf("WindowDays = %d, want the default %d", stats.WindowDays, DefaultStatsWindowDays)
+	}
+	if stats := env.getStats(t, "/api/dashboard/stats?days=365"); stats.WindowDays != MaxStatsWindowDays {
+		t.Errorf("WindowDays = %d, want %d", stats.WindowDays, MaxStatsWindowDays)
+	}
+}
+
+func TestGetDashboardStatsInvalidDays(t *testing.T) {
+	env := newTestEnv(t)
+	stats := env.useFakeStats(t)
+
+	for _, days := range []string{"0", "-1", "366", "week", "1.5"} {
+		w := serve(t, env.h.GetDashboardStats, http.MethodGet, "/api/dashboard/stats?days="+days, nil, asAdmin)
+		p := problem(t, w, http.StatusBadRequest)
+		if p.Code != apierror.CodeValidationFailed || len(p.Errors) != 1 || p.Errors[0].Field != "days" {
+			t.Errorf("days=%s: problem = %+v, want a validation error for days", days, p)
+		}
+	}
+	if n := stats.calls.Load(); n != 0 {
+		t.Errorf("stats computed %d times for invalid requests", n)
+	}
+}
+
+func TestGetDashboardStatsAdminsOnly(t *testing.T) {
+	env := newTestEnv(t)
+	stats := env.useFakeStats(t)
+
+	w := serve(t, env.h.GetDashboardStats, http.MethodGet, "/api/dashboard/stats", nil, func(r *http.Request) *http.Request {
+		return asPrincipal(r, "1", auth.RoleUser)
+	})
+	wantCode(t, w, http.StatusForbidden, apierror.CodeForbidden)
+
+	w = serve(t, env.h.GetDashboardStats, http.MethodGet, "/api/dashboard/stats", nil)
+	wantCode(t, w, http.StatusUnauthorized, apierror.CodeUnauthorized)
+
+	if n := stats.calls.Load(); n != 0 {
+		t.Errorf("stats computed %d times for refused requests", n)
+	}
+}
+
+func TestGetDashboardStatsCached(t *testing.T) {
+	env := newTestEnv(t)
+	stats := env.useFakeStats(t)
+	env.createUser(t, "alice", "password1")
+
+	first := env.getStats(t, "/api/dashboard/stats")
+	env.createUser(t, "bob", "password1")
+	second := env.getStats(t, "/api/dashboard/stats")
+	if n := stats.calls.Load(); n != 1 {
+		t.Fatalf("stats computed %d times within statsCacheTTL, want 1", n)
+	}
+	if second.TotalUsers != first.TotalUsers || !second.GeneratedAt.Equal(first.GeneratedAt) {
+		t.Errorf("second load = %+v, want the cached %+v", second, first)
+	}
+
+	// Each window is cached separately
+	if week := env.getStats(t, "/api/dashboard/stats?days=7"); week.TotalUsers != 2 {
+		t.Errorf("TotalUsers = %d for a new window, want 2", week.TotalUsers)
+	}
+	if n := stats.calls.Load(); n != 2 {
+		t.Errorf("stats computed %d times for two windows, want 2", n)
+	}
+}
+
+func TestGetDashboardStatsExpire(t *testing.T) {
+	env := newTestEnv(t)
+	stats := env.useFakeStats(t)
+	// Stats are already statsCacheTTL old when they are cached
+	stats.age = statsCacheTTL
+
+	env.createUser(t, "alice", "password1")
+	env.getStats(t, "/api/dashboard/stats")
+	env.createUser(t, "bob", "password1")
+	if got := env.getStats(t, "/api/dashboard/stats"); got.TotalUsers != 2 {
+		t.Errorf("TotalUsers = %d after the cache expired, want 2", got.TotalUsers)
+	}
+	if n := stats.calls.Load(); n != 2 {
+		t.Errorf("stats computed %d times, want 2", n)
+	}
+}
+
+func TestGetDashboardStatsError(t *testing.T) {
+	env := newTestEnv(t)
+	stats := env.useFakeStats(t)
+	stats.compute = func(context.Context, int) error { return errors.New("connection refused") }
+
+	w := serve(t, env.h.GetDashboardStats, http.MethodGet, "/api/dashboard/stats", nil, asAdmin)
+	wantCode(t, w, http.StatusInternalServerError, apierror.CodeInternal)
+
+	// Failures are not cached
+	stats.compute = nil
+	env.getStats(t, "/api/dashboard/stats")
+	if n := stats.calls.Load(); n != 2 {
+		t.Errorf("stats computed %d times, want 2", n)
+	}
+}
+
+func TestGetDashboardStatsConcurrent(t *testing.T) {
+	env := newTestEnv(t)
+	stats := env.useFakeStats(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	stats.compute = func(ctx context.Context, days int) error {
+		if days == DefaultStatsWindowDays {
+			close(started)
+			<-release
+		}
+		return nil
+	}
+
+	// Loads of the same window wait for the computation in progress
+	load := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		env.h.GetDashboardStats(w, asAdmin(httptest.NewRequest(http.MethodGet, path, nil)))
+		return w
+	}
+	var wg sync.WaitGroup
+	recorders := make([]*httptest.ResponseRecorder, 3)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		recorders[0] = load("/api/dashboard/stats")
+	}()
+	<-started
+	for i := 1; i < len(recorders); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			recorders[i] = load("/api/dashboard/stats")
+		}(i)
+	}
+
+	// Other windows are not held up by the slow computation
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- load("/api/dashboard/stats?days=7") }()
+	select {
+	case w := <-done:
+		decode(t, w, http.StatusOK, nil)
+	case <-time.After(5 * time.Second):
+		t.Fatal("a slow computation blocked another window")
+	}
+
+	// A waiting request gives up when it is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := serve(t, env.h.GetDashboardStats, http.MethodGet, "/api/dashboard/stats", nil, func(r *http.Request) *http.Request {
+		return r.WithContext(ctx)
+	}, asAdmin)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("cancelled request: status = %d, want 500", w.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	if n := stats.calls.Load(); n != 2 {
+		t.Errorf("stats computed %d times, want once per window", n)
+	}
+	var first models.DashboardStats
+	for i, w := range recorders {
+		var got models.DashboardStats
+		decode(t, w, http.StatusOK, &got)
+		if i == 0 {
+			first = got
+		} else if !got.GeneratedAt.Equal(first.GeneratedAt) {
+			t.Errorf("load %d got stats generated at %s, want the shared %s", i, got.GeneratedAt, first.GeneratedAt)
+		}
+	}
+}
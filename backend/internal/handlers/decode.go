@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/validate"
+)
+
+// decodeJSON reads the JSON request body into v, rejecting unknown fields
+// and trailing data, and validates it with validate.Struct. On failure it
+// writes the problem response and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err == nil && dec.More() {
+		err = errors.New("trailing data after JSON value")
+	}
+	if err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return false
+	}
+
+	if err := validate.Struct(v); err != nil {
+		apierror.Write(w, r, err)
+		return false
+	}
+	return true
+}
+
+// decodeError turns a JSON decoding error into a client error that names the
+// offending field where possible, without echoing decoder internals
+func decodeError(err error) *apierror.Error {
+	var maxBytes *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &maxBytes):
+		return apierror.New(http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge, "The request body is too large.")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return apierror.Field(typeErr.Field, apierror.FieldInvalid, "Must be a JSON "+jsonType(typeErr.Type.Kind())+".")
+	case errors.Is(err, io.EOF):
+		return apierror.BadRequest("The request body is empty.")
+	}
+
+	// encoding/json has no typed error for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return apierror.Field(strings.Trim(field, `"`), apierror.FieldUnknown, "Unknown field.")
+	}
+	return errInvalidBody
+}
+
+// jsonType names the JSON type a Go kind is decoded from
+func jsonType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return "number"
+}
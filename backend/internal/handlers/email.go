@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/mail"
+	"github.com/yourusername/ums/backend/internal/metrics"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// EmailVerification configures the email verification flow
+type EmailVerification struct {
+	// Required refuses to sign in users whose email is not verified
+	Required bool
+	// TTL is how long a verification link can be used
+	TTL time.Duration
+	// LinkURL is the frontend page that redeems tokens; the token is added
+	// as the "token" query parameter
+	LinkURL string
+}
+
+// VerifyEmailRequest is the body of POST /api/email/verify
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ResendVerificationRequest is the body of POST /api/email/resend
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,max=100,email"`
+}
+
+// Normalize prepares the email for validation
+func (req *ResendVerificationRequest) Normalize() {
+	req.Email = models.NormalizeEmail(req.Email)
+}
+
+// VerifyEmail redeems a verification token and marks the email address of
+// its user verified
+func (h *Handlers) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.emailVerifications.Verify(r.Context(), auth.HashOpaqueToken(req.Token))
+	if errors.Is(err, models.ErrVerificationInvalid) {
+		apierror.Write(w, r, apierror.Field("token", apierror.FieldInvalid, "The verification link is invalid or has expired."))
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to verify email", "failed to verify email", err)
+		return
+	}
+
+	h.writeProfile(w, r, user)
+}
+
+// ResendVerification sends a new verification link to an unverified address.
+// It answers 202 whether or not the address belongs to a user, so it cannot
+// be used to find registered addresses: the address is looked up by the mail
+// worker rather than while the request waits. Requests are throttled by
+// client IP and address, see throttleMail.
+func (h *Handlers) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req ResendVerificationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	send, ok := h.throttleMail(w, r, mailKindVerification, req.Email)
+	if !ok {
+		return
+	}
+	if !send {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	log := logger(r)
+	h.queueMail(r, func(ctx context.Context, m mail.Mailer) {
+		user, err := h.users.GetByEmail(ctx, req.Email)
+		switch {
+		case errors.Is(err, models.ErrUserNotFound):
+		case err != nil:
+			log.Error("failed to load user", "error", err)
+		case user.Status == models.UserStatusActive && user.EmailVerifiedAt == nil:
+			h.mailVerification(ctx, log, m, user)
+		}
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendVerification mails a verification link for the current email of user
+// through the mail queue
+func (h *Handlers) sendVerification(r *http.Request, user models.User) {
+	log := logger(r)
+	h.queueMail(r, func(ctx context.Context, m mail.Mailer) {
+		h.mailVerification(ctx, log, m, user)
+	})
+}
+
+// mailVerification issues a verification token for the current email of
+// user and mails the link. Failures are logged rather than returned: the
+// user can ask for another link.
+func (h *Handlers) mailVerification(ctx context.Context, log *slog.Logger, m mail.Mailer, user models.User) {
+	raw, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		log.Error("failed to create verification token", "error", err, "user_id", user.ID)
+		return
+	}
+	now := time.Now()
+	v := models.EmailVerification{
+		TokenHash: hash,
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: now.Add(h.emailVerification.TTL),
+		CreatedAt: now,
+	}
+	if err := h.emailVerifications.Create(ctx, v); err != nil {
+		log.Error("failed to store verification token", "error", err, "user_id", user.ID)
+		return
+	}
+
+	sendMail(ctx, log, m, user.ID, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf("Hello %s,\n\n"+
+			"Open the link below to verify your email address. It expires in %s.\n\n"+
+			"%s?token=%s\n\n"+
+			"If you did not sign up, you can ignore this email.\n",
+			user.Username, h.emailVerification.TTL, h.emailVerification.LinkURL, raw),
+	})
+}
+
+// queueMail hands job to the mail worker, so that neither issuing the token
+// an email carries nor delivering it holds up the response. Nothing is
+// queued when mail is disabled.
+func (h *Handlers) queueMail(r *http.Request, job mail.Job) {
+	if h.mailQueue == nil {
+		return
+	}
+	if err := h.mailQueue.Enqueue(job); err != nil {
+		logger(r).Error("failed to queue email", "error", err)
+	}
+}
+
+// sendMail sends msg to a user, logging failures
+func sendMail(ctx context.Context, log *slog.Logger, m mail.Mailer, userID string, msg mail.Message) {
+	if err := m.Send(ctx, msg); err != nil {
+		log.Error("failed to send email", "error", err, "user_id", userID, "subject", msg.Subject)
+	}
+}
+
+// requireVerifiedEmail refuses to sign in user while their email address is
+// unverified and verification is required. It writes the error response and
+// reports whether the user may sign in.
+func (h *Handlers) requireVerifiedEmail(w http.ResponseWriter, r *http.Request, user models.User) bool {
+	if h.emailVerification.Required && user.EmailVerifiedAt == nil {
+		metrics.LoginFailed(metrics.LoginUnverified)
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodeEmailNotVerified, "Verify your email address before signing in."))
+		return false
+	}
+	return true
+}
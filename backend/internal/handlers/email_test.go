@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// issueVerification stores an email verification of user's current address
+// and returns the raw token
+func (env *testEnv) issueVerification(t *testing.T, user models.User) string {
+	t.Helper()
+	raw, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	v := models.EmailVerification{TokenHash: hash, UserID: user.ID, Email: user.Email, ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+	if err := env.verifications.Create(context.Background(), v); err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerifyEmail(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+	token := env.issueVerification(t, user)
+
+	w := serve(t, env.h.VerifyEmail, http.MethodPost, "/api/email/verify", VerifyEmailRequest{Token: token})
+	var got models.User
+	decode(t, w, http.StatusOK, &got)
+	if got.EmailVerifiedAt == nil {
+		t.Error("email not marked verified")
+	}
+
+	w = serve(t, env.h.VerifyEmail, http.MethodPost, "/api/email/verify", VerifyEmailRequest{Token: token})
+	problem(t, w, http.StatusBadRequest)
+}
+
+func TestVerifyEmailAfterAddressChange(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+	token := env.issueVerification(t, user)
+
+	user.Email = "alice@example.org"
+	if _, err := env.users.Update(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(t, env.h.VerifyEmail, http.MethodPost, "/api/email/verify", VerifyEmailRequest{Token: token})
+	problem(t, w, http.StatusBadRequest)
+
+	stored, _ := env.users.Get(context.Background(), user.ID)
+	if stored.EmailVerifiedAt != nil {
+		t.Error("new address marked verified by a link sent to the old one")
+	}
+}
+
+func TestRegisterSendsVerification(t *testing.T) {
+	env := newTestEnv(t)
+	env.useMail(t)
+	env.configure(t, func(d *Deps) {
+		d.EmailVerification = EmailVerification{Required: true, LinkURL: "http://localhost:5173/verify-email"}
+	})
+
+	w := serve(t, env.h.Register, http.MethodPost, "/api/register", AuthRequest{
+		Username: "alice", Password: "password1", Email: "alice@example.com",
+	})
+	decode(t, w, http.StatusCreated, nil)
+
+	msgs := env.deliverMail()
+	if len(msgs) != 1 || msgs[0].To != "alice@example.com" {
+		t.Fatalf("delivered %+v, want one message to alice", msgs)
+	}
+	w = serve(t, env.h.VerifyEmail, http.MethodPost, "/api/email/verify", VerifyEmailRequest{Token: linkToken(t, msgs[0])})
+	decode(t, w, http.StatusOK, nil)
+
+	env.login(t, "alice", "password1")
+}
+
+func TestResendVerification(t *testing.T) {
+	env := newTestEnv(t)
+	env.useMail(t)
+	env.createUser(t, "alice", "password1")
+	verified := env.createUser(t, "bob", "password1")
+	w := serve(t, env.h.VerifyEmail, http.MethodPost, "/api/email/verify", VerifyEmailRequest{Token: env.issueVerification(t, verified)})
+	decode(t, w, http.StatusOK, nil)
+
+	// Every address gets the same answer; only unverified users get mail
+	for _, addr := range []string{"alice@example.com", "bob@example.com", "nobody@example.com"} {
+		w := serve(t, env.h.ResendVerification, http.MethodPost, "/api/email/resend", ResendVerificationRequest{Email: addr})
+		decode(t, w, http.StatusAccepted, nil)
+	}
+
+	msgs := env.deliverMail()
+	if len(msgs) != 1 || msgs[0].To != "alice@example.com" {
+		t.Errorf("delivered %+v, want one message to alice", msgs)
+	}
+}
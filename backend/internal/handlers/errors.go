@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// Errors shared by several handlers
+var (
+	errInvalidBody  = apierror.BadRequest("The request body is not valid JSON.")
+	errUserNotFound = apierror.NotFound("User not found.")
+	errRoleNotFound = apierror.NotFound("Role not found.")
+	errUnknownRole  = apierror.Field("roles", apierror.FieldUnknown, "Unknown role.")
+	errRoleExists   = apierror.Taken(apierror.CodeConflict, "name")
+	errBuiltInRole  = apierror.Conflict(apierror.CodeConflict, "Built-in roles cannot be deleted or renamed.")
+
+	errInvalidRefreshToken = apierror.Unauthorized(apierror.CodeInvalidToken, "The refresh token is invalid or expired.")
+)
+
+// loadUser returns the user with the given ID. It answers 404 if there is no
+// such user and 500 on other errors, and reports whether the user was loaded.
+func (h *Handlers) loadUser(w http.ResponseWriter, r *http.Request, id string) (models.User, bool) {
+	user, err := h.users.Get(r.Context(), id)
+	if errors.Is(err, models.ErrUserNotFound) {
+		apierror.Write(w, r, errUserNotFound)
+		return user, false
+	}
+	if err != nil {
+		serverError(w, r, "Failed to load user", "failed to load user", err, "target_user_id", id)
+		return user, false
+	}
+	return user, true
+}
+
+// userConflict maps the ErrUserExists family to the field that is taken
+func userConflict(err error) *apierror.Error {
+	switch {
+	case errors.Is(err, models.ErrUsernameTaken):
+		return apierror.Taken(apierror.CodeUsernameTaken, "username")
+	case errors.Is(err, models.ErrEmailTaken):
+		return apierror.Taken(apierror.CodeEmailTaken, "email")
+	}
+	return apierror.Conflict(apierror.CodeConflict, "Username or email already exists.")
+}
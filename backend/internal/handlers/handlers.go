@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/mail"
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/search"
+	"github.com/yourusername/ums/backend/internal/session"
+)
+
+// Deps are the stores, services and settings the handlers use. Every store
+// and service is required except MailQueue.
+type Deps struct {
+	Users              models.UserRepository
+	Roles              models.RoleRepository
+	RefreshTokens      models.RefreshTokenStore
+	LoginHistory       models.LoginHistory
+	Stats              models.StatsRepository
+	SecurityLog        models.SecurityLog
+	MFAStore           models.MFAStore
+	PasskeyStore       models.PasskeyStore
+	Invitations        models.InvitationStore
+	EmailVerifications models.EmailVerificationStore
+	PasswordResets     models.PasswordResetStore
+	Searcher           search.UserSearcher
+	Tokens             *auth.TokenManager
+	Sessions           *session.Manager
+	// Policy decides which permissions a principal holds
+	Policy *auth.Policy
+	// MailQueue delivers verification and password reset emails; nil
+	// disables them
+	MailQueue *mail.Queue
+
+	EmailVerification EmailVerification
+	PasswordReset     PasswordReset
+	MFA               MFAConfig
+	Passkeys          PasskeyConfig
+}
+
+// Handlers serves the API endpoints with the dependencies it was built with
+type Handlers struct {
+	users              models.UserRepository
+	roleRepository     models.RoleRepository
+	refreshTokens      models.RefreshTokenStore
+	loginHistory       models.LoginHistory
+	statsRepository    models.StatsRepository
+	securityLog        models.SecurityLog
+	mfaStore           models.MFAStore
+	passkeyStore       models.PasskeyStore
+	invitations        models.InvitationStore
+	emailVerifications models.EmailVerificationStore
+	passwordResets     models.PasswordResetStore
+	userSearcher       search.UserSearcher
+	tokenManager       *auth.TokenManager
+	sessionManager     *session.Manager
+	policy             *auth.Policy
+	mailQueue          *mail.Queue
+
+	emailVerification EmailVerification
+	passwordReset     PasswordReset
+	mfaConfig         MFAConfig
+	passkeyConfig     PasskeyConfig
+
+	statsCache   statsCache
+	mailThrottle *mailThrottle
+}
+
+// New returns handlers using d. Zero TTLs and an empty MFA issuer take their
+// defaults. It fails if a required dependency is missing.
+func New(d Deps) (*Handlers, error) {
+	required := []struct {
+		name    string
+		missing bool
+	}{
+		{"Users", d.Users == nil},
+		{"Roles", d.Roles == nil},
+		{"RefreshTokens", d.RefreshTokens == nil},
+		{"LoginHistory", d.LoginHistory == nil},
+		{"Stats", d.Stats == nil},
+		{"SecurityLog", d.SecurityLog == nil},
+		{"MFAStore", d.MFAStore == nil},
+		{"PasskeyStore", d.PasskeyStore == nil},
+		{"Invitations", d.Invitations == nil},
+		{"EmailVerifications", d.EmailVerifications == nil},
+		{"PasswordResets", d.PasswordResets == nil},
+		{"Searcher", d.Searcher == nil},
+		{"Tokens", d.Tokens == nil},
+		{"Sessions", d.Sessions == nil},
+		{"Policy", d.Policy == nil},
+	}
+	var missing []string
+	for _, r := range required {
+		if r.missing {
+			missing = append(missing, r.name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("handlers: missing dependencies: %s", strings.Join(missing, ", "))
+	}
+
+	if d.EmailVerification.TTL <= 0 {
+		d.EmailVerification.TTL = auth.DefaultVerificationTTL
+	}
+	if d.PasswordReset.TTL <= 0 {
+		d.PasswordReset.TTL = auth.DefaultPasswordResetTTL
+	}
+	if d.MFA.Issuer == "" {
+		d.MFA.Issuer = "UMS"
+	}
+	if d.MFA.ChallengeTTL <= 0 {
+		d.MFA.ChallengeTTL = auth.DefaultMFAChallengeTTL
+	}
+	if d.Passkeys.CeremonyTTL <= 0 {
+		d.Passkeys.CeremonyTTL = auth.DefaultPasskeyCeremonyTTL
+	}
+
+	return &Handlers{
+		users:              d.Users,
+		roleRepository:     d.Roles,
+		refreshTokens:      d.RefreshTokens,
+		loginHistory:       d.LoginHistory,
+		statsRepository:    d.Stats,
+		securityLog:        d.SecurityLog,
+		mfaStore:           d.MFAStore,
+		passkeyStore:       d.PasskeyStore,
+		invitations:        d.Invitations,
+		emailVerifications: d.EmailVerifications,
+		passwordResets:     d.PasswordResets,
+		userSearcher:       d.Searcher,
+		tokenManager:       d.Tokens,
+		sessionManager:     d.Sessions,
+		policy:             d.Policy,
+		mailQueue:          d.MailQueue,
+		emailVerification:  d.EmailVerification,
+		passwordReset:      d.PasswordReset,
+		mfaConfig:          d.MFA,
+		passkeyConfig:      d.Passkeys,
+		statsCache:         statsCache{entries: map[int]*statsEntry{}},
+		mailThrottle:       newMailThrottle(),
+	}, nil
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/password"
+)
+
+// InvitationResponse carries a freshly issued invite token. The token is
+// shown only once; the recipient redeems it at POST /api/invitations/accept.
+type InvitationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AcceptInvitationRequest is the body of POST /api/invitations/accept
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=128"`
+}
+
+// ResendInvitation issues a new invite token for a pending user, invalidating
+// earlier ones
+func (h *Handlers) ResendInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+
+	principal, ok := h.policy.Check(w, r, auth.PermUsersCreate, auth.UserResource(userID))
+	if !ok {
+		return
+	}
+
+	user, ok := h.loadUser(w, r, userID)
+	if !ok {
+		return
+	}
+	if user.Status != models.UserStatusPending {
+		apierror.Write(w, r, apierror.Conflict(apierror.CodeConflict, "User has already accepted their invitation."))
+		return
+	}
+
+	inv, err := h.issueInvitation(r.Context(), user.ID, principal.UserID)
+	if err != nil {
+		serverError(w, r, "Failed to create invitation", "failed to issue invitation", err, "invited_user_id", user.ID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inv)
+}
+
+// AcceptInvitation sets the password of an invited user, activates them,
+// marks their email address verified and signs them in
+func (h *Handlers) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req AcceptInvitationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	hashed, err := password.Hash(req.Password)
+	if err != nil {
+		serverError(w, r, "Failed to set password", "failed to hash password", err)
+		return
+	}
+
+	user, err := h.invitations.Accept(r.Context(), auth.HashOpaqueToken(req.Token), hashed)
+	if errors.Is(err, models.ErrInvitationInvalid) {
+		apierror.Write(w, r, apierror.Field("token", apierror.FieldInvalid, "The invitation is invalid or has expired."))
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to accept invitation", "failed to accept invitation", err)
+		return
+	}
+	// Accepting verifies the address, but the same rules as for signing in apply
+	if !h.requireVerifiedEmail(w, r, user) {
+		return
+	}
+	if h.requirePasskeyEnrollment(w, r, user) {
+		return
+	}
+
+	h.writeAuthResponse(w, r, http.StatusOK, user)
+}
+
+// issueInvitation creates an invite token for userID on behalf of createdBy
+func (h *Handlers) issueInvitation(ctx context.Context, userID, createdBy string) (*InvitationResponse, error) {
+	raw, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	inv := models.Invitation{
+		TokenHash: hash,
+		UserID:    userID,
+		CreatedBy: createdBy,
+		ExpiresAt: now.Add(auth.DefaultInviteTTL),
+		CreatedAt: now,
+	}
+	if err := h.invitations.Create(ctx, inv); err != nil {
+		return nil, err
+	}
+
+	return &InvitationResponse{Token: raw, ExpiresAt: inv.ExpiresAt}, nil
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// invite creates a pending user through CreateUser and returns the response
+func (env *testEnv) invite(t *testing.T, username string) CreateUserResponse {
+	t.Helper()
+	req := CreateUserRequest{Username: username, Email: username + "@example.com"}
+	w := serve(t, env.h.CreateUser, http.MethodPost, "/api/users", req, asAdmin)
+	var resp CreateUserResponse
+	decode(t, w, http.StatusCreated, &resp)
+	if resp.Status != models.UserStatusPending || resp.Invitation == nil {
+		t.Fatalf("CreateUser without password returned %+v", resp)
+	}
+	return resp
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	env := newTestEnv(t)
+	invited := env.invite(t, "alice")
+
+	req := AcceptInvitationRequest{Token: invited.Invitation.Token, Password: "password1"}
+	w := serve(t, env.h.AcceptInvitation, http.MethodPost, "/api/invitations/accept", req)
+	var resp AuthResponse
+	decode(t, w, http.StatusOK, &resp)
+	if resp.User.ID != invited.ID || resp.User.Status != models.UserStatusActive || resp.Token == "" {
+		t.Errorf("AcceptInvitation returned %+v", resp)
+	}
+	env.login(t, "alice", "password1")
+
+	// Invitations are single-use
+	w = serve(t, env.h.AcceptInvitation, http.MethodPost, "/api/invitations/accept", req)
+	problem(t, w, http.StatusBadRequest)
+}
+
+func TestAcceptInvitationSuperseded(t *testing.T) {
+	env := newTestEnv(t)
+	first := env.invite(t, "alice")
+
+	// Resending discards the earlier link
+	w := serve(t, env.h.ResendInvitation, http.MethodPost, "/api/users/"+first.ID+"/invitation", nil,
+		asAdmin, withVars(map[string]string{"id": first.ID}))
+	var second InvitationResponse
+	decode(t, w, http.StatusOK, &second)
+
+	w = serve(t, env.h.AcceptInvitation, http.MethodPost, "/api/invitations/accept", AcceptInvitationRequest{Token: first.Invitation.Token, Password: "password1"})
+	problem(t, w, http.StatusBadRequest)
+
+	w = serve(t, env.h.AcceptInvitation, http.MethodPost, "/api/invitations/accept", AcceptInvitationRequest{Token: second.Token, Password: "password1"})
+	decode(t, w, http.StatusOK, nil)
+
+	if n, _ := env.invitations.DeleteExpired(context.Background(), time.Now().Add(auth.DefaultInviteTTL+time.Minute)); n != 1 {
+		t.Errorf("DeleteExpired removed %d invitations, want the accepted one", n)
+	}
+}
+
+func TestAcceptInvitationExpired(t *testing.T) {
+	env := newTestEnv(t)
+	user := models.User{Username: "alice", Email: "alice@example.com", Status: models.UserStatusPending}
+	if err := env.users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	raw, hash, _ := auth.NewOpaqueToken()
+	err := env.invitations.Create(context.Background(), models.Invitation{TokenHash: hash, UserID: user.ID, ExpiresAt: time.Now().Add(-time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(t, env.h.AcceptInvitation, http.MethodPost, "/api/invitations/accept", AcceptInvitationRequest{Token: raw, Password: "password1"})
+	p := problem(t, w, http.StatusBadRequest)
+	if p.Code != apierror.CodeValidationFailed || len(p.Errors) != 1 || p.Errors[0].Field != "token" {
+		t.Errorf("problem = %+v, want an invalid token", p)
+	}
+}
+
+func TestAcceptInvitationVerifiesEmail(t *testing.T) {
+	env := newTestEnv(t)
+	env.configure(t, func(d *Deps) { d.EmailVerification = EmailVerification{Required: true} })
+	invited := env.invite(t, "alice")
+
+	w := serve(t, env.h.AcceptInvitation, http.MethodPost, "/api/invitations/accept", AcceptInvitationRequest{Token: invited.Invitation.Token, Password: "password1"})
+	decode(t, w, http.StatusOK, nil)
+
+	user, err := env.users.Get(context.Background(), invited.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Error("accepting the invitation did not verify the address")
+	}
+	env.login(t, "alice", "password1")
+}
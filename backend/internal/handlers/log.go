@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/logging"
+)
+
+// logger returns the logger of request r, tagged with the signed-in user if any
+func logger(r *http.Request) *slog.Logger {
+	l := logging.FromContext(r.Context())
+	if p, ok := auth.PrincipalFromContext(r.Context()); ok {
+		l = l.With("user_id", p.UserID)
+	}
+	return l
+}
+
+// serverError logs err as the cause of a failed request and answers 500 with
+// the generic message public, which never reveals the cause to the client.
+// args are extra slog attributes, such as the ID of the affected record.
+func serverError(w http.ResponseWriter, r *http.Request, public, msg string, err error, args ...any) {
+	logger(r).Error(msg, append([]any{"error", err}, args...)...)
+	apierror.WriteProblem(w, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, public).Problem(r))
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/session"
+)
+
+// Limits of the public endpoints that send mail, ForgotPassword and
+// ResendVerification, so they cannot be used to flood an inbox or fill the
+// mail queue
+const (
+	// MailAddressCooldown is how long an address waits between two emails
+	// of the same kind
+	MailAddressCooldown = time.Minute
+	// MailIPLimit is how many requests to these endpoints a client IP may
+	// make per MailIPWindow
+	MailIPLimit  = 20
+	MailIPWindow = 15 * time.Minute
+)
+
+// Kinds of throttled email
+const (
+	mailKindVerification  = "verification"
+	mailKindPasswordReset = "password_reset"
+)
+
+// mailThrottle remembers recent requests for mail by address and by client
+// IP. It is kept in memory, so every instance throttles on its own.
+type mailThrottle struct {
+	sync.Mutex
+	now func() time.Time
+	// addresses holds when an email of a kind was last queued for an
+	// address, keyed by kind and address
+	addresses map[string]time.Time
+	ips       map[string]*ipWindow
+	nextSweep time.Time
+}
+
+// ipWindow counts the requests of a client IP since start
+type ipWindow struct {
+	start time.Time
+	count int
+}
+
+func newMailThrottle() *mailThrottle {
+	return &mailThrottle{now: time.Now, addresses: map[string]time.Time{}, ips: map[string]*ipWindow{}}
+}
+
+// allowIP counts a request from ip. It reports whether the request is within
+// MailIPLimit and, if not, how long until the window ends.
+func (t *mailThrottle) allowIP(ip string) (time.Duration, bool) {
+	t.Lock()
+	defer t.Unlock()
+	now := t.now()
+	t.sweep(now)
+
+	w, ok := t.ips[ip]
+	if !ok || !now.Before(w.start.Add(MailIPWindow)) {
+		w = &ipWindow{start: now}
+		t.ips[ip] = w
+	}
+	if w.count >= MailIPLimit {
+		return w.start.Add(MailIPWindow).Sub(now), false
+	}
+	w.count++
+	return 0, true
+}
+
+// allowAddress reports whether an email of kind may be queued for addr, and
+// starts the cooldown of addr if so
+func (t *mailThrottle) allowAddress(kind, addr string) bool {
+	t.Lock()
+	defer t.Unlock()
+	now := t.now()
+	t.sweep(now)
+
+	key := kind + " " + addr
+	if last, ok := t.addresses[key]; ok && now.Before(last.Add(MailAddressCooldown)) {
+		return false
+	}
+	t.addresses[key] = now
+	return true
+}
+
+// sweep forgets expired entries every MailIPWindow, so the maps only hold
+// recent requests. The caller holds the lock.
+func (t *mailThrottle) sweep(now time.Time) {
+	if now.Before(t.nextSweep) {
+		return
+	}
+	t.nextSweep = now.Add(MailIPWindow)
+	for key, last := range t.addresses {
+		if !now.Before(last.Add(MailAddressCooldown)) {
+			delete(t.addresses, key)
+		}
+	}
+	for ip, w := range t.ips {
+		if !now.Before(w.start.Add(MailIPWindow)) {
+			delete(t.ips, ip)
+		}
+	}
+}
+
+// throttleMail applies the mail limits to a request for an email of kind to
+// addr. A client over its limit gets 429 and ok is false. An address in its
+// cooldown is answered like any other, so responses do not tell which
+// addresses were asked for; send is false and no email goes out.
+func (h *Handlers) throttleMail(w http.ResponseWriter, r *http.Request, kind, addr string) (send, ok bool) {
+	if wait, ok := h.mailThrottle.allowIP(session.ClientIP(r)); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		apierror.Write(w, r, apierror.New(http.StatusTooManyRequests, apierror.CodeTooManyRequests, "Too many requests. Try again later."))
+		return false, false
+	}
+	if !h.mailThrottle.allowAddress(kind, addr) {
+		logger(r).Info("email throttled", "kind", kind)
+		return false, true
+	}
+	return true, true
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+)
+
+// fromIP returns an option sending a request from ip
+func fromIP(ip string) func(*http.Request) *http.Request {
+	return func(r *http.Request) *http.Request {
+		r.RemoteAddr = ip + ":40000"
+		return r
+	}
+}
+
+// useClock makes the mail throttle of env read the time from *now
+func (env *testEnv) useClock(now *time.Time) {
+	env.h.mailThrottle.now = func() time.Time { return *now }
+}
+
+func TestMailThrottleAddressCooldown(t *testing.T) {
+	env := newTestEnv(t)
+	env.useMail(t)
+	env.createUser(t, "alice", "password1")
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	env.useClock(&now)
+
+	forgot := func(ip string) {
+		t.Helper()
+		w := serve(t, env.h.ForgotPassword, http.MethodPost, "/api/password/forgot", ForgotPasswordRequest{Email: "Alice@Example.com"}, fromIP(ip))
+		decode(t, w, http.StatusAccepted, nil)
+	}
+
+	// Repeats within the cooldown are answered alike but send nothing, from
+	// any client
+	forgot("192.0.2.1")
+	forgot("192.0.2.1")
+	forgot("198.51.100.7")
+	if msgs := env.deliverMail(); len(msgs) != 1 {
+		t.Fatalf("%d emails sent within the cooldown, want 1", len(msgs))
+	}
+
+	// The cooldown is kept per kind of email
+	w := serve(t, env.h.ResendVerification, http.MethodPost, "/api/email/resend", ResendVerificationRequest{Email: "alice@example.com"})
+	decode(t, w, http.StatusAccepted, nil)
+	if msgs := env.deliverMail(); len(msgs) != 2 {
+		t.Fatalf("%d emails sent, want a reset and a verification", len(msgs))
+	}
+
+	now = now.Add(MailAddressCooldown)
+	forgot("192.0.2.1")
+	if msgs := env.deliverMail(); len(msgs) != 3 || msgs[2].To != "alice@example.com" {
+		t.Errorf("delivered %+v, want another reset email after the cooldown", msgs)
+	}
+}
+
+func TestMailThrottleIPLimit(t *testing.T) {
+	env := newTestEnv(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	env.useClock(&now)
+
+	request := func(i int, ip string) *httptest.ResponseRecorder {
+		// Alternate endpoints and addresses: the limit counts both
+		if i%2 == 0 {
+			return serve(t, env.h.ForgotPassword, http.MethodPost, "/api/password/forgot",
+				ForgotPasswordRequest{Email: "user" + strconv.Itoa(i) + "@example.com"}, fromIP(ip))
+		}
+		return serve(t, env.h.ResendVerification, http.MethodPost, "/api/email/resend",
+			ResendVerificationRequest{Email: "user" + strconv.Itoa(i) + "@example.com"}, fromIP(ip))
+	}
+
+	for i := 0; i < MailIPLimit; i++ {
+		decode(t, request(i, "192.0.2.1"), http.StatusAccepted, nil)
+	}
+	now = now.Add(time.Minute)
+	w := request(MailIPLimit, "192.0.2.1")
+	wantCode(t, w, http.StatusTooManyRequests, apierror.CodeTooManyRequests)
+	if got, want := w.Header().Get("Retry-After"), strconv.Itoa(int((MailIPWindow - time.Minute).Seconds())); got != want {
+		t.Errorf("Retry-After = %q, want %s", got, want)
+	}
+
+	// Other clients are not affected, and the limit resets with the window
+	decode(t, request(0, "198.51.100.7"), http.StatusAccepted, nil)
+	now = now.Add(MailIPWindow)
+	decode(t, request(1, "192.0.2.1"), http.StatusAccepted, nil)
+}
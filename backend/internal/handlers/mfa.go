@@ -0,0 +1,564 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/metrics"
+	"github.com/yourusername/ums/backend/internal/mfa"
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/password"
+	"github.com/yourusername/ums/backend/internal/secretbox"
+	"github.com/yourusername/ums/backend/internal/session"
+)
+
+// MFAConfig configures two-factor authentication
+type MFAConfig struct {
+	// Issuer names the service in authenticator apps
+	Issuer string
+	// Box encrypts TOTP secrets; nil makes two-factor authentication
+	// unavailable
+	Box *secretbox.Box
+	// ChallengeTTL is how long the second step of a sign-in can be completed
+	ChallengeTTL time.Duration
+}
+
+var (
+	errMFAUnavailable = apierror.New(http.StatusServiceUnavailable, apierror.CodeUnavailable, "Two-factor authentication is not available.")
+	errInvalidMFACode = apierror.Field("code", apierror.FieldInvalid, "The code is not valid.")
+	errMFANotEnabled  = apierror.Conflict(apierror.CodeConflict, "Two-factor authentication is not enabled.")
+
+	errMFAAlreadyEnabled = apierror.Conflict(apierror.CodeConflict, "Two-factor authentication is already enabled.")
+
+	errMFAChallengeExpired = apierror.Unauthorized(apierror.CodeInvalidToken, "The sign-in has expired; enter your password again.")
+
+	// errNoMFABox is returned by verifySecondFactor without an encryption key
+	errNoMFABox = errors.New("mfa: no encryption key configured")
+)
+
+// mfaLockedError is returned by verifySecondFactor while the second factor of
+// a user is locked after too many wrong codes
+type mfaLockedError struct {
+	until time.Time
+}
+
+func (e *mfaLockedError) Error() string {
+	return models.ErrMFALocked.Error()
+}
+
+func (e *mfaLockedError) Unwrap() error {
+	return models.ErrMFALocked
+}
+
+// writeMFALocked answers a request for a locked second factor with 429 and
+// the time left in Retry-After
+func writeMFALocked(w http.ResponseWriter, r *http.Request, err error) {
+	var locked *mfaLockedError
+	wait := models.MFALockout
+	if errors.As(err, &locked) {
+		wait = time.Until(locked.until)
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(wait.Seconds())))))
+	apierror.Write(w, r, apierror.New(http.StatusTooManyRequests, apierror.CodeTooManyRequests, "Too many wrong codes. Try again later."))
+}
+
+// MFAChallengeResponse is returned by POST /api/login instead of an
+// AuthResponse when the user has two-factor authentication enabled. The
+// token is redeemed with a code at POST /api/login/mfa.
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// LoginMFARequest is the body of POST /api/login/mfa. Code is a code from
+// the authenticator app or a recovery code.
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required,max=20"`
+}
+
+// MFAStatus is the body of GET /api/profile/mfa
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// EnrollMFARequest is the body of POST /api/profile/mfa/enroll
+type EnrollMFARequest struct {
+	Password string `json:"password" validate:"required,max=128"`
+}
+
+// EnrollMFAResponse carries a new TOTP secret. QRCode is a data: URL of a
+// PNG encoding OTPAuthURL.
+type EnrollMFAResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"`
+}
+
+// MFACodeRequest is the body of the profile endpoints that need a current
+// code from the authenticator app or a recovery code
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required,max=20"`
+}
+
+// RecoveryCodesResponse carries new recovery codes. They are shown only
+// once; only their hashes are stored.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// GetMFAStatus reports whether the caller has two-factor authentication
+// enabled and how many recovery codes they have left
+func (h *Handlers) GetMFAStatus(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var status MFAStatus
+	m, err := h.mfaStore.Get(r.Context(), principal.UserID)
+	if err != nil && !errors.Is(err, models.ErrMFANotFound) {
+		serverError(w, r, "Database error", "failed to load mfa", err)
+		return
+	}
+	if err == nil && m.Enabled() {
+		status.Enabled = true
+		if status.RecoveryCodesLeft, err = h.mfaStore.CountRecoveryCodes(r.Context(), principal.UserID); err != nil {
+			serverError(w, r, "Database error", "failed to count recovery codes", err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// EnrollMFA generates a TOTP secret for the caller after checking their
+// password. The secret takes effect once ConfirmMFA accepts a code from it;
+// enrolling again before that replaces it.
+func (h *Handlers) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+	if h.mfaConfig.Box == nil {
+		apierror.Write(w, r, errMFAUnavailable)
+		return
+	}
+
+	var req EnrollMFARequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, ok := h.loadUser(w, r, principal.UserID)
+	if !ok {
+		return
+	}
+	if valid, _, err := password.Verify(user.Password, req.Password); err != nil || !valid {
+		apierror.Write(w, r, apierror.Forbidden("Current password is incorrect."))
+		return
+	}
+
+	key, err := mfa.NewKey(h.mfaConfig.Issuer, user.Username)
+	if err != nil {
+		serverError(w, r, "Failed to enroll", "failed to generate totp secret", err)
+		return
+	}
+	qr, err := key.QRCode()
+	if err != nil {
+		serverError(w, r, "Failed to enroll", "failed to render qr code", err)
+		return
+	}
+	sealed, err := h.mfaConfig.Box.Seal([]byte(key.Secret), mfaOwner(user.ID))
+	if err != nil {
+		serverError(w, r, "Failed to enroll", "failed to encrypt totp secret", err)
+		return
+	}
+
+	err = h.mfaStore.StartEnrollment(r.Context(), user.ID, sealed)
+	if errors.Is(err, models.ErrMFAEnabled) {
+		apierror.Write(w, r, errMFAAlreadyEnabled)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to enroll", "failed to store totp secret", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EnrollMFAResponse{Secret: key.Secret, OTPAuthURL: key.URL, QRCode: qr})
+}
+
+// ConfirmMFA enables the caller's pending TOTP secret once it produced a
+// valid code, and returns their recovery codes
+func (h *Handlers) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	m, err := h.mfaStore.Get(r.Context(), principal.UserID)
+	if errors.Is(err, models.ErrMFANotFound) {
+		apierror.Write(w, r, apierror.Conflict(apierror.CodeConflict, "Start the enrollment first."))
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Database error", "failed to load mfa", err)
+		return
+	}
+	if m.Enabled() {
+		apierror.Write(w, r, errMFAAlreadyEnabled)
+		return
+	}
+
+	secret, ok := h.openMFASecret(w, r, m)
+	if !ok {
+		return
+	}
+	step, valid := mfa.Validate(secret, req.Code, time.Now(), m.LastStep)
+	if !valid {
+		apierror.Write(w, r, errInvalidMFACode)
+		return
+	}
+
+	codes, hashes, err := mfa.NewRecoveryCodes()
+	if err != nil {
+		serverError(w, r, "Failed to enable two-factor authentication", "failed to generate recovery codes", err)
+		return
+	}
+	err = h.mfaStore.Confirm(r.Context(), principal.UserID, step, hashes)
+	if errors.Is(err, models.ErrMFAEnabled) {
+		apierror.Write(w, r, errMFAAlreadyEnabled)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to enable two-factor authentication", "failed to confirm mfa", err)
+		return
+	}
+	h.recordSecurityEvent(r, principal.UserID, models.EventMFAEnabled)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes after checking
+// a current code
+func (h *Handlers) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !h.checkSecondFactor(w, r, principal.UserID, req.Code) {
+		return
+	}
+
+	codes, hashes, err := mfa.NewRecoveryCodes()
+	if err != nil {
+		serverError(w, r, "Failed to create recovery codes", "failed to generate recovery codes", err)
+		return
+	}
+	if err := h.mfaStore.ReplaceRecoveryCodes(r.Context(), principal.UserID, hashes); err != nil {
+		serverError(w, r, "Failed to create recovery codes", "failed to store recovery codes", err)
+		return
+	}
+	h.recordSecurityEvent(r, principal.UserID, models.EventRecoveryCodesRenewed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMFA removes the caller's second factor after checking a current
+// code or a recovery code
+func (h *Handlers) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !h.checkSecondFactor(w, r, principal.UserID, req.Code) {
+		return
+	}
+
+	if _, err := h.mfaStore.Delete(r.Context(), principal.UserID); err != nil {
+		serverError(w, r, "Failed to disable two-factor authentication", "failed to delete mfa", err)
+		return
+	}
+	h.recordSecurityEvent(r, principal.UserID, models.EventMFADisabled)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetUserMFA removes the second factor of another user, e.g. one who lost
+// both their device and their recovery codes
+func (h *Handlers) ResetUserMFA(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+
+	// Checked on the collection so that users:update:own grants nothing here
+	if _, ok := h.policy.Check(w, r, auth.PermMFAReset, auth.Collection("users")); !ok {
+		return
+	}
+
+	if _, ok := h.loadUser(w, r, userID); !ok {
+		return
+	}
+
+	had, err := h.mfaStore.Delete(r.Context(), userID)
+	if err != nil {
+		serverError(w, r, "Failed to reset two-factor authentication", "failed to delete mfa", err, "target_user_id", userID)
+		return
+	}
+	if !had {
+		apierror.Write(w, r, errMFANotEnabled)
+		return
+	}
+	h.recordSecurityEvent(r, userID, models.EventMFAReset)
+	logger(r).Info("mfa reset", "target_user_id", userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoginMFA completes a sign-in started by Login with a code from the
+// authenticator app or a recovery code. A challenge tolerates
+// models.MaxMFAAttempts wrong codes, and the user models.MaxMFAFailures in a
+// row across challenges.
+func (h *Handlers) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req LoginMFARequest
+	if !decodeJSON(w, r, &req) {
+		metrics.LoginFailed(metrics.LoginInvalidRequest)
+		return
+	}
+
+	// The attempt is counted before the code is checked, so concurrent
+	// guesses cannot exceed the limit
+	tokenHash := auth.HashOpaqueToken(req.MFAToken)
+	userID, err := h.mfaStore.UseChallengeAttempt(r.Context(), tokenHash)
+	if err != nil {
+		if !errors.Is(err, models.ErrMFAChallengeInvalid) {
+			logger(r).Error("failed to use mfa challenge", "error", err)
+		}
+		metrics.LoginFailed(metrics.LoginMFAExpired)
+		apierror.Write(w, r, errMFAChallengeExpired)
+		return
+	}
+
+	user, err := h.users.Get(r.Context(), userID)
+	if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		metrics.LoginFailed(metrics.LoginError)
+		serverError(w, r, "Failed to sign in", "failed to load user", err, "user_id", userID)
+		return
+	}
+	if err != nil || user.Status != models.UserStatusActive {
+		metrics.LoginFailed(metrics.LoginInactive)
+		apierror.Write(w, r, errMFAChallengeExpired)
+		return
+	}
+
+	ok, err := h.verifySecondFactor(r, user.ID, req.Code)
+	if errors.Is(err, models.ErrMFANotFound) {
+		// The second factor was reset after the password step
+		metrics.LoginFailed(metrics.LoginMFAExpired)
+		apierror.Write(w, r, errMFAChallengeExpired)
+		return
+	}
+	if errors.Is(err, errNoMFABox) {
+		metrics.LoginFailed(metrics.LoginError)
+		apierror.Write(w, r, errMFAUnavailable)
+		return
+	}
+	if errors.Is(err, models.ErrMFALocked) {
+		metrics.LoginFailed(metrics.LoginMFALocked)
+		writeMFALocked(w, r, err)
+		return
+	}
+	if err != nil {
+		metrics.LoginFailed(metrics.LoginError)
+		serverError(w, r, "Failed to verify code", "failed to verify second factor", err, "user_id", user.ID)
+		return
+	}
+	if !ok {
+		metrics.LoginFailed(metrics.LoginBadMFACode)
+		apierror.Write(w, r, apierror.Unauthorized(apierror.CodeInvalidCredentials, "Invalid verification code."))
+		return
+	}
+
+	if err := h.mfaStore.CompleteChallenge(r.Context(), tokenHash); err != nil {
+		if !errors.Is(err, models.ErrMFAChallengeInvalid) {
+			logger(r).Error("failed to complete mfa challenge", "error", err, "user_id", user.ID)
+		}
+		metrics.LoginFailed(metrics.LoginMFAExpired)
+		apierror.Write(w, r, errMFAChallengeExpired)
+		return
+	}
+
+	if h.requirePasskeyEnrollment(w, r, user) {
+		return
+	}
+	if h.writeAuthResponse(w, r, http.StatusOK, user) {
+		metrics.LoginSucceeded()
+	} else {
+		metrics.LoginFailed(metrics.LoginError)
+	}
+}
+
+// startMFAChallenge answers the password step of a sign-in for a user with
+// two-factor authentication with a challenge token for LoginMFA
+func (h *Handlers) startMFAChallenge(w http.ResponseWriter, r *http.Request, user models.User) {
+	raw, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		metrics.LoginFailed(metrics.LoginError)
+		serverError(w, r, "Failed to sign in", "failed to create mfa challenge token", err, "user_id", user.ID)
+		return
+	}
+	now := time.Now()
+	c := models.MFAChallenge{
+		TokenHash: hash,
+		UserID:    user.ID,
+		ExpiresAt: now.Add(h.mfaConfig.ChallengeTTL),
+		CreatedAt: now,
+	}
+	if err := h.mfaStore.CreateChallenge(r.Context(), c); err != nil {
+		metrics.LoginFailed(metrics.LoginError)
+		serverError(w, r, "Failed to sign in", "failed to store mfa challenge", err, "user_id", user.ID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MFAChallengeResponse{MFARequired: true, MFAToken: raw, ExpiresAt: c.ExpiresAt})
+}
+
+// checkSecondFactor verifies code for a profile endpoint, writing the error
+// response if it is not accepted
+func (h *Handlers) checkSecondFactor(w http.ResponseWriter, r *http.Request, userID, code string) bool {
+	ok, err := h.verifySecondFactor(r, userID, code)
+	switch {
+	case errors.Is(err, models.ErrMFANotFound):
+		apierror.Write(w, r, errMFANotEnabled)
+		return false
+	case errors.Is(err, errNoMFABox):
+		apierror.Write(w, r, errMFAUnavailable)
+		return false
+	case errors.Is(err, models.ErrMFALocked):
+		writeMFALocked(w, r, err)
+		return false
+	case err != nil:
+		serverError(w, r, "Failed to verify code", "failed to verify second factor", err)
+		return false
+	case !ok:
+		apierror.Write(w, r, errInvalidMFACode)
+		return false
+	}
+	return true
+}
+
+// verifySecondFactor checks a TOTP code or recovery code of a user with
+// confirmed two-factor authentication and uses it up. It returns
+// models.ErrMFANotFound if the user has none, and an error matching
+// models.ErrMFALocked while the user is locked out after
+// models.MaxMFAFailures wrong codes.
+func (h *Handlers) verifySecondFactor(r *http.Request, userID, code string) (bool, error) {
+	m, err := h.mfaStore.Get(r.Context(), userID)
+	if err != nil {
+		return false, err
+	}
+	if !m.Enabled() {
+		return false, models.ErrMFANotFound
+	}
+
+	// Every check counts against the budget of the user before the code is
+	// compared, so concurrent guesses cannot exceed it
+	until, err := h.mfaStore.UseAttempt(r.Context(), userID)
+	if errors.Is(err, models.ErrMFALocked) {
+		return false, &mfaLockedError{until: until}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := h.checkMFACode(r, userID, m, code)
+	if err != nil || !ok {
+		return ok, err
+	}
+	if err := h.mfaStore.ResetAttempts(r.Context(), userID); err != nil {
+		logger(r).Error("failed to reset mfa attempts", "error", err, "user_id", userID)
+	}
+	return true, nil
+}
+
+// checkMFACode checks and uses up a TOTP code or recovery code against m,
+// the second factor of a user
+func (h *Handlers) checkMFACode(r *http.Request, userID string, m models.MFA, code string) (bool, error) {
+	if !mfa.IsCode(code) {
+		ok, err := h.mfaStore.UseRecoveryCode(r.Context(), userID, mfa.HashRecoveryCode(code))
+		if ok {
+			h.recordSecurityEvent(r, userID, models.EventRecoveryCodeUsed)
+		}
+		return ok, err
+	}
+
+	if h.mfaConfig.Box == nil {
+		return false, errNoMFABox
+	}
+	secret, err := h.mfaConfig.Box.Open(m.Secret, mfaOwner(userID))
+	if err != nil {
+		return false, err
+	}
+	step, ok := mfa.Validate(string(secret), code, time.Now(), m.LastStep)
+	if !ok {
+		return false, nil
+	}
+	// Fails if a concurrent request accepted the same code
+	return h.mfaStore.UseStep(r.Context(), userID, step)
+}
+
+// openMFASecret decrypts the TOTP secret of m, writing the error response if
+// it cannot
+func (h *Handlers) openMFASecret(w http.ResponseWriter, r *http.Request, m models.MFA) (string, bool) {
+	if h.mfaConfig.Box == nil {
+		apierror.Write(w, r, errMFAUnavailable)
+		return "", false
+	}
+	secret, err := h.mfaConfig.Box.Open(m.Secret, mfaOwner(m.UserID))
+	if err != nil {
+		serverError(w, r, "Failed to verify code", "failed to decrypt totp secret", err)
+		return "", false
+	}
+	return string(secret), true
+}
+
+// mfaOwner is the additional data binding an encrypted TOTP secret to its
+// user
+func mfaOwner(userID string) []byte {
+	return []byte("user_mfa:" + userID)
+}
+
+// recordSecurityEvent adds event to the security log of a user, logging
+// rather than failing the request if it cannot
+func (h *Handlers) recordSecurityEvent(r *http.Request, userID, event string) {
+	if err := h.securityLog.RecordSecurityEvent(r.Context(), userID, event, session.ClientIP(r), session.ClientUserAgent(r)); err != nil {
+		logger(r).Error("failed to record security event", "error", err, "event", event, "target_user_id", userID)
+	}
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/secretbox"
+)
+
+// mfaUser is a user with confirmed two-factor authentication
+type mfaUser struct {
+	models.User
+	secret        string
+	recoveryCodes []string
+}
+
+// enrollMFA enables two-factor authentication for a new user through the
+// profile endpoints
+func (env *testEnv) enrollMFA(t *testing.T, username, password string) mfaUser {
+	t.Helper()
+	box, err := secretbox.New(make([]byte, secretbox.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.configure(t, func(d *Deps) { d.MFA = MFAConfig{Issuer: "UMS", Box: box} })
+
+	user := env.createUser(t, username, password)
+	as := func(r *http.Request) *http.Request { return asPrincipal(r, user.ID, auth.RoleUser) }
+
+	var enrolled EnrollMFAResponse
+	w := serve(t, env.h.EnrollMFA, http.MethodPost, "/api/profile/mfa/enroll", EnrollMFARequest{Password: password}, as)
+	decode(t, w, http.StatusOK, &enrolled)
+
+	var codes RecoveryCodesResponse
+	w = serve(t, env.h.ConfirmMFA, http.MethodPost, "/api/profile/mfa/confirm", MFACodeRequest{Code: totpCode(t, enrolled.Secret, time.Now())}, as)
+	decode(t, w, http.StatusOK, &codes)
+
+	return mfaUser{User: user, secret: enrolled.Secret, recoveryCodes: codes.RecoveryCodes}
+}
+
+// totpCode returns the code of secret at t
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := totp.GenerateCode(secret, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// startMFALogin signs in with a password and returns the MFA challenge token
+func (env *testEnv) startMFALogin(t *testing.T, username, password string) string {
+	t.Helper()
+	w := serve(t, env.h.Login, http.MethodPost, "/api/login", LoginRequest{Username: username, Password: password})
+	var challenge MFAChallengeResponse
+	decode(t, w, http.StatusOK, &challenge)
+	if !challenge.MFARequired || challenge.MFAToken == "" {
+		t.Fatalf("login did not ask for a second factor: %+v", challenge)
+	}
+	return challenge.MFAToken
+}
+
+// loginMFA completes a sign-in with code
+func (env *testEnv) loginMFA(t *testing.T, token, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	return serve(t, env.h.LoginMFA, http.MethodPost, "/api/login/mfa", LoginMFARequest{MFAToken: token, Code: code})
+}
+
+// wantCode checks that w is a problem response with status and code
+func wantCode(t *testing.T, w *httptest.ResponseRecorder, status int, code apierror.Code) {
+	t.Helper()
+	if p := problem(t, w, status); p.Code != code {
+		t.Errorf("code = %s, want %s", p.Code, code)
+	}
+}
+
+func TestLoginMFA(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.enrollMFA(t, "alice", "password1")
+
+	token := env.startMFALogin(t, "alice", "password1")
+	var resp AuthResponse
+	decode(t, env.loginMFA(t, token, totpCode(t, user.secret, time.Now().Add(30*time.Second))), http.StatusOK, &resp)
+	if resp.User.ID != user.ID || resp.Token == "" {
+		t.Errorf("LoginMFA returned %+v", resp)
+	}
+
+	// The challenge signs in once
+	wantCode(t, env.loginMFA(t, token, totpCode(t, user.secret, time.Now().Add(30*time.Second))), http.StatusUnauthorized, apierror.CodeInvalidToken)
+}
+
+func TestLoginMFARejectsReplayedCode(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.enrollMFA(t, "alice", "password1")
+
+	// The code that confirmed the enrollment cannot sign in
+	code := totpCode(t, user.secret, time.Now())
+	wantCode(t, env.loginMFA(t, env.startMFALogin(t, "alice", "password1"), code), http.StatusUnauthorized, apierror.CodeInvalidCredentials)
+
+	next := totpCode(t, user.secret, time.Now().Add(30*time.Second))
+	decode(t, env.loginMFA(t, env.startMFALogin(t, "alice", "password1"), next), http.StatusOK, nil)
+	wantCode(t, env.loginMFA(t, env.startMFALogin(t, "alice", "password1"), next), http.StatusUnauthorized, apierror.CodeInvalidCredentials)
+}
+
+func TestLoginMFARecoveryCodeSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.enrollMFA(t, "alice", "password1")
+	code := user.recoveryCodes[0]
+
+	decode(t, env.loginMFA(t, env.startMFALogin(t, "alice", "password1"), code), http.StatusOK, nil)
+	wantCode(t, env.loginMFA(t, env.startMFALogin(t, "alice", "password1"), code), http.StatusUnauthorized, apierror.CodeInvalidCredentials)
+
+	var status MFAStatus
+	w := serve(t, env.h.GetMFAStatus, http.MethodGet, "/api/profile/mfa", nil, func(r *http.Request) *http.Request {
+		return asPrincipal(r, user.ID, auth.RoleUser)
+	})
+	decode(t, w, http.StatusOK, &status)
+	if status.RecoveryCodesLeft != len(user.recoveryCodes)-1 {
+		t.Errorf("recovery codes left = %d, want %d", status.RecoveryCodesLeft, len(user.recoveryCodes)-1)
+	}
+
+	events := env.events.Events()
+	if last := events[len(events)-1]; last.Event != models.EventRecoveryCodeUsed {
+		t.Errorf("last security event = %q, want %q", last.Event, models.EventRecoveryCodeUsed)
+	}
+}
+
+func TestLoginMFAAttemptLimit(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.enrollMFA(t, "alice", "password1")
+	token := env.startMFALogin(t, "alice", "password1")
+
+	for i := 0; i < models.MaxMFAAttempts; i++ {
+		wantCode(t, env.loginMFA(t, token, "000000"), http.StatusUnauthorized, apierror.CodeInvalidCredentials)
+	}
+	// Out of attempts, even the right code needs the password again
+	code := totpCode(t, user.secret, time.Now().Add(30*time.Second))
+	wantCode(t, env.loginMFA(t, token, code), http.StatusUnauthorized, apierror.CodeInvalidToken)
+}
+
+func TestLoginMFAConcurrentAttempts(t *testing.T) {
+	env := newTestEnv(t)
+	env.enrollMFA(t, "alice", "password1")
+	token := env.startMFALogin(t, "alice", "password1")
+
+	const guesses = 20
+	var wg sync.WaitGroup
+	codes := make(chan apierror.Code, guesses)
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := serve(t, env.h.LoginMFA, http.MethodPost, "/api/login/mfa", LoginMFARequest{MFAToken: token, Code: "000000"})
+			var p apierror.Problem
+			if w.Code != http.StatusUnauthorized || json.NewDecoder(w.Body).Decode(&p) != nil {
+				t.Errorf("status = %d: %s", w.Code, w.Body)
+			}
+			codes <- p.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	checked := 0
+	for code := range codes {
+		if code == apierror.CodeInvalidCredentials {
+			checked++
+		}
+	}
+	if checked != models.MaxMFAAttempts {
+		t.Errorf("%d of %d concurrent guesses were checked, want %d", checked, guesses, models.MaxMFAAttempts)
+	}
+}
+
+func TestLoginMFALockout(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.enrollMFA(t, "alice", "password1")
+
+	// Wrong codes count across challenges: signing in again buys no more
+	// guesses
+	for i := 0; i < models.MaxMFAFailures; i++ {
+		token := env.startMFALogin(t, "alice", "password1")
+		wantCode(t, env.loginMFA(t, token, "000000"), http.StatusUnauthorized, apierror.CodeInvalidCredentials)
+	}
+	token := env.startMFALogin(t, "alice", "password1")
+	w := env.loginMFA(t, token, totpCode(t, user.secret, time.Now().Add(30*time.Second)))
+	wantCode(t, w, http.StatusTooManyRequests, apierror.CodeTooManyRequests)
+	if retry, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retry <= 0 || retry > int(models.MFALockout.Seconds()) {
+		t.Errorf("Retry-After = %q", w.Header().Get("Retry-After"))
+	}
+
+	// The profile endpoints share the budget
+	as := func(r *http.Request) *http.Request { return asPrincipal(r, user.ID, auth.RoleUser) }
+	w = serve(t, env.h.DisableMFA, http.MethodDelete, "/api/profile/mfa", MFACodeRequest{Code: user.recoveryCodes[0]}, as)
+	wantCode(t, w, http.StatusTooManyRequests, apierror.CodeTooManyRequests)
+	if _, err := env.mfa.Get(context.Background(), user.ID); err != nil {
+		t.Errorf("second factor was disabled while locked: %v", err)
+	}
+}
+
+func TestMFAFailuresShared(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.enrollMFA(t, "alice", "password1")
+	as := func(r *http.Request) *http.Request { return asPrincipal(r, user.ID, auth.RoleUser) }
+	regenerate := func(code string) *httptest.ResponseRecorder {
+		return serve(t, env.h.RegenerateRecoveryCodes, http.MethodPost, "/api/profile/mfa/recovery-codes", MFACodeRequest{Code: code}, as)
+	}
+
+	// An accepted code resets the count
+	for i := 0; i < models.MaxMFAFailures-1; i++ {
+		wantCode(t, regenerate("000000"), http.StatusBadRequest, apierror.CodeValidationFailed)
+	}
+	decode(t, regenerate(user.recoveryCodes[0]), http.StatusOK, nil)
+
+	// Failures on the profile endpoints lock the sign-in
+	for i := 0; i < models.MaxMFAFailures-1; i++ {
+		wantCode(t, regenerate("000000"), http.StatusBadRequest, apierror.CodeValidationFailed)
+	}
+	wantCode(t, env.loginMFA(t, env.startMFALogin(t, "alice", "password1"), "000000"), http.StatusUnauthorized, apierror.CodeInvalidCredentials)
+	wantCode(t, env.loginMFA(t, env.startMFALogin(t, "alice", "password1"), user.recoveryCodes[1]), http.StatusTooManyRequests, apierror.CodeTooManyRequests)
+}
@@ -0,0 +1,709 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gorilla/mux"
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/metrics"
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/password"
+)
+
+// PasskeyConfig configures passkey (WebAuthn) registration and sign-in
+type PasskeyConfig struct {
+	// WebAuthn runs the ceremonies; nil makes passkeys unavailable
+	WebAuthn *webauthn.WebAuthn
+	// RequireForAdmins makes admins sign in with a passkey. Admins without
+	// one must register a passkey to complete a password sign-in.
+	RequireForAdmins bool
+	// CeremonyTTL is how long the response of the authenticator can be
+	// submitted
+	CeremonyTTL time.Duration
+}
+
+var (
+	errPasskeysUnavailable = apierror.New(http.StatusServiceUnavailable, apierror.CodeUnavailable, "Passkeys are not available.")
+	errPasskeyNotFound     = apierror.NotFound("Passkey not found.")
+	errInvalidPasskey      = apierror.Field("credential", apierror.FieldInvalid, "The passkey response is not valid.")
+
+	errPasskeyRegistrationExpired = apierror.Field("token", apierror.FieldInvalid, "The passkey registration has expired; start again.")
+	errPasskeyLoginExpired        = apierror.Unauthorized(apierror.CodeInvalidToken, "The passkey sign-in has expired; try again.")
+	errPasskeyRejected            = apierror.Unauthorized(apierror.CodeInvalidCredentials, "The passkey was not accepted.")
+	errPasskeyEnrollmentExpired   = apierror.Unauthorized(apierror.CodeInvalidToken, "The sign-in has expired; enter your password again.")
+)
+
+// passkeySelection asks for discoverable credentials with user
+// verification, so a passkey alone is enough to sign in
+var passkeySelection = protocol.AuthenticatorSelection{
+	RequireResidentKey: protocol.ResidentKeyRequired(),
+	ResidentKey:        protocol.ResidentKeyRequirementRequired,
+	UserVerification:   protocol.VerificationRequired,
+}
+
+// PasskeyOptionsResponse starts a passkey ceremony. Options is passed to
+// navigator.credentials.create or navigator.credentials.get once its
+// base64url fields are decoded; the response of the authenticator is sent
+// back together with Token.
+type PasskeyOptionsResponse struct {
+	Token     string    `json:"token"`
+	Options   any       `json:"options"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PasskeyOptionsRequest is the body of POST /api/profile/passkeys/options
+type PasskeyOptionsRequest struct {
+	Password string `json:"password" validate:"required,max=128"`
+}
+
+// CreatePasskeyRequest is the body of POST /api/profile/passkeys.
+// Credential is the PublicKeyCredential returned by
+// navigator.credentials.create, with binary fields in base64url.
+type CreatePasskeyRequest struct {
+	Token      string          `json:"token" validate:"required"`
+	Name       string          `json:"name" validate:"max=100"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+// PasskeyEnrollmentResponse is returned instead of an AuthResponse by the
+// password sign-in of an admin who has no passkey when passkeys are required
+// for admins. The token is exchanged at POST /api/login/passkey/enroll/options
+// for the options of a registration, which POST /api/login/passkey/enroll
+// completes.
+type PasskeyEnrollmentResponse struct {
+	PasskeyEnrollmentRequired bool      `json:"passkey_enrollment_required"`
+	EnrollmentToken           string    `json:"enrollment_token"`
+	ExpiresAt                 time.Time `json:"expires_at"`
+}
+
+// PasskeyEnrollmentOptionsRequest is the body of
+// POST /api/login/passkey/enroll/options
+type PasskeyEnrollmentOptionsRequest struct {
+	EnrollmentToken string `json:"enrollment_token" validate:"required"`
+}
+
+// PasskeyLoginRequest is the body of POST /api/login/passkey. Credential is
+// the PublicKeyCredential returned by navigator.credentials.get.
+type PasskeyLoginRequest struct {
+	Token      string          `json:"token" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+// PasskeyResponse describes a passkey. ID is the credential ID in base64url.
+type PasskeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+	BackedUp   bool       `json:"backed_up"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+func newPasskeyResponse(p models.Passkey) PasskeyResponse {
+	return PasskeyResponse{
+		ID:         base64.RawURLEncoding.EncodeToString(p.ID),
+		Name:       p.Name,
+		Transports: p.Transports,
+		BackedUp:   p.BackupState,
+		CreatedAt:  p.CreatedAt,
+		LastUsedAt: p.LastUsedAt,
+	}
+}
+
+// GetPasskeys lists the caller's passkeys
+func (h *Handlers) GetPasskeys(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	passkeys, err := h.passkeyStore.List(r.Context(), principal.UserID)
+	if err != nil {
+		serverError(w, r, "Database error", "failed to list passkeys", err)
+		return
+	}
+
+	resp := make([]PasskeyResponse, 0, len(passkeys))
+	for _, p := range passkeys {
+		resp = append(resp, newPasskeyResponse(p))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// BeginPasskeyRegistration checks the caller's password and returns the
+// options for creating a passkey, which CreatePasskey then stores
+func (h *Handlers) BeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+	if h.passkeyConfig.WebAuthn == nil {
+		apierror.Write(w, r, errPasskeysUnavailable)
+		return
+	}
+
+	var req PasskeyOptionsRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, ok := h.loadUser(w, r, principal.UserID)
+	if !ok {
+		return
+	}
+	if valid, _, err := password.Verify(user.Password, req.Password); err != nil || !valid {
+		apierror.Write(w, r, apierror.Forbidden("Current password is incorrect."))
+		return
+	}
+
+	h.beginPasskeyRegistration(w, r, models.PasskeyRegistration, user)
+}
+
+// beginPasskeyRegistration writes the options for creating a passkey of
+// user, completed by a ceremony of the given kind
+func (h *Handlers) beginPasskeyRegistration(w http.ResponseWriter, r *http.Request, kind string, user models.User) {
+	owner, err := h.loadPasskeyUser(r.Context(), user)
+	if err != nil {
+		serverError(w, r, "Database error", "failed to list passkeys", err)
+		return
+	}
+	// Authenticators that already hold a passkey of the user refuse to
+	// create another one
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(owner.passkeys))
+	for _, c := range owner.WebAuthnCredentials() {
+		exclusions = append(exclusions, c.Descriptor())
+	}
+
+	options, session, err := h.passkeyConfig.WebAuthn.BeginRegistration(owner,
+		webauthn.WithExclusions(exclusions), webauthn.WithAuthenticatorSelection(passkeySelection))
+	if err != nil {
+		serverError(w, r, "Failed to start passkey registration", "failed to begin webauthn registration", err)
+		return
+	}
+	h.startPasskeyCeremony(w, r, kind, user.ID, session, options)
+}
+
+// CreatePasskey verifies the response of the authenticator to
+// BeginPasskeyRegistration and stores the new passkey
+func (h *Handlers) CreatePasskey(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+	if h.passkeyConfig.WebAuthn == nil {
+		apierror.Write(w, r, errPasskeysUnavailable)
+		return
+	}
+
+	var req CreatePasskeyRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	ceremony, session, err := h.takePasskeyCeremony(r.Context(), req.Token, models.PasskeyRegistration)
+	if err != nil || ceremony.UserID != principal.UserID {
+		if err != nil && !errors.Is(err, models.ErrPasskeyCeremonyInvalid) {
+			logger(r).Error("failed to load passkey ceremony", "error", err)
+		}
+		apierror.Write(w, r, errPasskeyRegistrationExpired)
+		return
+	}
+
+	user, ok := h.loadUser(w, r, principal.UserID)
+	if !ok {
+		return
+	}
+	p, ok := h.registerPasskey(w, r, user, session, req)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newPasskeyResponse(p))
+}
+
+// registerPasskey verifies the response of the authenticator in req to the
+// registration session of user and stores the new passkey. It writes the
+// error response and reports whether the passkey was stored.
+func (h *Handlers) registerPasskey(w http.ResponseWriter, r *http.Request, user models.User, session webauthn.SessionData, req CreatePasskeyRequest) (models.Passkey, bool) {
+	owner, err := h.loadPasskeyUser(r.Context(), user)
+	if err != nil {
+		serverError(w, r, "Database error", "failed to list passkeys", err)
+		return models.Passkey{}, false
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		logger(r).Info("invalid passkey registration", "error", err)
+		apierror.Write(w, r, errInvalidPasskey)
+		return models.Passkey{}, false
+	}
+	credential, err := h.passkeyConfig.WebAuthn.CreateCredential(owner, session, parsed)
+	if err != nil {
+		logger(r).Info("passkey registration rejected", "error", err)
+		apierror.Write(w, r, errInvalidPasskey)
+		return models.Passkey{}, false
+	}
+
+	p := models.Passkey{
+		ID:              credential.ID,
+		UserID:          user.ID,
+		Name:            req.Name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      make([]string, 0, len(credential.Transport)),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       time.Now(),
+	}
+	for _, t := range credential.Transport {
+		p.Transports = append(p.Transports, string(t))
+	}
+	if p.Name == "" {
+		p.Name = "Passkey"
+	}
+
+	err = h.passkeyStore.Create(r.Context(), p)
+	if errors.Is(err, models.ErrPasskeyExists) {
+		apierror.Write(w, r, apierror.Conflict(apierror.CodeConflict, "This passkey is already registered."))
+		return p, false
+	}
+	if err != nil {
+		serverError(w, r, "Failed to register passkey", "failed to store passkey", err)
+		return p, false
+	}
+	h.recordSecurityEvent(r, user.ID, models.EventPasskeyAdded)
+	return p, true
+}
+
+// DeletePasskey removes one of the caller's passkeys. When passkeys are
+// required for admins, an admin cannot remove their last one.
+func (h *Handlers) DeletePasskey(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := base64.RawURLEncoding.DecodeString(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, errPasskeyNotFound)
+		return
+	}
+
+	if h.passkeyConfig.RequireForAdmins {
+		user, ok := h.loadUser(w, r, principal.UserID)
+		if !ok {
+			return
+		}
+		owner, err := h.loadPasskeyUser(r.Context(), user)
+		if err != nil {
+			serverError(w, r, "Database error", "failed to list passkeys", err)
+			return
+		}
+		if owner.passkey(id).ID == nil {
+			apierror.Write(w, r, errPasskeyNotFound)
+			return
+		}
+		if user.IsAdmin && len(owner.passkeys) == 1 {
+			apierror.Write(w, r, apierror.Conflict(apierror.CodeConflict, "Admins must keep at least one passkey."))
+			return
+		}
+	}
+
+	err = h.passkeyStore.Delete(r.Context(), principal.UserID, id)
+	if errors.Is(err, models.ErrPasskeyNotFound) {
+		apierror.Write(w, r, errPasskeyNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to delete passkey", "failed to delete passkey", err)
+		return
+	}
+	h.recordSecurityEvent(r, principal.UserID, models.EventPasskeyRemoved)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BeginPasskeyLogin returns the options for signing in with a passkey. The
+// authenticator offers the passkeys it holds, so no username is needed.
+func (h *Handlers) BeginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	if h.passkeyConfig.WebAuthn == nil {
+		apierror.Write(w, r, errPasskeysUnavailable)
+		return
+	}
+
+	options, session, err := h.passkeyConfig.WebAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		serverError(w, r, "Failed to start passkey sign-in", "failed to begin webauthn login", err)
+		return
+	}
+	h.startPasskeyCeremony(w, r, models.PasskeyLogin, "", session, options)
+}
+
+// LoginPasskey completes a sign-in started by BeginPasskeyLogin. A passkey
+// verifies the user on the device, so no second factor is asked for.
+func (h *Handlers) LoginPasskey(w http.ResponseWriter, r *http.Request) {
+	var req PasskeyLoginRequest
+	if !decodeJSON(w, r, &req) {
+		metrics.LoginFailed(metrics.LoginInvalidRequest)
+		return
+	}
+	if h.passkeyConfig.WebAuthn == nil {
+		metrics.LoginFailed(metrics.LoginError)
+		apierror.Write(w, r, errPasskeysUnavailable)
+		return
+	}
+
+	_, session, err := h.takePasskeyCeremony(r.Context(), req.Token, models.PasskeyLogin)
+	if err != nil {
+		if !errors.Is(err, models.ErrPasskeyCeremonyInvalid) {
+			logger(r).Error("failed to load passkey ceremony", "error", err)
+		}
+		metrics.LoginFailed(metrics.LoginBadPasskey)
+		apierror.Write(w, r, errPasskeyLoginExpired)
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		logger(r).Info("invalid passkey assertion", "error", err)
+		metrics.LoginFailed(metrics.LoginInvalidRequest)
+		apierror.Write(w, r, errInvalidPasskey)
+		return
+	}
+
+	// The authenticator names the user through the user handle, which is
+	// the user ID
+	var owner passkeyUser
+	var lookupErr error
+	credential, err := h.passkeyConfig.WebAuthn.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
+		user, err := h.users.Get(r.Context(), string(userHandle))
+		if err == nil {
+			owner, err = h.loadPasskeyUser(r.Context(), user)
+		}
+		if err != nil {
+			if !errors.Is(err, models.ErrUserNotFound) {
+				lookupErr = err
+			}
+			return nil, err
+		}
+		return owner, nil
+	}, session, parsed)
+	if lookupErr != nil {
+		metrics.LoginFailed(metrics.LoginError)
+		serverError(w, r, "Failed to sign in", "failed to load passkey owner", lookupErr)
+		return
+	}
+	if err != nil {
+		logger(r).Info("passkey sign-in rejected", "error", err)
+		metrics.LoginFailed(metrics.LoginBadPasskey)
+		apierror.Write(w, r, errPasskeyRejected)
+		return
+	}
+
+	user := owner.user
+	if user.Status != models.UserStatusActive {
+		metrics.LoginFailed(metrics.LoginInactive)
+		apierror.Write(w, r, errPasskeyRejected)
+		return
+	}
+
+	// A signature counter that did not increase means another authenticator
+	// holds a copy of the key
+	stored := owner.passkey(credential.ID)
+	if stored.CloneWarning || credential.Authenticator.CloneWarning {
+		if !stored.CloneWarning {
+			if err := h.passkeyStore.MarkCloned(r.Context(), credential.ID); err != nil {
+				logger(r).Error("failed to flag cloned passkey", "error", err, "user_id", user.ID)
+			}
+			h.recordSecurityEvent(r, user.ID, models.EventPasskeyCloned)
+			logger(r).Warn("passkey signature counter did not increase", "user_id", user.ID,
+				"stored_count", stored.SignCount, "sign_count", parsed.Response.AuthenticatorData.Counter)
+		}
+		metrics.LoginFailed(metrics.LoginBadPasskey)
+		apierror.Write(w, r, errPasskeyRejected)
+		return
+	}
+	if err := h.passkeyStore.Use(r.Context(), credential.ID, credential.Authenticator.SignCount, credential.Flags.BackupState); err != nil {
+		logger(r).Error("failed to record passkey use", "error", err, "user_id", user.ID)
+	}
+
+	if !h.requireVerifiedEmail(w, r, user) {
+		return
+	}
+
+	if h.writeAuthResponse(w, r, http.StatusOK, user) {
+		metrics.LoginSucceeded()
+	} else {
+		metrics.LoginFailed(metrics.LoginError)
+	}
+}
+
+// BeginPasskeyEnrollment exchanges the token of a PasskeyEnrollmentResponse
+// for the options of a registration, which EnrollPasskey completes
+func (h *Handlers) BeginPasskeyEnrollment(w http.ResponseWriter, r *http.Request) {
+	var req PasskeyEnrollmentOptionsRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if h.passkeyConfig.WebAuthn == nil {
+		apierror.Write(w, r, errPasskeysUnavailable)
+		return
+	}
+
+	ceremony, _, err := h.takePasskeyCeremony(r.Context(), req.EnrollmentToken, models.PasskeyEnrollment)
+	if err != nil {
+		if !errors.Is(err, models.ErrPasskeyCeremonyInvalid) {
+			logger(r).Error("failed to load passkey enrollment", "error", err)
+		}
+		apierror.Write(w, r, errPasskeyEnrollmentExpired)
+		return
+	}
+	user, ok := h.loadEnrollingUser(w, r, ceremony.UserID)
+	if !ok {
+		return
+	}
+	h.beginPasskeyRegistration(w, r, models.PasskeyEnrollmentRegistration, user)
+}
+
+// EnrollPasskey verifies the response of the authenticator to
+// BeginPasskeyEnrollment, stores the new passkey and signs the admin in
+func (h *Handlers) EnrollPasskey(w http.ResponseWriter, r *http.Request) {
+	var req CreatePasskeyRequest
+	if !decodeJSON(w, r, &req) {
+		metrics.LoginFailed(metrics.LoginInvalidRequest)
+		return
+	}
+	if h.passkeyConfig.WebAuthn == nil {
+		metrics.LoginFailed(metrics.LoginError)
+		apierror.Write(w, r, errPasskeysUnavailable)
+		return
+	}
+
+	ceremony, session, err := h.takePasskeyCeremony(r.Context(), req.Token, models.PasskeyEnrollmentRegistration)
+	if err != nil {
+		if !errors.Is(err, models.ErrPasskeyCeremonyInvalid) {
+			logger(r).Error("failed to load passkey ceremony", "error", err)
+		}
+		metrics.LoginFailed(metrics.LoginBadPasskey)
+		apierror.Write(w, r, errPasskeyEnrollmentExpired)
+		return
+	}
+	user, ok := h.loadEnrollingUser(w, r, ceremony.UserID)
+	if !ok {
+		metrics.LoginFailed(metrics.LoginInactive)
+		return
+	}
+	if _, ok := h.registerPasskey(w, r, user, session, req); !ok {
+		metrics.LoginFailed(metrics.LoginBadPasskey)
+		return
+	}
+
+	if !h.requireVerifiedEmail(w, r, user) {
+		return
+	}
+	if h.writeAuthResponse(w, r, http.StatusOK, user) {
+		metrics.LoginSucceeded()
+	} else {
+		metrics.LoginFailed(metrics.LoginError)
+	}
+}
+
+// loadEnrollingUser loads the active user a passkey enrollment belongs to,
+// writing the error response if there is none
+func (h *Handlers) loadEnrollingUser(w http.ResponseWriter, r *http.Request, userID string) (models.User, bool) {
+	user, err := h.users.Get(r.Context(), userID)
+	if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		serverError(w, r, "Failed to sign in", "failed to load user", err, "user_id", userID)
+		return user, false
+	}
+	if err != nil || user.Status != models.UserStatusActive {
+		apierror.Write(w, r, errPasskeyEnrollmentExpired)
+		return user, false
+	}
+	return user, true
+}
+
+// requirePasskey answers the password step of a sign-in for an admin who has
+// a passkey when passkeys are required for admins. It reports whether it
+// wrote a response. Admins without a passkey pass, to be sent to enrollment
+// by requirePasskeyEnrollment once every other check of the sign-in passed.
+func (h *Handlers) requirePasskey(w http.ResponseWriter, r *http.Request, user models.User) bool {
+	if !h.passkeyConfig.RequireForAdmins || !user.IsAdmin {
+		return false
+	}
+
+	n, err := h.passkeyStore.Count(r.Context(), user.ID)
+	if err != nil {
+		metrics.LoginFailed(metrics.LoginError)
+		serverError(w, r, "Failed to sign in", "failed to count passkeys", err, "user_id", user.ID)
+		return true
+	}
+	if n == 0 {
+		return false
+	}
+
+	metrics.LoginFailed(metrics.LoginNeedsPasskey)
+	apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodePasskeyRequired, "Sign in with your passkey."))
+	return true
+}
+
+// requirePasskeyEnrollment answers a sign-in of an admin who has no passkey
+// when passkeys are required for admins with a PasskeyEnrollmentResponse
+// instead of tokens. It reports whether it wrote a response.
+func (h *Handlers) requirePasskeyEnrollment(w http.ResponseWriter, r *http.Request, user models.User) bool {
+	missing, err := h.missingAdminPasskey(r.Context(), user)
+	if err != nil {
+		metrics.LoginFailed(metrics.LoginError)
+		serverError(w, r, "Failed to sign in", "failed to count passkeys", err, "user_id", user.ID)
+		return true
+	}
+	if !missing {
+		return false
+	}
+
+	raw, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		metrics.LoginFailed(metrics.LoginError)
+		serverError(w, r, "Failed to sign in", "failed to create passkey enrollment token", err, "user_id", user.ID)
+		return true
+	}
+	now := time.Now()
+	c := models.PasskeyCeremony{
+		TokenHash: hash,
+		Kind:      models.PasskeyEnrollment,
+		UserID:    user.ID,
+		Data:      "{}",
+		ExpiresAt: now.Add(h.passkeyConfig.CeremonyTTL),
+		CreatedAt: now,
+	}
+	if err := h.passkeyStore.CreateCeremony(r.Context(), c); err != nil {
+		metrics.LoginFailed(metrics.LoginError)
+		serverError(w, r, "Failed to sign in", "failed to store passkey enrollment", err, "user_id", user.ID)
+		return true
+	}
+
+	metrics.LoginFailed(metrics.LoginNeedsPasskey)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PasskeyEnrollmentResponse{PasskeyEnrollmentRequired: true, EnrollmentToken: raw, ExpiresAt: c.ExpiresAt})
+	return true
+}
+
+// missingAdminPasskey reports whether user is an admin without a passkey
+// while passkeys are required for admins
+func (h *Handlers) missingAdminPasskey(ctx context.Context, user models.User) (bool, error) {
+	if !h.passkeyConfig.RequireForAdmins || !user.IsAdmin {
+		return false, nil
+	}
+	n, err := h.passkeyStore.Count(ctx, user.ID)
+	return n == 0, err
+}
+
+// startPasskeyCeremony stores session and writes the options of a passkey
+// ceremony together with the token that completes it
+func (h *Handlers) startPasskeyCeremony(w http.ResponseWriter, r *http.Request, kind, userID string, session *webauthn.SessionData, options any) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		serverError(w, r, "Failed to start passkey ceremony", "failed to encode webauthn session", err)
+		return
+	}
+	raw, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		serverError(w, r, "Failed to start passkey ceremony", "failed to create passkey ceremony token", err)
+		return
+	}
+	now := time.Now()
+	c := models.PasskeyCeremony{
+		TokenHash: hash,
+		Kind:      kind,
+		UserID:    userID,
+		Data:      string(data),
+		ExpiresAt: now.Add(h.passkeyConfig.CeremonyTTL),
+		CreatedAt: now,
+	}
+	if err := h.passkeyStore.CreateCeremony(r.Context(), c); err != nil {
+		serverError(w, r, "Failed to start passkey ceremony", "failed to store passkey ceremony", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PasskeyOptionsResponse{Token: raw, Options: options, ExpiresAt: c.ExpiresAt})
+}
+
+// takePasskeyCeremony uses up the ceremony of a raw token and returns it with
+// its WebAuthn session
+func (h *Handlers) takePasskeyCeremony(ctx context.Context, token, kind string) (models.PasskeyCeremony, webauthn.SessionData, error) {
+	var session webauthn.SessionData
+	c, err := h.passkeyStore.TakeCeremony(ctx, auth.HashOpaqueToken(token), kind)
+	if err != nil {
+		return c, session, err
+	}
+	err = json.Unmarshal([]byte(c.Data), &session)
+	return c, session, err
+}
+
+// passkeyUser adapts a user and their passkeys to webauthn.User. The user
+// handle stored on authenticators is the user ID, which carries no personal
+// information.
+type passkeyUser struct {
+	user     models.User
+	passkeys []models.Passkey
+}
+
+func (h *Handlers) loadPasskeyUser(ctx context.Context, user models.User) (passkeyUser, error) {
+	passkeys, err := h.passkeyStore.List(ctx, user.ID)
+	return passkeyUser{user: user, passkeys: passkeys}, err
+}
+
+func (u passkeyUser) WebAuthnID() []byte          { return []byte(u.user.ID) }
+func (u passkeyUser) WebAuthnName() string        { return u.user.Username }
+func (u passkeyUser) WebAuthnDisplayName() string { return u.user.Username }
+func (u passkeyUser) WebAuthnIcon() string        { return "" }
+
+func (u passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.passkeys))
+	for _, p := range u.passkeys {
+		c := webauthn.Credential{
+			ID:              p.ID,
+			PublicKey:       p.PublicKey,
+			AttestationType: p.AttestationType,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: p.BackupEligible,
+				BackupState:    p.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:       p.AAGUID,
+				SignCount:    p.SignCount,
+				CloneWarning: p.CloneWarning,
+			},
+		}
+		for _, t := range p.Transports {
+			c.Transport = append(c.Transport, protocol.AuthenticatorTransport(t))
+		}
+		credentials = append(credentials, c)
+	}
+	return credentials
+}
+
+// passkey returns the stored passkey with the given credential ID
+func (u passkeyUser) passkey(id []byte) models.Passkey {
+	for _, p := range u.passkeys {
+		if bytes.Equal(p.ID, id) {
+			return p
+		}
+	}
+	return models.Passkey{}
+}
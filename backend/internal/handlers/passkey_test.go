@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:5173"
+)
+
+// usePasskeys enables passkeys for the relying party testRPID
+func (env *testEnv) usePasskeys(t *testing.T, requireForAdmins bool) {
+	t.Helper()
+	rp, err := webauthn.New(&webauthn.Config{RPID: testRPID, RPDisplayName: "UMS", RPOrigins: []string{testOrigin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.configure(t, func(d *Deps) { d.Passkeys = PasskeyConfig{WebAuthn: rp, RequireForAdmins: requireForAdmins} })
+}
+
+// authenticator is a software authenticator holding one ES256 passkey
+type authenticator struct {
+	key    *ecdsa.PrivateKey
+	id     []byte
+	userID []byte
+	// count is the signature counter of the next assertion
+	count uint32
+}
+
+func newAuthenticator(t *testing.T) *authenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+	return &authenticator{key: key, id: id, count: 1}
+}
+
+// authData returns authenticator data with the user present and verified.
+// Registrations also carry the credential ID and the COSE public key.
+func (a *authenticator) authData(t *testing.T, attested bool) []byte {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append([]byte{}, rpIDHash[:]...)
+	flags := byte(0x05)
+	if attested {
+		flags |= 0x40
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.count)
+	if !attested {
+		return data
+	}
+
+	coseKey, err := cbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, make([]byte, 16)...) // AAGUID
+	data = binary.BigEndian.AppendUint16(data, uint16(len(a.id)))
+	data = append(data, a.id...)
+	return append(data, coseKey...)
+}
+
+// clientData returns the client data of a ceremony of the given type
+func clientData(t *testing.T, ceremony string, challenge []byte) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// create answers the options of a registration with a new credential and
+// advances the signature counter
+func (a *authenticator) create(t *testing.T, options protocol.CredentialCreation) json.RawMessage {
+	t.Helper()
+	userID, err := base64.RawURLEncoding.DecodeString(options.Response.User.ID.(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.userID = userID
+	attestation, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(t, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.count++
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	return marshalCredential(t, a.id, map[string]string{
+		"clientDataJSON":    b64(clientData(t, "webauthn.create", options.Response.Challenge)),
+		"attestationObject": b64(attestation),
+	})
+}
+
+// get answers the options of a sign-in with a signed assertion and advances
+// the signature counter
+func (a *authenticator) get(t *testing.T, options protocol.CredentialAssertion) json.RawMessage {
+	t.Helper()
+	authData := a.authData(t, false)
+	client := clientData(t, "webauthn.get", options.Response.Challenge)
+	clientHash := sha256.Sum256(client)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.count++
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	return marshalCredential(t, a.id, map[string]string{
+		"clientDataJSON":    b64(client),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+		"userHandle":        b64(a.userID),
+	})
+}
+
+func marshalCredential(t *testing.T, id []byte, response map[string]string) json.RawMessage {
+	t.Helper()
+	raw := base64.RawURLEncoding.EncodeToString(id)
+	credential, err := json.Marshal(map[string]any{"id": raw, "rawId": raw, "type": "public-key", "response": response})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credential
+}
+
+// registrationOptions is a PasskeyOptionsResponse of a registration
+type registrationOptions struct {
+	Token   string                      `json:"token"`
+	Options protocol.CredentialCreation `json:"options"`
+}
+
+// loginOptions is a PasskeyOptionsResponse of a sign-in
+type loginOptions struct {
+	Token   string                       `json:"token"`
+	Options protocol.CredentialAssertion `json:"options"`
+}
+
+// addPasskey registers a passkey of a on the profile of user
+func (env *testEnv) addPasskey(t *testing.T, user models.User, password string, a *authenticator) *http.Response {
+	t.Helper()
+	as := func(r *http.Request) *http.Request { return asPrincipal(r, user.ID, auth.RoleUser) }
+
+	var options registrationOptions
+	w := serve(t, env.h.BeginPasskeyRegistration, http.MethodPost, "/api/profile/passkeys/options", PasskeyOptionsRequest{Password: password}, as)
+	decode(t, w, http.StatusOK, &options)
+
+	w = serve(t, env.h.CreatePasskey, http.MethodPost, "/api/profile/passkeys",
+		CreatePasskeyRequest{Token: options.Token, Name: "Laptop", Credential: a.create(t, options.Options)}, as)
+	return w.Result()
+}
+
+// loginPasskey signs in with the passkey of a
+func (env *testEnv) loginPasskey(t *testing.T, a *authenticator) *http.Response {
+	t.Helper()
+	var options loginOptions
+	w := serve(t, env.h.BeginPasskeyLogin, http.MethodPost, "/api/login/passkey/options", nil)
+	decode(t, w, http.StatusOK, &options)
+
+	w = serve(t, env.h.LoginPasskey, http.MethodPost, "/api/login/passkey",
+		PasskeyLoginRequest{Token: options.Token, Credential: a.get(t, options.Options)})
+	return w.Result()
+}
+
+// wantStatus checks the status of resp
+func wantStatus(t *testing.T, resp *http.Response, status int) {
+	t.Helper()
+	if resp.StatusCode != status {
+		var p apierror.Problem
+		json.NewDecoder(resp.Body).Decode(&p)
+		t.Fatalf("status = %d, want %d: %+v", resp.StatusCode, status, p)
+	}
+}
+
+// makeAdmin grants user the admin role
+func (env *testEnv) makeAdmin(t *testing.T, user models.User) {
+	t.Helper()
+	if err := models.SetUserAdmin(context.Background(), env.roles, user.ID, true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	env := newTestEnv(t)
+	env.usePasskeys(t, false)
+	user := env.createUser(t, "alice", "password1")
+	a := newAuthenticator(t)
+
+	w := serve(t, env.h.BeginPasskeyRegistration, http.MethodPost, "/api/profile/passkeys/options", PasskeyOptionsRequest{Password: "wrong-password"},
+		func(r *http.Request) *http.Request { return asPrincipal(r, user.ID, auth.RoleUser) })
+	problem(t, w, http.StatusForbidden)
+
+	wantStatus(t, env.addPasskey(t, user, "password1", a), http.StatusCreated)
+	passkeys, err := env.passkeys.List(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passkeys) != 1 || passkeys[0].Name != "Laptop" {
+		t.Fatalf("passkeys = %+v, want one named Laptop", passkeys)
+	}
+
+	resp := env.loginPasskey(t, a)
+	wantStatus(t, resp, http.StatusOK)
+	var signedIn AuthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&signedIn); err != nil {
+		t.Fatal(err)
+	}
+	if signedIn.User.ID != user.ID || signedIn.Token == "" {
+		t.Errorf("signed in as %q with token %q, want %s", signedIn.User.ID, signedIn.Token, user.ID)
+	}
+
+	passkeys, _ = env.passkeys.List(context.Background(), user.ID)
+	if passkeys[0].SignCount != 2 || passkeys[0].LastUsedAt == nil {
+		t.Errorf("passkey use not recorded: %+v", passkeys[0])
+	}
+}
+
+func TestPasskeyLoginCeremonyIsSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	env.usePasskeys(t, false)
+	user := env.createUser(t, "alice", "password1")
+	a := newAuthenticator(t)
+	wantStatus(t, env.addPasskey(t, user, "password1", a), http.StatusCreated)
+
+	var options loginOptions
+	decode(t, serve(t, env.h.BeginPasskeyLogin, http.MethodPost, "/api/login/passkey/options", nil), http.StatusOK, &options)
+	req := PasskeyLoginRequest{Token: options.Token, Credential: a.get(t, options.Options)}
+	decode(t, serve(t, env.h.LoginPasskey, http.MethodPost, "/api/login/passkey", req), http.StatusOK, nil)
+
+	w := serve(t, env.h.LoginPasskey, http.MethodPost, "/api/login/passkey", req)
+	wantCode(t, w, http.StatusUnauthorized, apierror.CodeInvalidToken)
+}
+
+func TestPasskeyCloneDetection(t *testing.T) {
+	env := newTestEnv(t)
+	env.usePasskeys(t, false)
+	user := env.createUser(t, "alice", "password1")
+	a := newAuthenticator(t)
+	wantStatus(t, env.addPasskey(t, user, "password1", a), http.StatusCreated)
+	a.count = 5
+	wantStatus(t, env.loginPasskey(t, a), http.StatusOK)
+
+	// A copy of the key still at an older counter gives itself away
+	a.count = 3
+	wantStatus(t, env.loginPasskey(t, a), http.StatusUnauthorized)
+
+	passkeys, _ := env.passkeys.List(context.Background(), user.ID)
+	if !passkeys[0].CloneWarning {
+		t.Error("passkey not flagged as cloned")
+	}
+	var flagged bool
+	for _, e := range env.events.Events() {
+		flagged = flagged || e.Event == models.EventPasskeyCloned
+	}
+	if !flagged {
+		t.Error("no security event for the cloned passkey")
+	}
+
+	// Once flagged, the passkey is refused even with a higher counter
+	a.count = 10
+	wantStatus(t, env.loginPasskey(t, a), http.StatusUnauthorized)
+}
+
+func TestAdminWithPasskeyCannotUsePassword(t *testing.T) {
+	env := newTestEnv(t)
+	env.usePasskeys(t, true)
+	admin := env.createUser(t, "root", "password1")
+	env.makeAdmin(t, admin)
+	admin.IsAdmin = true
+	a := newAuthenticator(t)
+	wantStatus(t, env.addPasskey(t, admin, "password1", a), http.StatusCreated)
+
+	w := serve(t, env.h.Login, http.MethodPost, "/api/login", LoginRequest{Username: "root", Password: "password1"})
+	wantCode(t, w, http.StatusForbidden, apierror.CodePasskeyRequired)
+
+	wantStatus(t, env.loginPasskey(t, a), http.StatusOK)
+}
+
+// startEnrollment signs in an admin without a passkey with their password
+// and returns the enrollment token
+func (env *testEnv) startEnrollment(t *testing.T, username, password string) string {
+	t.Helper()
+	w := serve(t, env.h.Login, http.MethodPost, "/api/login", LoginRequest{Username: username, Password: password})
+	var enrollment PasskeyEnrollmentResponse
+	decode(t, w, http.StatusOK, &enrollment)
+	if !enrollment.PasskeyEnrollmentRequired || enrollment.EnrollmentToken == "" {
+		t.Fatalf("login did not ask for a passkey: %+v", enrollment)
+	}
+	return enrollment.EnrollmentToken
+}
+
+// enroll registers the passkey of a with an enrollment token
+func (env *testEnv) enroll(t *testing.T, token string, a *authenticator) *http.Response {
+	t.Helper()
+	var options registrationOptions
+	w := serve(t, env.h.BeginPasskeyEnrollment, http.MethodPost, "/api/login/passkey/enroll/options", PasskeyEnrollmentOptionsRequest{EnrollmentToken: token})
+	decode(t, w, http.StatusOK, &options)
+
+	w = serve(t, env.h.EnrollPasskey, http.MethodPost, "/api/login/passkey/enroll",
+		CreatePasskeyRequest{Token: options.Token, Credential: a.create(t, options.Options)})
+	return w.Result()
+}
+
+func TestAdminWithoutPasskeyMustEnroll(t *testing.T) {
+	env := newTestEnv(t)
+	env.usePasskeys(t, true)
+	admin := env.createUser(t, "root", "password1")
+	env.makeAdmin(t, admin)
+
+	token := env.startEnrollment(t, "root", "password1")
+
+	// The enrollment token is no registration of its own
+	w := serve(t, env.h.EnrollPasskey, http.MethodPost, "/api/login/passkey/enroll",
+		CreatePasskeyRequest{Token: token, Credential: json.RawMessage(`{}`)})
+	wantCode(t, w, http.StatusUnauthorized, apierror.CodeInvalidToken)
+
+	a := newAuthenticator(t)
+	resp := env.enroll(t, env.startEnrollment(t, "root", "password1"), a)
+	wantStatus(t, resp, http.StatusOK)
+	var signedIn AuthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&signedIn); err != nil {
+		t.Fatal(err)
+	}
+	if signedIn.User.ID != admin.ID || signedIn.User.Role != auth.RoleAdmin {
+		t.Errorf("signed in as %q with role %q, want admin %s", signedIn.User.ID, signedIn.User.Role, admin.ID)
+	}
+
+	if n, _ := env.passkeys.Count(context.Background(), admin.ID); n != 1 {
+		t.Errorf("admin has %d passkeys, want 1", n)
+	}
+	w = serve(t, env.h.Login, http.MethodPost, "/api/login", LoginRequest{Username: "root", Password: "password1"})
+	wantCode(t, w, http.StatusForbidden, apierror.CodePasskeyRequired)
+	wantStatus(t, env.loginPasskey(t, a), http.StatusOK)
+}
+
+func TestAdminWithoutPasskeyMustEnrollAfterMFA(t *testing.T) {
+	env := newTestEnv(t)
+	env.usePasskeys(t, true)
+	admin := env.enrollMFA(t, "root", "password1")
+	env.makeAdmin(t, admin.User)
+
+	w := env.loginMFA(t, env.startMFALogin(t, "root", "password1"), totpCode(t, admin.secret, time.Now().Add(30*time.Second)))
+	var enrollment PasskeyEnrollmentResponse
+	decode(t, w, http.StatusOK, &enrollment)
+	if !enrollment.PasskeyEnrollmentRequired {
+		t.Fatal("second factor signed the admin in without a passkey")
+	}
+	wantStatus(t, env.enroll(t, enrollment.EnrollmentToken, newAuthenticator(t)), http.StatusOK)
+}
+
+func TestAdminSessionWithoutPasskeyIsNotRenewed(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser(t, "root", "password1")
+	env.makeAdmin(t, admin)
+	resp := env.login(t, "root", "password1")
+
+	env.usePasskeys(t, true)
+	w := serve(t, env.h.RefreshToken, http.MethodPost, "/api/refresh", RefreshRequest{RefreshToken: resp.RefreshToken})
+	wantCode(t, w, http.StatusForbidden, apierror.CodePasskeyRequired)
+}
+
+func TestAdminKeepsLastPasskey(t *testing.T) {
+	env := newTestEnv(t)
+	env.usePasskeys(t, true)
+	admin := env.createUser(t, "root", "password1")
+	first := newAuthenticator(t)
+	wantStatus(t, env.addPasskey(t, admin, "password1", first), http.StatusCreated)
+	env.makeAdmin(t, admin)
+
+	remove := func(a *authenticator) int {
+		id := base64.RawURLEncoding.EncodeToString(a.id)
+		return serve(t, env.h.DeletePasskey, http.MethodDelete, "/api/profile/passkeys/"+id, nil,
+			func(r *http.Request) *http.Request { return asPrincipal(r, admin.ID, auth.RoleAdmin) },
+			withVars(map[string]string{"id": id})).Code
+	}
+	if code := remove(first); code != http.StatusConflict {
+		t.Fatalf("removing the last passkey: status = %d, want %d", code, http.StatusConflict)
+	}
+
+	second := newAuthenticator(t)
+	wantStatus(t, env.addPasskey(t, admin, "password1", second), http.StatusCreated)
+	if code := remove(first); code != http.StatusNoContent {
+		t.Fatalf("removing a passkey: status = %d, want %d", code, http.StatusNoContent)
+	}
+	if code := remove(first); code != http.StatusNotFound {
+		t.Fatalf("removing a removed passkey: status = %d, want %d", code, http.StatusNotFound)
+	}
+}
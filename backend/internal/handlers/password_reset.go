@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/mail"
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/password"
+)
+
+// PasswordReset configures the forgot-password flow
+type PasswordReset struct {
+	// TTL is how long a reset link can be used
+	TTL time.Duration
+	// LinkURL is the frontend page that redeems tokens; the token is added
+	// as the "token" query parameter
+	LinkURL string
+}
+
+// ForgotPasswordRequest is the body of POST /api/password/forgot
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,max=100,email"`
+}
+
+// Normalize prepares the email for validation
+func (req *ForgotPasswordRequest) Normalize() {
+	req.Email = models.NormalizeEmail(req.Email)
+}
+
+// ResetPasswordRequest is the body of POST /api/password/reset
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=128"`
+}
+
+// ForgotPassword emails a password reset link to an active user. It answers
+// 202 whether or not the address belongs to a user, so it cannot be used to
+// find registered addresses. The request does the same work either way: the
+// address is looked up, and the token issued and mailed, by the mail worker.
+// Requests are throttled by client IP and address, see throttleMail.
+func (h *Handlers) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	send, ok := h.throttleMail(w, r, mailKindPasswordReset, req.Email)
+	if !ok {
+		return
+	}
+	if !send {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	log := logger(r)
+	h.queueMail(r, func(ctx context.Context, m mail.Mailer) {
+		user, err := h.users.GetByEmail(ctx, req.Email)
+		switch {
+		case errors.Is(err, models.ErrUserNotFound):
+		case err != nil:
+			log.Error("failed to load user", "error", err)
+		case user.Status == models.UserStatusActive:
+			h.mailPasswordReset(ctx, log, m, user)
+		}
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password with a reset token. Every session and
+// refresh token of the user is revoked, so whoever knew the old password is
+// signed out.
+func (h *Handlers) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	hashed, err := password.Hash(req.Password)
+	if err != nil {
+		serverError(w, r, "Failed to reset password", "failed to hash password", err)
+		return
+	}
+
+	user, err := h.passwordResets.Reset(r.Context(), auth.HashOpaqueToken(req.Token), hashed)
+	if errors.Is(err, models.ErrPasswordResetInvalid) {
+		apierror.Write(w, r, apierror.Field("token", apierror.FieldInvalid, "The reset link is invalid or has expired."))
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to reset password", "failed to reset password", err)
+		return
+	}
+
+	if err := h.EndUserSessions(r.Context(), user.ID); err != nil {
+		logger(r).Error("failed to end sessions after password reset", "error", err, "user_id", user.ID)
+	}
+	h.recordSecurityEvent(r, user.ID, models.EventPasswordReset)
+	logger(r).Info("password reset", "user_id", user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mailPasswordReset issues a reset token for user and mails the link.
+// Failures are logged rather than returned, as ForgotPassword answers the
+// same either way.
+func (h *Handlers) mailPasswordReset(ctx context.Context, log *slog.Logger, m mail.Mailer, user models.User) {
+	raw, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		log.Error("failed to create password reset token", "error", err, "user_id", user.ID)
+		return
+	}
+	now := time.Now()
+	reset := models.PasswordReset{
+		TokenHash: hash,
+		UserID:    user.ID,
+		ExpiresAt: now.Add(h.passwordReset.TTL),
+		CreatedAt: now,
+	}
+	if err := h.passwordResets.Create(ctx, reset); err != nil {
+		log.Error("failed to store password reset token", "error", err, "user_id", user.ID)
+		return
+	}
+
+	sendMail(ctx, log, m, user.ID, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("Hello %s,\n\n"+
+			"Someone asked to reset the password of your account. Open the link below to choose a new one. It expires in %s.\n\n"+
+			"%s?token=%s\n\n"+
+			"If it was not you, ignore this email; your password stays the same.\n",
+			user.Username, h.passwordReset.TTL, h.passwordReset.LinkURL, raw),
+	})
+}
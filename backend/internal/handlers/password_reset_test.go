@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// issueReset stores a password reset for userID expiring after ttl and
+// returns the raw token
+func (env *testEnv) issueReset(t *testing.T, userID string, ttl time.Duration) string {
+	t.Helper()
+	raw, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	reset := models.PasswordReset{TokenHash: hash, UserID: userID, ExpiresAt: now.Add(ttl), CreatedAt: now}
+	if err := env.resets.Create(context.Background(), reset); err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestResetPassword(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+	old := env.login(t, "alice", "password1")
+	token := env.issueReset(t, user.ID, time.Hour)
+
+	w := serve(t, env.h.ResetPassword, http.MethodPost, "/api/password/reset", ResetPasswordRequest{Token: token, Password: "password2"})
+	decode(t, w, http.StatusNoContent, nil)
+
+	env.login(t, "alice", "password2")
+	env.refreshFails(t, old.RefreshToken)
+
+	// Reset links are single-use
+	w = serve(t, env.h.ResetPassword, http.MethodPost, "/api/password/reset", ResetPasswordRequest{Token: token, Password: "password3"})
+	problem(t, w, http.StatusBadRequest)
+}
+
+func TestResetPasswordRejected(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+	pending := models.User{Username: "bob", Email: "bob@example.com", Status: models.UserStatusPending}
+	if err := env.users.Create(context.Background(), &pending); err != nil {
+		t.Fatal(err)
+	}
+
+	superseded := env.issueReset(t, user.ID, time.Hour)
+	env.issueReset(t, user.ID, time.Hour)
+
+	tests := map[string]string{
+		"unknown":    "not-a-token",
+		"expired":    env.issueReset(t, user.ID, -time.Second),
+		"superseded": superseded,
+		// Invited users accept their invitation instead
+		"pending user": env.issueReset(t, pending.ID, time.Hour),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := serve(t, env.h.ResetPassword, http.MethodPost, "/api/password/reset", ResetPasswordRequest{Token: token, Password: "password2"})
+			problem(t, w, http.StatusBadRequest)
+		})
+	}
+	env.login(t, "alice", "password1")
+}
+
+func TestForgotPassword(t *testing.T) {
+	env := newTestEnv(t)
+	env.useMail(t)
+	env.configure(t, func(d *Deps) { d.PasswordReset = PasswordReset{LinkURL: "http://localhost:5173/reset-password"} })
+	env.createUser(t, "alice", "password1")
+	pending := models.User{Username: "bob", Email: "bob@example.com", Status: models.UserStatusPending}
+	if err := env.users.Create(context.Background(), &pending); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is looked up or issued while the request waits
+	for _, addr := range []string{"alice@example.com", "bob@example.com", "nobody@example.com"} {
+		w := serve(t, env.h.ForgotPassword, http.MethodPost, "/api/password/forgot", ForgotPasswordRequest{Email: addr})
+		decode(t, w, http.StatusAccepted, nil)
+	}
+	if got := len(env.mail.Messages()); got != 0 {
+		t.Fatalf("%d messages sent before the mail worker ran", got)
+	}
+
+	msgs := env.deliverMail()
+	if len(msgs) != 1 || msgs[0].To != "alice@example.com" {
+		t.Fatalf("delivered %+v, want one message to alice", msgs)
+	}
+
+	w := serve(t, env.h.ResetPassword, http.MethodPost, "/api/password/reset", ResetPasswordRequest{Token: linkToken(t, msgs[0]), Password: "password2"})
+	decode(t, w, http.StatusNoContent, nil)
+	env.login(t, "alice", "password2")
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/password"
+)
+
+// ProfileResponse is the authenticated user's own record
+type ProfileResponse struct {
+	models.User
+	Roles []string `json:"roles"`
+}
+
+// UpdateProfileRequest holds the profile fields a user may change themselves
+type UpdateProfileRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=50,username"`
+	Email    string `json:"email" validate:"required,max=100,email"`
+}
+
+// Normalize prepares the username and email for validation
+func (req *UpdateProfileRequest) Normalize() {
+	req.Username = models.NormalizeUsername(req.Username)
+	req.Email = models.NormalizeEmail(req.Email)
+}
+
+// ChangePasswordRequest is the body of PUT /api/profile/password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=8,max=128"`
+}
+
+// GetProfile returns the caller's own user record
+func (h *Handlers) GetProfile(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	user, ok := h.loadUser(w, r, principal.UserID)
+	if !ok {
+		return
+	}
+
+	h.writeProfile(w, r, user)
+}
+
+// UpdateProfile updates the caller's username and email. Role changes go
+// through the user and role endpoints.
+func (h *Handlers) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateProfileRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, ok := h.loadUser(w, r, principal.UserID)
+	if !ok {
+		return
+	}
+	emailChanged := user.Email != req.Email
+	user.Username = req.Username
+	user.Email = req.Email
+
+	updated, err := h.users.Update(r.Context(), user)
+	if errors.Is(err, models.ErrUserExists) {
+		apierror.Write(w, r, userConflict(err))
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to update profile", "failed to update profile", err)
+		return
+	}
+	// The new address is unverified until the user follows the link
+	if emailChanged {
+		h.sendVerification(r, updated)
+	}
+
+	h.writeProfile(w, r, updated)
+}
+
+// ChangePassword replaces the caller's password after verifying the current
+// one, then signs out every other session of the user
+func (h *Handlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	principal, ok := currentPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, ok := h.loadUser(w, r, principal.UserID)
+	if !ok {
+		return
+	}
+
+	valid, _, err := password.Verify(user.Password, req.CurrentPassword)
+	if err != nil || !valid {
+		apierror.Write(w, r, apierror.Forbidden("Current password is incorrect."))
+		return
+	}
+
+	hashed, err := password.Hash(req.NewPassword)
+	if err != nil {
+		serverError(w, r, "Failed to change password", "failed to hash password", err)
+		return
+	}
+	err = h.users.UpdatePassword(r.Context(), user.ID, hashed)
+	if errors.Is(err, models.ErrUserNotFound) {
+		apierror.Write(w, r, errUserNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to change password", "failed to store password", err)
+		return
+	}
+
+	// Anyone else holding a session with the old password is signed out
+	if err := h.EndOtherSessions(r.Context(), user.ID, principal.SessionID); err != nil {
+		logger(r).Error("failed to end other sessions after password change", "error", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// currentPrincipal returns the authenticated caller or writes a 401
+func currentPrincipal(w http.ResponseWriter, r *http.Request) (auth.Principal, bool) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok || principal.UserID == "" {
+		auth.WriteError(w, r, auth.ErrUnauthenticated)
+		return principal, false
+	}
+	return principal, true
+}
+
+func (h *Handlers) writeProfile(w http.ResponseWriter, r *http.Request, user models.User) {
+	roles, err := h.roleRepository.UserRoles(r.Context(), user.ID)
+	if err != nil {
+		serverError(w, r, "Database error", "failed to load roles", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ProfileResponse{User: user, Roles: roles})
+}
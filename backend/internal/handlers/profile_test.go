@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// asSession returns an option signing a request in with the access token of
+// resp, including its session
+func (env *testEnv) asSession(t *testing.T, resp AuthResponse) func(*http.Request) *http.Request {
+	t.Helper()
+	p, err := env.tokens.Verify(resp.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(r *http.Request) *http.Request {
+		return r.WithContext(auth.WithPrincipal(r.Context(), p))
+	}
+}
+
+func TestChangePasswordWrongCurrent(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "alice", "password1")
+	resp := env.login(t, "alice", "password1")
+
+	w := serve(t, env.h.ChangePassword, http.MethodPut, "/api/profile/password",
+		ChangePasswordRequest{CurrentPassword: "wrong-password", NewPassword: "password2"}, env.asSession(t, resp))
+	problem(t, w, http.StatusForbidden)
+
+	env.login(t, "alice", "password1")
+	env.refreshSession(t, resp.RefreshToken)
+}
+
+func TestChangePasswordEndsOtherSessions(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "alice", "password1")
+	current := env.login(t, "alice", "password1")
+	other := env.login(t, "alice", "password1")
+
+	w := serve(t, env.h.ChangePassword, http.MethodPut, "/api/profile/password",
+		ChangePasswordRequest{CurrentPassword: "password1", NewPassword: "password2"}, env.asSession(t, current))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204: %s", w.Code, w.Body)
+	}
+
+	// The session that changed the password stays signed in
+	env.refreshSession(t, current.RefreshToken)
+	env.refreshFails(t, other.RefreshToken)
+	p, err := env.tokens.Verify(other.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.sessions.CheckSession(context.Background(), p.SessionID); err == nil {
+		t.Error("other session is still active after the password change")
+	}
+
+	env.login(t, "alice", "password2")
+	w = serve(t, env.h.Login, http.MethodPost, "/api/login", LoginRequest{Username: "alice", Password: "password1"})
+	problem(t, w, http.StatusUnauthorized)
+}
+
+func TestUpdateProfileEmailChange(t *testing.T) {
+	env := newTestEnv(t)
+	env.useMail(t)
+	user := env.createUser(t, "alice", "password1")
+	token := env.issueVerification(t, user)
+	decode(t, serve(t, env.h.VerifyEmail, http.MethodPost, "/api/email/verify", VerifyEmailRequest{Token: token}), http.StatusOK, nil)
+	as := func(r *http.Request) *http.Request { return asPrincipal(r, user.ID, auth.RoleUser) }
+
+	// Keeping the address keeps it verified
+	var got ProfileResponse
+	w := serve(t, env.h.UpdateProfile, http.MethodPut, "/api/profile", UpdateProfileRequest{Username: "alice2", Email: user.Email}, as)
+	decode(t, w, http.StatusOK, &got)
+	if got.Username != "alice2" || got.EmailVerifiedAt == nil {
+		t.Errorf("profile = %+v, want renamed and still verified", got.User)
+	}
+	if msgs := env.deliverMail(); len(msgs) != 0 {
+		t.Errorf("%d emails sent without an address change", len(msgs))
+	}
+
+	w = serve(t, env.h.UpdateProfile, http.MethodPut, "/api/profile", UpdateProfileRequest{Username: "alice2", Email: "alice@example.org"}, as)
+	decode(t, w, http.StatusOK, &got)
+	if got.Email != "alice@example.org" || got.EmailVerifiedAt != nil {
+		t.Errorf("profile = %+v, want the new address unverified", got.User)
+	}
+
+	msgs := env.deliverMail()
+	if len(msgs) != 1 || msgs[0].To != "alice@example.org" {
+		t.Fatalf("emails = %+v, want one verification to the new address", msgs)
+	}
+	w = serve(t, env.h.VerifyEmail, http.MethodPost, "/api/email/verify", VerifyEmailRequest{Token: linkToken(t, msgs[0])})
+	var verified models.User
+	decode(t, w, http.StatusOK, &verified)
+	if verified.EmailVerifiedAt == nil {
+		t.Error("new address not verified by the emailed link")
+	}
+}
+
+func TestUpdateProfileTaken(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+	env.createUser(t, "bob", "password1")
+	as := func(r *http.Request) *http.Request { return asPrincipal(r, user.ID, auth.RoleUser) }
+
+	tests := []struct {
+		req  UpdateProfileRequest
+		code apierror.Code
+	}{
+		{UpdateProfileRequest{Username: "alice", Email: "BOB@example.com"}, apierror.CodeEmailTaken},
+		{UpdateProfileRequest{Username: "Bob", Email: "alice@example.com"}, apierror.CodeUsernameTaken},
+	}
+	for _, tt := range tests {
+		w := serve(t, env.h.UpdateProfile, http.MethodPut, "/api/profile", tt.req, as)
+		wantCode(t, w, http.StatusConflict, tt.code)
+	}
+
+	stored, err := env.users.Get(context.Background(), user.ID)
+	if err != nil || stored.Username != "alice" || stored.Email != "alice@example.com" {
+		t.Errorf("alice = %+v, %v; want unchanged", stored, err)
+	}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// RoleRequest is the body for creating or updating a role
+type RoleRequest struct {
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+// UserRolesRequest is the body of PUT /api/users/{id}/roles
+type UserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+
+// GetPermissions lists every permission that can be granted to a role
+func (h *Handlers) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.policy.Check(w, r, auth.PermRolesManage, auth.Collection("permissions")); !ok {
+		return
+	}
+
+	permissions, err := h.roleRepository.Permissions(r.Context())
+	if err != nil {
+		serverError(w, r, "Database error", "failed to list permissions", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(permissions)
+}
+
+// GetRoles lists all roles with their permissions
+func (h *Handlers) GetRoles(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.policy.Check(w, r, auth.PermRolesManage, auth.Collection("roles")); !ok {
+		return
+	}
+
+	roles, err := h.roleRepository.List(r.Context())
+	if err != nil {
+		serverError(w, r, "Database error", "failed to list roles", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roles)
+}
+
+// GetRole retrieves a role by ID
+func (h *Handlers) GetRole(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, ok := h.policy.Check(w, r, auth.PermRolesManage, auth.Resource{Type: "role", ID: id}); !ok {
+		return
+	}
+
+	role, err := h.roleRepository.Get(r.Context(), id)
+	if errors.Is(err, models.ErrRoleNotFound) {
+		apierror.Write(w, r, errRoleNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Database error", "failed to load role", err, "role_id", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(role)
+}
+
+// CreateRole creates a custom role
+func (h *Handlers) CreateRole(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.policy.Check(w, r, auth.PermRolesManage, auth.Collection("roles")); !ok {
+		return
+	}
+
+	role, ok := h.decodeRole(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.roleRepository.Create(r.Context(), &role)
+	if errors.Is(err, models.ErrRoleExists) {
+		apierror.Write(w, r, errRoleExists)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to create role", "failed to create role", err, "role", role.Name)
+		return
+	}
+	h.policy.Invalidate()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+// UpdateRole replaces a role's name, description and permissions
+func (h *Handlers) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, ok := h.policy.Check(w, r, auth.PermRolesManage, auth.Resource{Type: "role", ID: id}); !ok {
+		return
+	}
+
+	role, ok := h.decodeRole(w, r)
+	if !ok {
+		return
+	}
+	role.ID = id
+
+	updated, err := h.roleRepository.Update(r.Context(), role)
+	switch {
+	case errors.Is(err, models.ErrRoleNotFound):
+		apierror.Write(w, r, errRoleNotFound)
+		return
+	case errors.Is(err, models.ErrBuiltInRole):
+		apierror.Write(w, r, errBuiltInRole)
+		return
+	case errors.Is(err, models.ErrRoleExists):
+		apierror.Write(w, r, errRoleExists)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to update role", "failed to update role", err, "role_id", id)
+		return
+	}
+	h.policy.Invalidate()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteRole deletes a custom role. Built-in roles cannot be deleted.
+func (h *Handlers) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, ok := h.policy.Check(w, r, auth.PermRolesManage, auth.Resource{Type: "role", ID: id}); !ok {
+		return
+	}
+
+	err := h.roleRepository.Delete(r.Context(), id)
+	switch {
+	case errors.Is(err, models.ErrRoleNotFound):
+		apierror.Write(w, r, errRoleNotFound)
+		return
+	case errors.Is(err, models.ErrBuiltInRole):
+		apierror.Write(w, r, errBuiltInRole)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to delete role", "failed to delete role", err, "role_id", id)
+		return
+	}
+	h.policy.Invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetUserRoles replaces the roles assigned to a user
+func (h *Handlers) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+	if _, ok := h.policy.Check(w, r, auth.PermRolesAssign, auth.UserResource(userID)); !ok {
+		return
+	}
+
+	var req UserRolesRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if _, ok := h.loadUser(w, r, userID); !ok {
+		return
+	}
+
+	err := h.roleRepository.SetUserRoles(r.Context(), userID, req.Roles)
+	if errors.Is(err, models.ErrRoleNotFound) {
+		apierror.Write(w, r, errUnknownRole)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to update roles", "failed to set roles", err, "target_user_id", userID)
+		return
+	}
+
+	// Sessions carry the roles they were started with
+	if err := h.EndUserSessions(r.Context(), userID); err != nil {
+		logger(r).Error("failed to end sessions after role change", "error", err, "target_user_id", userID)
+	}
+
+	roles, err := h.roleRepository.UserRoles(r.Context(), userID)
+	if err != nil {
+		serverError(w, r, "Database error", "failed to load roles", err, "target_user_id", userID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UserRolesRequest{Roles: roles})
+}
+
+// decodeRole reads and validates a RoleRequest
+func (h *Handlers) decodeRole(w http.ResponseWriter, r *http.Request) (models.Role, bool) {
+	var req RoleRequest
+	if !decodeJSON(w, r, &req) {
+		return models.Role{}, false
+	}
+
+	if !roleNamePattern.MatchString(req.Name) {
+		apierror.Write(w, r, apierror.Field("name", apierror.FieldInvalid, "Role name must be 1-50 lowercase letters, digits, '-' or '_'."))
+		return models.Role{}, false
+	}
+
+	known, err := h.roleRepository.Permissions(r.Context())
+	if err != nil {
+		serverError(w, r, "Database error", "failed to list permissions", err)
+		return models.Role{}, false
+	}
+	valid := map[string]bool{}
+	for _, p := range known {
+		valid[p.Name] = true
+	}
+	for _, p := range req.Permissions {
+		if !valid[p] {
+			apierror.Write(w, r, apierror.Field("permissions", apierror.FieldUnknown, "Unknown permission: "+p))
+			return models.Role{}, false
+		}
+	}
+
+	return models.Role{Name: req.Name, Description: req.Description, Permissions: req.Permissions}, true
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// withVars returns an option setting the mux route variables of a request
+func withVars(vars map[string]string) func(*http.Request) *http.Request {
+	return func(r *http.Request) *http.Request { return mux.SetURLVars(r, vars) }
+}
+
+// asAdmin returns an option signing a request in as an administrator
+func asAdmin(r *http.Request) *http.Request {
+	return asPrincipal(r, "admin", auth.RoleAdmin)
+}
+
+func TestSetUserRoles(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+	resp := env.login(t, "alice", "password1")
+
+	w := serve(t, env.h.SetUserRoles, http.MethodPut, "/api/users/"+user.ID+"/roles",
+		UserRolesRequest{Roles: []string{auth.RoleAdmin, auth.RoleUser}},
+		asAdmin, withVars(map[string]string{"id": user.ID}))
+	var got UserRolesRequest
+	decode(t, w, http.StatusOK, &got)
+	if len(got.Roles) != 2 {
+		t.Errorf("roles = %v, want admin and user", got.Roles)
+	}
+
+	// The admin flag follows the admin role
+	stored, err := env.users.Get(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.IsAdmin {
+		t.Error("is_admin not set after assigning the admin role")
+	}
+
+	// Sessions carrying the old roles are ended
+	env.refreshFails(t, resp.RefreshToken)
+}
+
+func TestSetUserRolesUnknownRole(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+
+	w := serve(t, env.h.SetUserRoles, http.MethodPut, "/api/users/"+user.ID+"/roles",
+		UserRolesRequest{Roles: []string{"nope"}},
+		asAdmin, withVars(map[string]string{"id": user.ID}))
+	problem(t, w, http.StatusBadRequest)
+
+	roles, err := env.roles.UserRoles(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roles) != 1 || roles[0] != auth.RoleUser {
+		t.Errorf("roles changed to %v", roles)
+	}
+}
+
+func TestSetUserRolesForbidden(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+
+	w := serve(t, env.h.SetUserRoles, http.MethodPut, "/api/users/"+user.ID+"/roles",
+		UserRolesRequest{Roles: []string{auth.RoleAdmin}},
+		func(r *http.Request) *http.Request { return asPrincipal(r, user.ID, auth.RoleUser) },
+		withVars(map[string]string{"id": user.ID}))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403: %s", w.Code, w.Body)
+	}
+}
+
+func TestCreateRole(t *testing.T) {
+	env := newTestEnv(t)
+
+	req := RoleRequest{Name: "support", Permissions: []string{string(auth.PermUsersRead)}}
+	w := serve(t, env.h.CreateRole, http.MethodPost, "/api/roles", req, asAdmin)
+	var role models.Role
+	decode(t, w, http.StatusCreated, &role)
+	if role.ID == "" || role.Name != "support" {
+		t.Errorf("created role = %+v", role)
+	}
+
+	grants, err := env.roles.Grants(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := grants["support"]; len(got) != 1 || got[0] != string(auth.PermUsersRead) {
+		t.Errorf("support grants = %v", got)
+	}
+
+	w = serve(t, env.h.CreateRole, http.MethodPost, "/api/roles", req, asAdmin)
+	if p := problem(t, w, http.StatusConflict); p.Code != apierror.CodeConflict {
+		t.Errorf("code = %s, want %s", p.Code, apierror.CodeConflict)
+	}
+}
+
+// brokenRoles is a role repository whose lookups fail like an unreachable
+// database
+type brokenRoles struct {
+	*models.MemoryRoleRepository
+}
+
+func (brokenRoles) Get(ctx context.Context, id string) (models.Role, error) {
+	return models.Role{}, errors.New("connection refused")
+}
+
+func TestGetRoleStatus(t *testing.T) {
+	env := newTestEnv(t)
+	roles, err := env.roles.List(context.Background())
+	if err != nil || len(roles) == 0 {
+		t.Fatalf("List = %v, %v", roles, err)
+	}
+
+	tests := []struct {
+		name string
+		repo models.RoleRepository
+		id   string
+		want int
+	}{
+		{"found", env.roles, roles[0].ID, http.StatusOK},
+		{"missing", env.roles, "999", http.StatusNotFound},
+		{"repository error", brokenRoles{env.roles}, roles[0].ID, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.configure(t, func(d *Deps) { d.Roles = tt.repo })
+			w := serve(t, env.h.GetRole, http.MethodGet, "/api/roles/"+tt.id, nil, asAdmin, withVars(map[string]string{"id": tt.id}))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/search"
+)
+
+// SearchResponse is the body of GET /api/users/search
+type SearchResponse struct {
+	Query   string          `json:"query"`
+	Results []search.Result `json:"results"`
+}
+
+// SearchUsers returns users matching ?q ranked by relevance
+func (h *Handlers) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.policy.Check(w, r, auth.PermUsersList, auth.Collection("users")); !ok {
+		return
+	}
+
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		apierror.Write(w, r, apierror.Field("q", apierror.FieldRequired, "A search query is required."))
+		return
+	}
+
+	limit := search.DefaultLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > search.MaxLimit {
+			apierror.Write(w, r, apierror.Field("limit", apierror.FieldInvalid, fmt.Sprintf("limit must be between 1 and %d.", search.MaxLimit)))
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.userSearcher.SearchUsers(r.Context(), q, limit)
+	if err != nil {
+		serverError(w, r, "Database error", "failed to search users", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SearchResponse{Query: q, Results: results})
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yourusername/ums/backend/internal/auth"
+)
+
+func TestSearchUsers(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "john_doe", "password1")
+	env.createUser(t, "alice", "password1")
+
+	w := serve(t, env.h.SearchUsers, http.MethodGet, "/api/users/search?q=jo", nil, asAdmin)
+	var resp SearchResponse
+	decode(t, w, http.StatusOK, &resp)
+	if len(resp.Results) != 1 || resp.Results[0].User.Username != "john_doe" {
+		t.Fatalf("results = %+v, want john_doe", resp.Results)
+	}
+	if h := resp.Results[0].Highlights["username"]; h != "<mark>jo</mark>hn_doe" {
+		t.Errorf("username highlight = %q", h)
+	}
+}
+
+func TestSearchUsersInvalid(t *testing.T) {
+	env := newTestEnv(t)
+
+	p := problem(t, serve(t, env.h.SearchUsers, http.MethodGet, "/api/users/search", nil, asAdmin), http.StatusBadRequest)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "q" {
+		t.Errorf("errors = %+v, want q", p.Errors)
+	}
+	p = problem(t, serve(t, env.h.SearchUsers, http.MethodGet, "/api/users/search?q=jo&limit=1000", nil, asAdmin), http.StatusBadRequest)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "limit" {
+		t.Errorf("errors = %+v, want limit", p.Errors)
+	}
+
+	w := serve(t, env.h.SearchUsers, http.MethodGet, "/api/users/search?q=jo", nil,
+		func(r *http.Request) *http.Request { return asPrincipal(r, "1", auth.RoleUser) })
+	problem(t, w, http.StatusForbidden)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/session"
+)
+
+// SessionResponse is an active session as listed to its owner
+type SessionResponse struct {
+	session.Session
+	Current bool `json:"current"`
+}
+
+// Logout ends the caller's current session
+func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	if principal.SessionID != "" {
+		if err := h.endSession(r.Context(), principal.SessionID); err != nil && !errors.Is(err, session.ErrNotFound) {
+			serverError(w, r, "Failed to log out", "failed to end session", err, "session_id", principal.SessionID)
+			return
+		}
+	}
+
+	h.sessionManager.ClearCookie(w, r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll ends every session of the caller, signing them out everywhere
+func (h *Handlers) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	if err := h.EndUserSessions(r.Context(), principal.UserID); err != nil {
+		serverError(w, r, "Failed to log out", "failed to end sessions", err)
+		return
+	}
+
+	h.sessionManager.ClearCookie(w, r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSessions lists the caller's active sessions
+func (h *Handlers) GetSessions(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	list, err := h.sessionManager.Store().ListByUser(r.Context(), principal.UserID)
+	if err != nil {
+		serverError(w, r, "Database error", "failed to list sessions", err)
+		return
+	}
+
+	resp := make([]SessionResponse, 0, len(list))
+	for _, s := range list {
+		resp = append(resp, SessionResponse{Session: s, Current: s.ID == principal.SessionID})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RevokeSession ends one of the caller's sessions
+func (h *Handlers) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	id := mux.Vars(r)["id"]
+
+	s, err := h.sessionManager.Store().Get(r.Context(), id)
+	if err != nil || s.UserID != principal.UserID {
+		apierror.Write(w, r, apierror.NotFound("Session not found."))
+		return
+	}
+
+	if err := h.endSession(r.Context(), id); err != nil && !errors.Is(err, session.ErrNotFound) {
+		serverError(w, r, "Failed to revoke session", "failed to end session", err, "session_id", id)
+		return
+	}
+
+	if id == principal.SessionID {
+		h.sessionManager.ClearCookie(w, r)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// endSession revokes a session together with its refresh tokens
+func (h *Handlers) endSession(ctx context.Context, id string) error {
+	if err := h.refreshTokens.RevokeFamily(ctx, id); err != nil {
+		return err
+	}
+	return h.sessionManager.Store().Revoke(ctx, id)
+}
+
+// EndUserSessions revokes every session and refresh token of a user
+func (h *Handlers) EndUserSessions(ctx context.Context, userID string) error {
+	if err := h.refreshTokens.RevokeUser(ctx, userID); err != nil {
+		return err
+	}
+	return h.sessionManager.Store().RevokeAllForUser(ctx, userID)
+}
+
+// EndOtherSessions revokes every session and refresh token of a user except
+// the session keepID
+func (h *Handlers) EndOtherSessions(ctx context.Context, userID, keepID string) error {
+	if err := h.refreshTokens.RevokeOthers(ctx, userID, keepID); err != nil {
+		return err
+	}
+	return h.sessionManager.Store().RevokeOthers(ctx, userID, keepID)
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/mail"
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/search"
+	"github.com/yourusername/ums/backend/internal/session"
+)
+
+// testEnv holds the in-memory stores the handlers are wired to by newTestEnv
+type testEnv struct {
+	users    *models.MemoryUserRepository
+	roles    *models.MemoryRoleRepository
+	refresh  *models.MemoryRefreshTokenStore
+	logins   *models.MemoryLoginHistory
+	events   *models.MemorySecurityLog
+	mfa      *models.MemoryMFAStore
+	passkeys *models.MemoryPasskeyStore
+	sessions *session.Manager
+	tokens   *auth.TokenManager
+
+	// h serves the requests of the test, built from deps
+	h    *Handlers
+	deps Deps
+
+	invitations   *models.MemoryInvitationStore
+	verifications *models.MemoryEmailVerificationStore
+	resets        *models.MemoryPasswordResetStore
+
+	// mail receives the emails delivered by deliverMail once useMail was
+	// called
+	mail      *mail.Memory
+	mailQueue *mail.Queue
+}
+
+// newTestEnv builds handlers on fresh in-memory stores with the default
+// configuration
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	key, err := auth.GenerateEdDSAKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := &testEnv{
+		users:    models.NewMemoryUserRepository(),
+		refresh:  models.NewMemoryRefreshTokenStore(),
+		logins:   models.NewMemoryLoginHistory(),
+		events:   models.NewMemorySecurityLog(),
+		mfa:      models.NewMemoryMFAStore(),
+		passkeys: models.NewMemoryPasskeyStore(),
+		sessions: session.NewManager(session.NewMemoryStore()),
+		tokens:   auth.NewTokenManager(auth.NewKeySet(key), time.Minute),
+	}
+	env.roles = models.NewMemoryRoleRepository(env.users)
+	env.invitations = models.NewMemoryInvitationStore(env.users)
+	env.verifications = models.NewMemoryEmailVerificationStore(env.users)
+	env.resets = models.NewMemoryPasswordResetStore(env.users)
+	env.tokens.SetSessionBackend(env.sessions)
+
+	env.deps = Deps{
+		Users:              env.users,
+		Roles:              env.roles,
+		RefreshTokens:      env.refresh,
+		LoginHistory:       env.logins,
+		Stats:              models.NewMemoryStatsRepository(env.users, env.logins),
+		SecurityLog:        env.events,
+		MFAStore:           env.mfa,
+		PasskeyStore:       env.passkeys,
+		Invitations:        env.invitations,
+		EmailVerifications: env.verifications,
+		PasswordResets:     env.resets,
+		Searcher:           search.NewMemorySearcher(env.users),
+		Tokens:             env.tokens,
+		Sessions:           env.sessions,
+		Policy: auth.NewPolicy(func() (map[string][]string, error) {
+			return env.roles.Grants(context.Background())
+		}, time.Minute),
+	}
+	env.configure(t, func(*Deps) {})
+	return env
+}
+
+// configure applies fn to the dependencies of env and rebuilds env.h
+func (env *testEnv) configure(t *testing.T, fn func(d *Deps)) {
+	t.Helper()
+	fn(&env.deps)
+	h, err := New(env.deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.h = h
+}
+
+// useMail queues the emails of the handlers for delivery to env.mail
+func (env *testEnv) useMail(t *testing.T) {
+	t.Helper()
+	env.mail = mail.NewMemory()
+	env.mailQueue = mail.NewQueue(env.mail, 0)
+	env.configure(t, func(d *Deps) { d.MailQueue = env.mailQueue })
+}
+
+// deliverMail runs the queued mail jobs and returns every message delivered
+// so far
+func (env *testEnv) deliverMail() []mail.Message {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	env.mailQueue.Run(ctx)
+	return env.mail.Messages()
+}
+
+// linkToken returns the token of the link in msg
+func linkToken(t *testing.T, msg mail.Message) string {
+	t.Helper()
+	m := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(msg.Text)
+	if m == nil {
+		t.Fatalf("no link in %q", msg.Text)
+	}
+	return m[1]
+}
+
+// createUser stores an active user with the given password
+func (env *testEnv) createUser(t *testing.T, username, password string) models.User {
+	t.Helper()
+	user := models.User{Username: username, Email: username + "@example.com", Password: password}
+	if err := env.users.Create(context.Background(), &user); err != nil {
+		t.Fatalf("creating %s: %v", username, err)
+	}
+	return user
+}
+
+// serve calls handler with a request carrying body as JSON and returns the
+// recorded response. Requests can be adjusted with opts, e.g. asPrincipal.
+func serve(t *testing.T, handler http.HandlerFunc, method, path string, body any, opts ...func(*http.Request) *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(method, path, &buf)
+	r.Header.Set("Content-Type", "application/json")
+	for _, opt := range opts {
+		r = opt(r)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// decode decodes the response body into v, failing the test if it has a
+// different status
+func decode(t *testing.T, w *httptest.ResponseRecorder, status int, v any) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body)
+	}
+	if v != nil {
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+	}
+}
+
+// problem decodes a problem response, failing the test if it has a different
+// status
+func problem(t *testing.T, w *httptest.ResponseRecorder, status int) apierror.Problem {
+	t.Helper()
+	var p apierror.Problem
+	decode(t, w, status, &p)
+	return p
+}
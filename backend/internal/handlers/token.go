@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/session"
+)
+
+// Tokens are the credentials issued after a successful authentication
+type Tokens struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// StartSession starts a server-side session for the user, sets the session
+// cookie and issues access and refresh tokens bound to that session
+func (h *Handlers) StartSession(w http.ResponseWriter, r *http.Request, userID string) (auth.Principal, Tokens, error) {
+	roles, err := h.roleRepository.UserRoles(r.Context(), userID)
+	if err != nil {
+		return auth.Principal{}, Tokens{}, err
+	}
+
+	s, err := h.sessionManager.Start(w, r, userID, roles)
+	if err != nil {
+		return auth.Principal{}, Tokens{}, err
+	}
+
+	if err := h.loginHistory.RecordLogin(r.Context(), userID, s.IP, s.UserAgent); err != nil {
+		logger(r).Error("failed to record login", "error", err, "user_id", userID)
+	}
+
+	principal := auth.NewPrincipal(userID, roles)
+	principal.SessionID = s.ID
+	creds, err := h.issueTokens(r.Context(), principal)
+	return principal, creds, err
+}
+
+// issueTokens issues an access token for p and a refresh token in the token
+// family of p's session
+func (h *Handlers) issueTokens(ctx context.Context, p auth.Principal) (Tokens, error) {
+	var creds Tokens
+	var err error
+
+	creds.Token, creds.ExpiresAt, err = h.tokenManager.Issue(p)
+	if err != nil {
+		return creds, err
+	}
+
+	raw, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return creds, err
+	}
+
+	token := models.RefreshToken{
+		UserID:    p.UserID,
+		FamilyID:  p.SessionID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(auth.DefaultRefreshTokenTTL),
+	}
+	if err := h.refreshTokens.Create(ctx, &token); err != nil {
+		return creds, err
+	}
+
+	creds.RefreshToken = raw
+	creds.RefreshExpiresAt = token.ExpiresAt
+	return creds, nil
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a
+// rotated refresh token. Each refresh token can be redeemed once; presenting
+// it a second time ends the session it belongs to.
+func (h *Handlers) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	stored, err := h.refreshTokens.Use(r.Context(), auth.HashOpaqueToken(req.RefreshToken))
+	switch {
+	case errors.Is(err, models.ErrRefreshTokenReused):
+		logger(r).Warn("refresh token reuse detected, revoking session", "user_id", stored.UserID, "session_id", stored.FamilyID)
+		if err := h.sessionManager.Store().Revoke(r.Context(), stored.FamilyID); err != nil && !errors.Is(err, session.ErrNotFound) {
+			logger(r).Error("failed to revoke session", "error", err, "user_id", stored.UserID, "session_id", stored.FamilyID)
+		}
+		apierror.Write(w, r, errInvalidRefreshToken)
+		return
+	case errors.Is(err, models.ErrRefreshTokenInvalid):
+		apierror.Write(w, r, errInvalidRefreshToken)
+		return
+	case err != nil:
+		serverError(w, r, "Database error", "failed to redeem refresh token", err)
+		return
+	}
+
+	// The token family is the session; a logged out session cannot be refreshed
+	s, err := h.sessionManager.Lookup(r.Context(), stored.FamilyID)
+	if err != nil {
+		apierror.Write(w, r, errInvalidRefreshToken)
+		return
+	}
+
+	user, err := h.users.Get(r.Context(), stored.UserID)
+	if errors.Is(err, models.ErrUserNotFound) {
+		apierror.Write(w, r, errInvalidRefreshToken)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to refresh token", "failed to load user", err, "user_id", stored.UserID)
+		return
+	}
+
+	// Sessions of admins who have no passkey although one is required, e.g.
+	// started before the requirement was turned on, are not renewed
+	missing, err := h.missingAdminPasskey(r.Context(), user)
+	if err != nil {
+		serverError(w, r, "Failed to refresh token", "failed to count passkeys", err, "user_id", user.ID)
+		return
+	}
+	if missing {
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodePasskeyRequired, "Sign in again to register a passkey."))
+		return
+	}
+
+	// Pick up role changes made since the session started
+	roles, err := h.roleRepository.UserRoles(r.Context(), user.ID)
+	if err != nil {
+		serverError(w, r, "Database error", "failed to load roles", err, "user_id", user.ID)
+		return
+	}
+
+	principal := auth.NewPrincipal(user.ID, roles)
+	principal.SessionID = s.ID
+	creds, err := h.issueTokens(r.Context(), principal)
+	if err != nil {
+		serverError(w, r, "Failed to issue token", "failed to issue tokens", err, "user_id", user.ID)
+		return
+	}
+
+	writeTokens(w, http.StatusOK, user, principal, creds)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/logging"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// login signs in username and returns the response
+func (env *testEnv) login(t *testing.T, username, password string) AuthResponse {
+	t.Helper()
+	w := serve(t, env.h.Login, http.MethodPost, "/api/login", LoginRequest{Username: username, Password: password})
+	var resp AuthResponse
+	decode(t, w, http.StatusOK, &resp)
+	return resp
+}
+
+// refreshSession redeems a refresh token and returns the response
+func (env *testEnv) refreshSession(t *testing.T, token string) AuthResponse {
+	t.Helper()
+	w := serve(t, env.h.RefreshToken, http.MethodPost, "/api/refresh", RefreshRequest{RefreshToken: token})
+	var resp AuthResponse
+	decode(t, w, http.StatusOK, &resp)
+	return resp
+}
+
+// refreshFails checks that a refresh token is rejected
+func (env *testEnv) refreshFails(t *testing.T, token string) {
+	t.Helper()
+	w := serve(t, env.h.RefreshToken, http.MethodPost, "/api/refresh", RefreshRequest{RefreshToken: token})
+	if p := problem(t, w, http.StatusUnauthorized); p.Code != apierror.CodeInvalidToken {
+		t.Errorf("code = %s, want %s", p.Code, apierror.CodeInvalidToken)
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "alice", "password1")
+	first := env.login(t, "alice", "password1")
+
+	second := env.refreshSession(t, first.RefreshToken)
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+	p, err := env.tokens.Verify(second.Token)
+	if err != nil {
+		t.Fatalf("refreshed access token does not verify: %v", err)
+	}
+	if p.SessionID == "" || p.UserID != first.User.ID {
+		t.Errorf("refreshed principal = %+v", p)
+	}
+
+	third := env.refreshSession(t, second.RefreshToken)
+	if third.RefreshToken == "" {
+		t.Fatal("no refresh token after the second rotation")
+	}
+}
+
+func TestRefreshTokenReuseEndsSession(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "alice", "password1")
+	first := env.login(t, "alice", "password1")
+	second := env.refreshSession(t, first.RefreshToken)
+
+	// Replaying the rotated token revokes the whole family
+	env.refreshFails(t, first.RefreshToken)
+	env.refreshFails(t, second.RefreshToken)
+
+	p, err := env.tokens.Verify(second.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.sessions.CheckSession(context.Background(), p.SessionID); err == nil {
+		t.Error("session is still active after refresh token reuse")
+	}
+}
+
+func TestRefreshTokenUnknown(t *testing.T) {
+	env := newTestEnv(t)
+	env.refreshFails(t, "not-a-token")
+}
+
+func TestRefreshPicksUpRoleChanges(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+	first := env.login(t, "alice", "password1")
+
+	if err := models.SetUserAdmin(context.Background(), env.roles, user.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	second := env.refreshSession(t, first.RefreshToken)
+	if second.User.Role != auth.RoleAdmin || !second.User.IsAdmin {
+		t.Errorf("after promotion role = %q, is_admin = %v", second.User.Role, second.User.IsAdmin)
+	}
+	p, err := env.tokens.Verify(second.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsAdmin() {
+		t.Errorf("refreshed access token roles = %v, want admin", p.Roles)
+	}
+}
+
+func TestRefreshAfterLogout(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "alice", "password1")
+	resp := env.login(t, "alice", "password1")
+	p, err := env.tokens.Verify(resp.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(t, env.h.Logout, http.MethodPost, "/api/logout", nil, func(r *http.Request) *http.Request {
+		return r.WithContext(auth.WithPrincipal(r.Context(), p))
+	})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("logout status = %d: %s", w.Code, w.Body)
+	}
+
+	// A token revoked by the logout is invalid, but it was never used, so
+	// presenting it is not reported as reuse
+	var logs bytes.Buffer
+	w = serve(t, env.h.RefreshToken, http.MethodPost, "/api/refresh", RefreshRequest{RefreshToken: resp.RefreshToken}, func(r *http.Request) *http.Request {
+		return r.WithContext(logging.WithLogger(r.Context(), slog.New(slog.NewTextHandler(&logs, nil))))
+	})
+	wantCode(t, w, http.StatusUnauthorized, apierror.CodeInvalidToken)
+	if strings.Contains(logs.String(), "reuse") {
+		t.Errorf("refresh after logout logged as reuse:\n%s", logs.String())
+	}
+	if _, err := env.refresh.Use(context.Background(), auth.HashOpaqueToken(resp.RefreshToken)); !errors.Is(err, models.ErrRefreshTokenInvalid) {
+		t.Errorf("Use after logout = %v, want ErrRefreshTokenInvalid", err)
+	}
+}
+
+func TestEndUserSessions(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+	a := env.login(t, "alice", "password1")
+	b := env.login(t, "alice", "password1")
+
+	if err := env.h.EndUserSessions(context.Background(), user.ID); err != nil {
+		t.Fatal(err)
+	}
+	env.refreshFails(t, a.RefreshToken)
+	env.refreshFails(t, b.RefreshToken)
+	if sessions, _ := env.sessions.Store().ListByUser(context.Background(), user.ID); len(sessions) != 0 {
+		t.Errorf("%d sessions left after EndUserSessions", len(sessions))
+	}
+}
@@ -1,69 +1,380 @@
 package handlers
 
 import (
-    "encoding/json"
-    "net/http"
-    "github.com/gorilla/mux"
-    "github.com/yourusername/ums/backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/metrics"
+	"github.com/yourusername/ums/backend/internal/models"
 )
 
-// GetUsers retrieves all users
-func GetUsers(w http.ResponseWriter, r *http.Request) {
-    users, err := models.GetAllUsers()
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
-        return
-    }
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(users)
+// UpdateUserRequest is the body of PUT /api/users/{id}. IsAdmin is a pointer
+// so that omitting it leaves the flag unchanged.
+type UpdateUserRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=50,username"`
+	Email    string `json:"email" validate:"required,max=100,email"`
+	IsAdmin  *bool  `json:"is_admin"`
+}
+
+// Normalize prepares the username and email for validation
+func (req *UpdateUserRequest) Normalize() {
+	req.Username = models.NormalizeUsername(req.Username)
+	req.Email = models.NormalizeEmail(req.Email)
+}
+
+// CreateUserRequest is the body of POST /api/users. Omitting the password
+// creates a pending user and returns an invitation instead.
+type CreateUserRequest struct {
+	Username string   `json:"username" validate:"required,min=3,max=50,username"`
+	Email    string   `json:"email" validate:"required,max=100,email"`
+	Password string   `json:"password" validate:"omitempty,min=8,max=128"`
+	Role     string   `json:"role"`
+	Roles    []string `json:"roles"`
+	IsAdmin  bool     `json:"is_admin"`
+}
+
+// Normalize prepares the username and email for validation
+func (req *CreateUserRequest) Normalize() {
+	req.Username = models.NormalizeUsername(req.Username)
+	req.Email = models.NormalizeEmail(req.Email)
+}
+
+// CreateUserResponse is the created user with its roles and, in invitation
+// mode, the invite token
+type CreateUserResponse struct {
+	models.User
+	Roles      []string            `json:"roles"`
+	Invitation *InvitationResponse `json:"invitation,omitempty"`
+}
+
+// CreateUser lets an administrator create a user with a chosen password and
+// roles, or invite one by omitting the password
+func (h *Handlers) CreateUser(w http.ResponseWriter, r *http.Request) {
+	principal, ok := h.policy.Check(w, r, auth.PermUsersCreate, auth.Collection("users"))
+	if !ok {
+		return
+	}
+
+	var req CreateUserRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	invite := req.Password == ""
+
+	roles := req.Roles
+	if req.Role != "" {
+		roles = append(roles, req.Role)
+	}
+	if req.IsAdmin {
+		roles = append(roles, models.AdminRole)
+	}
+	if grantsRoles(roles) {
+		if err := h.policy.Authorize(principal, auth.PermRolesAssign, auth.Collection("users")); err != nil {
+			auth.WriteError(w, r, err)
+			return
+		}
+	}
+
+	user := models.User{
+		Username: req.Username,
+		Password: req.Password, // hashed by User.BeforeSave
+		Email:    req.Email,
+		Status:   models.UserStatusActive,
+	}
+	if invite {
+		user.Status = models.UserStatusPending
+	}
+
+	err := h.users.Create(r.Context(), &user)
+	if errors.Is(err, models.ErrUserExists) {
+		apierror.Write(w, r, userConflict(err))
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to create user", "failed to create user", err)
+		return
+	}
+
+	resp := CreateUserResponse{User: user}
+	if err := h.createUserGrants(r.Context(), &resp, roles, invite, principal.UserID); err != nil {
+		// Do not leave a half-created user behind
+		if err := h.users.Delete(r.Context(), user.ID); err != nil {
+			logger(r).Error("failed to remove user after failed creation", "error", err, "target_user_id", user.ID)
+		}
+		if errors.Is(err, models.ErrRoleNotFound) {
+			apierror.Write(w, r, errUnknownRole)
+			return
+		}
+		serverError(w, r, "Failed to create user", "failed to set up user", err, "target_user_id", user.ID)
+		return
+	}
+
+	if invite {
+		metrics.Registered(metrics.RegisterInvitation)
+	} else {
+		metrics.Registered(metrics.RegisterAdmin)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// grantsRoles reports whether roles contains anything beyond the default role
+func grantsRoles(roles []string) bool {
+	for _, name := range roles {
+		if name != models.UserRole {
+			return true
+		}
+	}
+	return false
+}
+
+// createUserGrants assigns roles to the new user in resp and, when invite is
+// set, issues its invitation
+func (h *Handlers) createUserGrants(ctx context.Context, resp *CreateUserResponse, roles []string, invite bool, createdBy string) error {
+	userID := resp.User.ID
+
+	if len(roles) > 0 {
+		if err := h.roleRepository.SetUserRoles(ctx, userID, roles); err != nil {
+			return err
+		}
+	}
+
+	names, err := h.roleRepository.UserRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	resp.Roles = names
+	for _, name := range names {
+		if name == models.AdminRole {
+			resp.User.IsAdmin = true
+		}
+	}
+
+	if invite {
+		if resp.Invitation, err = h.issueInvitation(ctx, userID, createdBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUsers returns one page of users. See parseUserQuery for the supported
+// query parameters.
+func (h *Handlers) GetUsers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.policy.Check(w, r, auth.PermUsersList, auth.Collection("users")); !ok {
+		return
+	}
+
+	q, err := parseUserQuery(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	page, err := h.users.List(r.Context(), q)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		apierror.Write(w, r, apierror.Field("cursor", apierror.FieldInvalid, "The cursor is invalid."))
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Database error", "failed to list users", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseUserQuery reads the pagination, filter and sort parameters of
+// GET /api/users:
+//
+//	limit, cursor, offset                     page size and position
+//	username, email_domain, is_admin, status  filters
+//	created_after, created_before             RFC 3339 times or YYYY-MM-DD dates
+//	sort, order                               sort field and asc or desc
+func parseUserQuery(v url.Values) (models.UserQuery, error) {
+	q := models.UserQuery{
+		Sort:   v.Get("sort"),
+		Cursor: v.Get("cursor"),
+		Filter: models.UserFilter{
+			UsernamePrefix: v.Get("username"),
+			EmailDomain:    v.Get("email_domain"),
+			Status:         v.Get("status"),
+		},
+	}
+
+	if q.Sort == "" {
+		q.Sort = "id"
+	}
+	if _, ok := models.UserSortFields[q.Sort]; !ok {
+		return q, apierror.Field("sort", apierror.FieldInvalid, "sort must be one of id, username, email, created_at.")
+	}
+
+	switch v.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, apierror.Field("order", apierror.FieldInvalid, "order must be asc or desc.")
+	}
+
+	var err error
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil || q.Limit < 1 || q.Limit > models.MaxUserPageSize {
+			return q, apierror.Field("limit", apierror.FieldInvalid, fmt.Sprintf("limit must be between 1 and %d.", models.MaxUserPageSize))
+		}
+	}
+	if s := v.Get("offset"); s != "" {
+		if q.Offset, err = strconv.Atoi(s); err != nil || q.Offset < 0 {
+			return q, apierror.Field("offset", apierror.FieldInvalid, "offset must be a non-negative integer.")
+		}
+		if q.Cursor != "" {
+			return q, apierror.Field("offset", apierror.FieldInvalid, "cursor and offset cannot be combined.")
+		}
+	}
+
+	if s := v.Get("is_admin"); s != "" {
+		isAdmin, err := strconv.ParseBool(s)
+		if err != nil {
+			return q, apierror.Field("is_admin", apierror.FieldInvalid, "is_admin must be true or false.")
+		}
+		q.Filter.IsAdmin = &isAdmin
+	}
+	switch q.Filter.Status {
+	case "", models.UserStatusActive, models.UserStatusPending:
+	default:
+		return q, apierror.Field("status", apierror.FieldInvalid, "status must be active or pending.")
+	}
+	if q.Filter.CreatedAfter, err = parseTimeParam(v.Get("created_after")); err != nil {
+		return q, apierror.Field("created_after", apierror.FieldInvalid, "created_after must be an RFC 3339 time or a date.")
+	}
+	if q.Filter.CreatedBefore, err = parseTimeParam(v.Get("created_before")); err != nil {
+		return q, apierror.Field("created_before", apierror.FieldInvalid, "created_before must be an RFC 3339 time or a date.")
+	}
+
+	return q, nil
+}
+
+// parseTimeParam parses an RFC 3339 time or a YYYY-MM-DD date. An empty
+// string yields the zero time.
+func parseTimeParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
 }
 
 // GetUser retrieves user information by ID
-func GetUser(w http.ResponseWriter, r *http.Request) {
-    vars := mux.Vars(r)
-    userID := vars["id"]
-
-    user, err := models.GetUserByID(userID)
-    if err != nil {
-        http.Error(w, "User not found", http.StatusNotFound)
-        return
-    }
-
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(user)
+func (h *Handlers) GetUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	if _, ok := h.policy.Check(w, r, auth.PermUsersRead, auth.UserResource(userID)); !ok {
+		return
+	}
+
+	user, ok := h.loadUser(w, r, userID)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
 }
 
-// UpdateUser updates user information
-func UpdateUser(w http.ResponseWriter, r *http.Request) {
-    vars := mux.Vars(r)
-    userID := vars["id"]
-
-    var user models.User
-    if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-        http.Error(w, "Invalid input", http.StatusBadRequest)
-        return
-    }
-
-    user.ID = userID // Ensure the ID matches the URL parameter
-    updatedUser, err := models.UpdateUser(user)
-    if err != nil {
-        http.Error(w, "Failed to update user", http.StatusInternalServerError)
-        return
-    }
-
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(updatedUser)
+// UpdateUser updates user information. Changing is_admin grants or revokes
+// the admin role and requires the roles:assign permission.
+func (h *Handlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	principal, ok := h.policy.Check(w, r, auth.PermUsersUpdate, auth.UserResource(userID))
+	if !ok {
+		return
+	}
+
+	var req UpdateUserRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, ok := h.loadUser(w, r, userID)
+	if !ok {
+		return
+	}
+
+	roleChanged := req.IsAdmin != nil && *req.IsAdmin != user.IsAdmin
+	if roleChanged {
+		if err := h.policy.Authorize(principal, auth.PermRolesAssign, auth.UserResource(userID)); err != nil {
+			auth.WriteError(w, r, err)
+			return
+		}
+	}
+	user.Username = req.Username
+	user.Email = req.Email
+
+	updatedUser, err := h.users.Update(r.Context(), user)
+	if errors.Is(err, models.ErrUserExists) {
+		apierror.Write(w, r, userConflict(err))
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to update user", "failed to update user", err, "target_user_id", userID)
+		return
+	}
+
+	if roleChanged {
+		if err := models.SetUserAdmin(r.Context(), h.roleRepository, userID, *req.IsAdmin); err != nil {
+			serverError(w, r, "Failed to update user", "failed to change admin role", err, "target_user_id", userID)
+			return
+		}
+		updatedUser.IsAdmin = *req.IsAdmin
+	}
+
+	// Sessions carry the role they were started with, so end them to make
+	// the new role take effect
+	if roleChanged {
+		if err := h.EndUserSessions(r.Context(), userID); err != nil {
+			logger(r).Error("failed to end sessions after role change", "error", err, "target_user_id", userID)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedUser)
 }
 
 // DeleteUser deletes a user by ID
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
-    vars := mux.Vars(r)
-    userID := vars["id"]
+func (h *Handlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	if _, ok := h.policy.Check(w, r, auth.PermUsersDelete, auth.UserResource(userID)); !ok {
+		return
+	}
 
-    if err := models.DeleteUser(userID); err != nil {
-        http.Error(w, "Failed to delete user", http.StatusInternalServerError)
-        return
-    }
+	err := h.users.Delete(r.Context(), userID)
+	if errors.Is(err, models.ErrUserNotFound) {
+		apierror.Write(w, r, errUserNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to delete user", "failed to delete user", err, "target_user_id", userID)
+		return
+	}
 
-    w.WriteHeader(http.StatusNoContent)
-}
\ No newline at end of file
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// brokenUsers is a repository whose lookups fail like an unreachable database
+type brokenUsers struct {
+	*models.MemoryUserRepository
+}
+
+func (brokenUsers) Get(ctx context.Context, id string) (models.User, error) {
+	return models.User{}, errors.New("connection refused")
+}
+
+// asPrincipal returns r carrying the principal of userID holding roles
+func asPrincipal(r *http.Request, userID string, roles ...string) *http.Request {
+	return r.WithContext(auth.WithPrincipal(r.Context(), auth.NewPrincipal(userID, roles)))
+}
+
+func TestGetUserStatus(t *testing.T) {
+	env := newTestEnv(t)
+	repo := env.users
+	user := env.createUser(t, "alice", "password1")
+
+	tests := []struct {
+		name string
+		repo models.UserRepository
+		id   string
+		want int
+	}{
+		{"found", repo, user.ID, http.StatusOK},
+		{"missing", repo, "999", http.StatusNotFound},
+		{"repository error", brokenUsers{repo}, user.ID, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.configure(t, func(d *Deps) { d.Users = tt.repo })
+			r := httptest.NewRequest(http.MethodGet, "/api/users/"+tt.id, nil)
+			r = mux.SetURLVars(asPrincipal(r, "1", auth.RoleAdmin), map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+
+			env.h.GetUser(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusOK {
+				var got models.User
+				if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatal(err)
+				}
+				if got.Username != "alice" || got.Password != "" {
+					t.Errorf("GetUser returned %+v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteUserNotFound(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "password1")
+	deleteUser := func(id string) *httptest.ResponseRecorder {
+		return serve(t, env.h.DeleteUser, http.MethodDelete, "/api/users/"+id, nil, asAdmin, withVars(map[string]string{"id": id}))
+	}
+
+	if w := deleteUser(user.ID); w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204: %s", w.Code, w.Body)
+	}
+	// The deleted user, unknown and non-numeric IDs are not found
+	for _, id := range []string{user.ID, "999", "abc"} {
+		wantCode(t, deleteUser(id), http.StatusNotFound, apierror.CodeNotFound)
+	}
+}
+
+func TestGetProfileRepositoryError(t *testing.T) {
+	env := newTestEnv(t)
+	env.configure(t, func(d *Deps) { d.Users = brokenUsers{env.users} })
+	r := asPrincipal(httptest.NewRequest(http.MethodGet, "/api/profile", nil), "1", auth.RoleUser)
+	w := httptest.NewRecorder()
+
+	env.h.GetProfile(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500: %s", w.Code, w.Body)
+	}
+}
+
+func TestUserRoutesAuthorization(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice", "password1")
+	bob := env.createUser(t, "bob", "password1")
+	admin := env.createUser(t, "admin", "password1")
+	env.makeAdmin(t, admin)
+
+	as := func(user models.User, roles ...string) func(*http.Request) *http.Request {
+		return func(r *http.Request) *http.Request { return asPrincipal(r, user.ID, roles...) }
+	}
+	anonymous := func(r *http.Request) *http.Request { return r }
+	id := func(user models.User) func(*http.Request) *http.Request {
+		return withVars(map[string]string{"id": user.ID})
+	}
+	type opts = []func(*http.Request) *http.Request
+	isAdmin := true
+	update := func(user models.User, admin *bool) UpdateUserRequest {
+		return UpdateUserRequest{Username: user.Username, Email: user.Email, IsAdmin: admin}
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		body    any
+		opts    opts
+		want    int
+	}{
+		{"user reads self", env.h.GetUser, http.MethodGet, nil, opts{as(alice, auth.RoleUser), id(alice)}, http.StatusOK},
+		{"user reads other", env.h.GetUser, http.MethodGet, nil, opts{as(alice, auth.RoleUser), id(bob)}, http.StatusForbidden},
+		{"user updates self", env.h.UpdateUser, http.MethodPut, update(alice, nil), opts{as(alice, auth.RoleUser), id(alice)}, http.StatusOK},
+		{"user makes self admin", env.h.UpdateUser, http.MethodPut, update(alice, &isAdmin), opts{as(alice, auth.RoleUser), id(alice)}, http.StatusForbidden},
+		{"user updates other", env.h.UpdateUser, http.MethodPut, update(bob, nil), opts{as(alice, auth.RoleUser), id(bob)}, http.StatusForbidden},
+		{"user deletes other", env.h.DeleteUser, http.MethodDelete, nil, opts{as(alice, auth.RoleUser), id(bob)}, http.StatusForbidden},
+		{"user lists users", env.h.GetUsers, http.MethodGet, nil, opts{as(alice, auth.RoleUser)}, http.StatusForbidden},
+		{"anonymous reads", env.h.GetUser, http.MethodGet, nil, opts{anonymous, id(alice)}, http.StatusUnauthorized},
+		{"anonymous updates", env.h.UpdateUser, http.MethodPut, update(alice, nil), opts{anonymous, id(alice)}, http.StatusUnauthorized},
+		{"anonymous deletes", env.h.DeleteUser, http.MethodDelete, nil, opts{anonymous, id(alice)}, http.StatusUnauthorized},
+		{"anonymous lists", env.h.GetUsers, http.MethodGet, nil, opts{anonymous}, http.StatusUnauthorized},
+		{"admin reads other", env.h.GetUser, http.MethodGet, nil, opts{as(admin, auth.RoleAdmin), id(alice)}, http.StatusOK},
+		{"admin lists users", env.h.GetUsers, http.MethodGet, nil, opts{as(admin, auth.RoleAdmin)}, http.StatusOK},
+		{"admin makes other admin", env.h.UpdateUser, http.MethodPut, update(alice, &isAdmin), opts{as(admin, auth.RoleAdmin), id(alice)}, http.StatusOK},
+		{"admin deletes other", env.h.DeleteUser, http.MethodDelete, nil, opts{as(admin, auth.RoleAdmin), id(bob)}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, tt.handler, tt.method, "/api/users", tt.body, tt.opts...)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+
+	// The requests of the administrator took effect
+	ctx := context.Background()
+	if stored, err := env.users.Get(ctx, alice.ID); err != nil || !stored.IsAdmin {
+		t.Errorf("alice = %+v, %v; want made admin by the administrator only", stored, err)
+	}
+	if _, err := env.users.Get(ctx, bob.ID); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("bob not deleted by the administrator: %v", err)
+	}
+}
+
+func TestGetUsersPages(t *testing.T) {
+	env := newTestEnv(t)
+	for _, name := range []string{"dave", "alice", "carol", "bob", "erin"} {
+		env.createUser(t, name, "password1")
+	}
+
+	list := func(query string) models.UserPage {
+		t.Helper()
+		var page models.UserPage
+		decode(t, serve(t, env.h.GetUsers, http.MethodGet, "/api/users?"+query, nil, asAdmin), http.StatusOK, &page)
+		return page
+	}
+
+	var got []string
+	page := list("sort=username&order=desc&limit=2")
+	for {
+		if page.Total != 5 {
+			t.Fatalf("total = %d, want 5", page.Total)
+		}
+		for _, u := range page.Users {
+			got = append(got, u.Username)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		page = list("sort=username&order=desc&limit=2&cursor=" + page.NextCursor)
+	}
+	if want := "erin,dave,carol,bob,alice"; strings.Join(got, ",") != want {
+		t.Errorf("users = %v, want %s", got, want)
+	}
+
+	page = list("username=CA&limit=1")
+	if page.Total != 1 || len(page.Users) != 1 || page.Users[0].Username != "carol" {
+		t.Errorf("username filter returned %+v", page)
+	}
+	if page = list("status=pending"); page.Total != 0 {
+		t.Errorf("status filter returned %+v", page)
+	}
+}
+
+func TestGetUsersInvalidQuery(t *testing.T) {
+	env := newTestEnv(t)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		env.createUser(t, name, "password1")
+	}
+
+	var first models.UserPage
+	decode(t, serve(t, env.h.GetUsers, http.MethodGet, "/api/users?sort=username&limit=1", nil, asAdmin), http.StatusOK, &first)
+	if first.NextCursor == "" {
+		t.Fatal("no next_cursor on the first page")
+	}
+
+	tests := []struct {
+		query string
+		field string
+	}{
+		{"limit=0", "limit"},
+		{"limit=201", "limit"},
+		{"limit=ten", "limit"},
+		{"offset=-1", "offset"},
+		{"offset=1&cursor=" + first.NextCursor, "offset"},
+		{"cursor=garbage", "cursor"},
+		{"sort=email&cursor=" + first.NextCursor, "cursor"},
+		{"sort=username&order=desc&cursor=" + first.NextCursor, "cursor"},
+		{"sort=password", "sort"},
+		{"order=up", "order"},
+		{"is_admin=maybe", "is_admin"},
+		{"status=banned", "status"},
+		{"created_after=yesterday", "created_after"},
+	}
+	for _, tt := range tests {
+		w := serve(t, env.h.GetUsers, http.MethodGet, "/api/users?"+tt.query, nil, asAdmin)
+		p := problem(t, w, http.StatusBadRequest)
+		if len(p.Errors) != 1 || p.Errors[0].Field != tt.field {
+			t.Errorf("%s: errors = %+v, want one for %s", tt.query, p.Errors, tt.field)
+		}
+	}
+}
+
+// failingRoles is a role repository whose assignments fail like an
+// unreachable database
+type failingRoles struct {
+	*models.MemoryRoleRepository
+}
+
+func (failingRoles) SetUserRoles(ctx context.Context, userID string, names []string) error {
+	return errors.New("connection refused")
+}
+
+func TestCreateUser(t *testing.T) {
+	env := newTestEnv(t)
+
+	req := CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "password1", Roles: []string{auth.RoleAdmin}}
+	w := serve(t, env.h.CreateUser, http.MethodPost, "/api/users", req, asAdmin)
+	var resp CreateUserResponse
+	decode(t, w, http.StatusCreated, &resp)
+	if resp.Status != models.UserStatusActive || resp.Invitation != nil || !resp.IsAdmin || resp.Password != "" {
+		t.Errorf("CreateUser with password returned %+v", resp)
+	}
+	env.login(t, "alice", "password1")
+
+	invited := env.invite(t, "bob")
+	if invited.Invitation.Token == "" || len(invited.Roles) != 1 || invited.Roles[0] != auth.RoleUser {
+		t.Errorf("invitation = %+v, roles = %v", invited.Invitation, invited.Roles)
+	}
+	w = serve(t, env.h.Login, http.MethodPost, "/api/login", LoginRequest{Username: "bob", Password: "password1"})
+	problem(t, w, http.StatusUnauthorized)
+}
+
+func TestCreateUserRolesForbidden(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	support := models.Role{Name: "support", Permissions: []string{string(auth.PermUsersCreate)}}
+	if err := env.roles.Create(ctx, &support); err != nil {
+		t.Fatal(err)
+	}
+	as := func(r *http.Request) *http.Request { return asPrincipal(r, "1", "support") }
+
+	for _, req := range []CreateUserRequest{
+		{Username: "alice", Email: "alice@example.com", Roles: []string{"support"}},
+		{Username: "alice", Email: "alice@example.com", Role: auth.RoleAdmin},
+		{Username: "alice", Email: "alice@example.com", IsAdmin: true},
+	} {
+		w := serve(t, env.h.CreateUser, http.MethodPost, "/api/users", req, as)
+		problem(t, w, http.StatusForbidden)
+	}
+	if page, err := env.users.List(ctx, models.UserQuery{}); err != nil || page.Total != 0 {
+		t.Fatalf("users = %+v, %v; want none created", page, err)
+	}
+
+	// Creating plain users needs no role permission
+	req := CreateUserRequest{Username: "alice", Email: "alice@example.com", Roles: []string{auth.RoleUser}}
+	decode(t, serve(t, env.h.CreateUser, http.MethodPost, "/api/users", req, as), http.StatusCreated, nil)
+}
+
+func TestCreateUserRollback(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	req := CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "password1", Roles: []string{"nope"}}
+
+	w := serve(t, env.h.CreateUser, http.MethodPost, "/api/users", req, asAdmin)
+	if p := problem(t, w, http.StatusBadRequest); len(p.Errors) != 1 || p.Errors[0].Field != "roles" {
+		t.Errorf("errors = %+v, want one for roles", p.Errors)
+	}
+
+	env.configure(t, func(d *Deps) { d.Roles = failingRoles{env.roles} })
+	req.Roles = []string{auth.RoleAdmin}
+	w = serve(t, env.h.CreateUser, http.MethodPost, "/api/users", req, asAdmin)
+	problem(t, w, http.StatusInternalServerError)
+
+	if page, err := env.users.List(ctx, models.UserQuery{}); err != nil || page.Total != 0 {
+		t.Fatalf("users = %+v, %v; want the failed creations removed", page, err)
+	}
+}
+
+func TestCreateUserTaken(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "alice", "password1")
+
+	tests := []struct {
+		req  CreateUserRequest
+		code apierror.Code
+	}{
+		{CreateUserRequest{Username: "Alice", Email: "alice2@example.com", Password: "password1"}, apierror.CodeUsernameTaken},
+		{CreateUserRequest{Username: "alice2", Email: "ALICE@example.com", Password: "password1"}, apierror.CodeEmailTaken},
+		{CreateUserRequest{Username: "alice", Email: "alice3@example.com"}, apierror.CodeUsernameTaken},
+	}
+	for _, tt := range tests {
+		w := serve(t, env.h.CreateUser, http.MethodPost, "/api/users", tt.req, asAdmin)
+		wantCode(t, w, http.StatusConflict, tt.code)
+	}
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	umsdb "github.com/yourusername/ums/backend/internal/db"
+)
+
+// Database checks that db answers a ping
+func Database(db *sql.DB) Checker {
+	return CheckerFunc("database", db.PingContext)
+}
+
+// Migrations checks that every migration known to m has been applied and
+// none was modified afterwards. Migrations applied by a newer build are
+// tolerated so older instances stay ready during a rolling deploy. The check
+// only reads the schema, so probes never change a database that has not been
+// migrated yet.
+func Migrations(m *umsdb.Migrator) Checker {
+	return CheckerFunc("migrations", func(ctx context.Context) error {
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		var pending, modified []string
+		for _, s := range statuses {
+			name := fmt.Sprintf("%04d_%s", s.Version, s.Name)
+			switch s.State {
+			case umsdb.MigrationPending:
+				pending = append(pending, name)
+			case umsdb.MigrationModified:
+				modified = append(modified, name)
+			}
+		}
+		if len(pending) > 0 && len(pending) == len(statuses) {
+			return errors.New("not migrated")
+		}
+		if len(modified) > 0 {
+			return fmt.Errorf("modified after being applied: %s", strings.Join(modified, ", "))
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("pending: %s", strings.Join(pending, ", "))
+		}
+		return nil
+	})
+}
+
+// Pool checks that less than maxUsage of db's connection limit is in use.
+// It always passes when the pool is unlimited.
+func Pool(db *sql.DB, maxUsage float64) Checker {
+	return CheckerFunc("database_pool", func(ctx context.Context) error {
+		stats := db.Stats()
+		if stats.MaxOpenConnections == 0 {
+			return nil
+		}
+		usage := float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		if usage >= maxUsage {
+			return fmt.Errorf("%d of %d connections in use", stats.InUse, stats.MaxOpenConnections)
+		}
+		return nil
+	})
+}
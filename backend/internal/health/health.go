@@ -0,0 +1,161 @@
+// Package health implements the liveness and readiness endpoints.
+//
+// /healthz only reports that the process is serving requests. /readyz runs
+// every registered Checker concurrently, each with its own timeout, and
+// answers 503 if any of them fails. Probes are unauthenticated, so the
+// response only names the checks and their status; failures are logged.
+// Dependencies such as a mail server or a cache add themselves with
+// Registry.Register.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/logging"
+)
+
+// Check statuses
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Checker is a dependency the service needs to be ready
+type Checker interface {
+	// Name identifies the check in the readiness report
+	Name() string
+	// Check returns an error if the dependency is unusable. It should
+	// return promptly once ctx is done.
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc returns a Checker named name that runs fn
+func CheckerFunc(name string, fn func(ctx context.Context) error) Checker {
+	return funcChecker{name: name, fn: fn}
+}
+
+type funcChecker struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c funcChecker) Name() string                    { return c.name }
+func (c funcChecker) Check(ctx context.Context) error { return c.fn(ctx) }
+
+// Result is the outcome of one check. Only the status is sent to clients.
+type Result struct {
+	Status   string        `json:"status"`
+	Err      error         `json:"-"`
+	Duration time.Duration `json:"-"`
+}
+
+// Report is the readiness response body
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Registry holds the readiness checks. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	checkers []Checker
+	timeout  time.Duration
+}
+
+// NewRegistry returns an empty registry that gives each check timeout to finish
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register adds a check. Checks are reported under their names, so names
+// should be unique.
+func (r *Registry) Register(c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers = append(r.checkers, c)
+}
+
+// Check runs every check concurrently and reports StatusFail if any fails
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checkers := append([]Checker(nil), r.checkers...)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checkers))
+	var wg sync.WaitGroup
+	for i, c := range checkers {
+		wg.Add(1)
+		go func(i int, c Checker) {
+			defer wg.Done()
+			results[i] = r.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checkers))}
+	for i, c := range checkers {
+		report.Checks[c.Name()] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() { errc <- c.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		// The checker ignored its context; report it without waiting
+		err = ctx.Err()
+	}
+
+	res := Result{Status: StatusOK, Err: err, Duration: time.Since(start)}
+	if err != nil {
+		res.Status = StatusFail
+	}
+	return res
+}
+
+// ServeHTTP answers readiness probes with the report, with status 503 if a
+// check failed. The errors of failed checks are logged rather than sent.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	report := r.Check(req.Context())
+
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+		logger := logging.FromContext(req.Context())
+		for name, res := range report.Checks {
+			if res.Err != nil {
+				logger.Warn("readiness check failed", "check", name, "error", res.Err, "duration_ms", res.Duration.Milliseconds())
+			}
+		}
+	}
+	writeJSON(w, status, report)
+}
+
+// Live answers liveness probes. It never touches dependencies, so a slow
+// database does not get a healthy process restarted.
+func Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,153 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/logging"
+)
+
+func pass(name string) Checker {
+	return CheckerFunc(name, func(ctx context.Context) error { return nil })
+}
+
+func fail(name string, err error) Checker {
+	return CheckerFunc(name, func(ctx context.Context) error { return err })
+}
+
+func TestRegistryCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		checkers []Checker
+		want     string
+	}{
+		{"no checks", nil, StatusOK},
+		{"all pass", []Checker{pass("a"), pass("b")}, StatusOK},
+		{"one fails", []Checker{pass("a"), fail("b", errors.New("down")), pass("c")}, StatusFail},
+		{"all fail", []Checker{fail("a", errors.New("down")), fail("b", errors.New("down"))}, StatusFail},
+	}
+	for _, tt := range tests {
+		r := NewRegistry(time.Second)
+		for _, c := range tt.checkers {
+			r.Register(c)
+		}
+
+		report := r.Check(context.Background())
+		if report.Status != tt.want {
+			t.Errorf("%s: status = %s, want %s", tt.name, report.Status, tt.want)
+		}
+		if len(report.Checks) != len(tt.checkers) {
+			t.Errorf("%s: %d results for %d checks", tt.name, len(report.Checks), len(tt.checkers))
+		}
+		for _, c := range tt.checkers {
+			res := report.Checks[c.Name()]
+			if wantErr := c.Check(context.Background()); !errors.Is(res.Err, wantErr) || (wantErr == nil) != (res.Status == StatusOK) {
+				t.Errorf("%s: %s = %+v, want error %v", tt.name, c.Name(), res, wantErr)
+			}
+		}
+	}
+}
+
+func TestRegistryCheckTimeout(t *testing.T) {
+	r := NewRegistry(50 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	// One check honours its context, the other ignores it
+	r.Register(CheckerFunc("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	r.Register(CheckerFunc("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	}))
+	r.Register(pass("fast"))
+
+	start := time.Now()
+	report := r.Check(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Check took %s with a 50ms timeout", elapsed)
+	}
+
+	if report.Status != StatusFail {
+		t.Errorf("status = %s, want %s", report.Status, StatusFail)
+	}
+	for _, name := range []string{"slow", "stuck"} {
+		if res := report.Checks[name]; res.Status != StatusFail || !errors.Is(res.Err, context.DeadlineExceeded) {
+			t.Errorf("%s = %+v, want failed with a deadline error", name, res)
+		}
+	}
+	if res := report.Checks["fast"]; res.Status != StatusOK {
+		t.Errorf("fast = %+v, want ok despite the slow checks", res)
+	}
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	secret := errors.New("dial tcp 10.0.0.5:5432: connection refused")
+	tests := []struct {
+		name     string
+		checkers []Checker
+		want     int
+	}{
+		{"ready", []Checker{pass("database"), pass("migrations")}, http.StatusOK},
+		{"not ready", []Checker{pass("database"), fail("migrations", secret)}, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		r := NewRegistry(time.Second)
+		for _, c := range tt.checkers {
+			r.Register(c)
+		}
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logs, nil))
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		req = req.WithContext(logging.WithLogger(req.Context(), logger))
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "no-store" {
+			t.Errorf("%s: Cache-Control = %q", tt.name, got)
+		}
+
+		// The body names the checks and their status and nothing else
+		var body struct {
+			Status string                       `json:"status"`
+			Checks map[string]map[string]string `json:"checks"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v: %s", tt.name, err, w.Body)
+		}
+		if len(body.Checks) != len(tt.checkers) {
+			t.Errorf("%s: checks = %v", tt.name, body.Checks)
+		}
+		for name, check := range body.Checks {
+			if len(check) != 1 || check["status"] == "" {
+				t.Errorf("%s: %s reported as %v, want only its status", tt.name, name, check)
+			}
+		}
+
+		if tt.want == http.StatusOK {
+			if logs.Len() != 0 {
+				t.Errorf("%s: logged %s", tt.name, logs.String())
+			}
+			continue
+		}
+		if strings.Contains(w.Body.String(), "10.0.0.5") {
+			t.Errorf("%s: body leaks the error: %s", tt.name, w.Body)
+		}
+		if !strings.Contains(logs.String(), `"check":"migrations"`) || !strings.Contains(logs.String(), "10.0.0.5") {
+			t.Errorf("%s: failure not logged: %s", tt.name, logs.String())
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package logging sets up structured logging with log/slog and tags the log
+// lines of a request with its request ID.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// Formats accepted by New
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// redacted replaces the value of attributes that may hold credentials
+const redacted = "[REDACTED]"
+
+// secretKeys are attribute keys whose values are never written, as a safety
+// net against a password or token being passed to a logger by mistake
+var secretKeys = map[string]bool{
+	"password":      true,
+	"new_password":  true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"authorization": true,
+	"cookie":        true,
+	"secret":        true,
+}
+
+// New returns a logger writing to w. level is debug, info, warn or error;
+// format is json or text.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("logging: invalid level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("logging: invalid format %q", format)
+}
+
+// Setup makes logger the default for slog and routes the standard log
+// package through it, so every line ends up in the same format
+func Setup(logger *slog.Logger) {
+	slog.SetDefault(logger)
+	log.SetFlags(0)
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if secretKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger of the request ctx belongs to, which adds
+// the request ID to every line, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
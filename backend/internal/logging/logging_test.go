@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewRedactsSecrets(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatText} {
+		var buf bytes.Buffer
+		logger, err := New(&buf, "info", format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		logger.Info("login",
+			"username", "alice",
+			"password", "hunter2-password",
+			"Refresh_Token", "rt-secret-value",
+			slog.Group("request", "authorization", "Bearer jwt-secret-value", "path", "/api/login"),
+		)
+
+		out := buf.String()
+		for _, secret := range []string{"hunter2-password", "rt-secret-value", "jwt-secret-value"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("%s: %q logged: %s", format, secret, out)
+			}
+		}
+		for _, kept := range []string{"alice", "/api/login", redacted} {
+			if !strings.Contains(out, kept) {
+				t.Errorf("%s: %q missing: %s", format, kept, out)
+			}
+		}
+	}
+}
+
+func TestNewRedactsEveryKey(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "debug", FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key := range secretKeys {
+		buf.Reset()
+		logger.Debug("event", key, "s3cret")
+		var line map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		if line[key] != redacted {
+			t.Errorf("%s logged as %v", key, line[key])
+		}
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "loud", FormatJSON); err == nil {
+		t.Error("invalid level accepted")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("invalid format accepted")
+	}
+
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("dropped")
+	if buf.Len() != 0 {
+		t.Errorf("info line written at warn level: %s", buf.String())
+	}
+}
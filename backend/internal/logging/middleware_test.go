@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"3f9c2a7e-1b4d-4c1e-9a0e-6b8f2d1c5e7a", true},
+		{"req_123.abc:def", true},
+		{strings.Repeat("a", maxRequestIDLength), true},
+		{"", false},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+		{"abc def", false},
+		{"abc\ndef", false},
+		{"abc\r\nX-Injected: 1", false},
+		{"abc\tdef", false},
+		{"abc\x00", false},
+		{"abc\x7f", false},
+		{"ümlaut", false},
+	}
+	for _, tt := range tests {
+		if got := validRequestID(tt.id); got != tt.want {
+			t.Errorf("validRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+// captureDefault makes slog write JSON lines to the returned buffer for the
+// rest of the test
+func captureDefault(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: redact})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	logs := captureDefault(t)
+	var seen string
+	h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+		FromContext(r.Context()).Info("handled", "token", "abc-secret")
+	}))
+
+	tests := []struct {
+		header string
+		keep   bool
+	}{
+		{"client-id-1", true},
+		{"", false},
+		{strings.Repeat("x", maxRequestIDLength+1), false},
+		{"bad\nid", false},
+	}
+	for _, tt := range tests {
+		logs.Reset()
+		r := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+		if tt.header != "" {
+			r.Header.Set(RequestIDHeader, tt.header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		got := w.Header().Get(RequestIDHeader)
+		if got != seen || got == "" {
+			t.Errorf("%q: response ID %q, context ID %q", tt.header, got, seen)
+		}
+		if (got == tt.header) != tt.keep {
+			t.Errorf("%q: response ID %q, want the header kept: %v", tt.header, got, tt.keep)
+		}
+		if !validRequestID(got) {
+			t.Errorf("%q: invalid ID %q issued", tt.header, got)
+		}
+
+		// Both the handler's line and the access log carry the ID
+		lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("%q: logged %d lines, want 2: %s", tt.header, len(lines), logs)
+		}
+		for _, l := range lines {
+			var line map[string]any
+			if err := json.Unmarshal([]byte(l), &line); err != nil {
+				t.Fatal(err)
+			}
+			if line["request_id"] != got {
+				t.Errorf("%q: line %s lacks request_id %q", tt.header, l, got)
+			}
+		}
+		if strings.Contains(logs.String(), "abc-secret") {
+			t.Errorf("token logged: %s", logs)
+		}
+	}
+}
+
+func TestMiddlewareQuietPaths(t *testing.T) {
+	logs := captureDefault(t)
+	status := http.StatusOK
+	h := Middleware("/healthz")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	tests := []struct {
+		path   string
+		status int
+		level  string
+	}{
+		{"/healthz", http.StatusOK, "DEBUG"},
+		{"/healthz", http.StatusInternalServerError, "ERROR"},
+		{"/api/users", http.StatusOK, "INFO"},
+		{"/api/users", http.StatusBadGateway, "ERROR"},
+	}
+	for _, tt := range tests {
+		logs.Reset()
+		status = tt.status
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		var line map[string]any
+		if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		if line["level"] != tt.level || line["status"] != float64(tt.status) || line["path"] != tt.path {
+			t.Errorf("%s %d: logged %v", tt.path, tt.status, line)
+		}
+	}
+}
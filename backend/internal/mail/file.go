@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// File writes every message to Dir as an .eml file instead of sending it,
+// for development without a mail server
+type File struct {
+	Dir  string
+	From string
+
+	once sync.Once
+	err  error
+}
+
+// Send writes msg to a new file in Dir
+func (m *File) Send(ctx context.Context, msg Message) error {
+	data, err := encode(msg, m.From)
+	if err != nil {
+		return err
+	}
+	if err := m.mkdir(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), randomHex(4))
+	return os.WriteFile(filepath.Join(m.Dir, name), data, 0o600)
+}
+
+func (m *File) mkdir() error {
+	m.once.Do(func() {
+		m.err = os.MkdirAll(m.Dir, 0o700)
+	})
+	return m.err
+}
@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSend(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := &File{Dir: dir, From: "UMS <no-reply@example.com>"}
+
+	for _, to := range []string{"alice@example.com", "bob@example.com"} {
+		if err := m.Send(context.Background(), Message{To: to, Subject: "Hello", Text: "Hi " + to}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d files written, want one per message", len(entries))
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".eml") {
+			t.Errorf("file %s is not an .eml file", e.Name())
+		}
+		info, err := e.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("%s has mode %o, want 600", e.Name(), perm)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "From: UMS <no-reply@example.com>\r\n") || !strings.Contains(string(data), "Subject: Hello\r\n") {
+			t.Errorf("%s:\n%s", e.Name(), data)
+		}
+	}
+}
+
+func TestFileSendErrors(t *testing.T) {
+	dir := t.TempDir()
+	m := &File{Dir: dir, From: "no-reply@example.com"}
+	if err := m.Send(context.Background(), Message{To: "not an address"}); err == nil {
+		t.Error("invalid recipient accepted")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d files written for an invalid message", len(entries))
+	}
+
+	// Dir cannot be created below a regular file
+	blocker := filepath.Join(dir, "file")
+	if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m = &File{Dir: filepath.Join(blocker, "mail"), From: "no-reply@example.com"}
+	if err := m.Send(context.Background(), Message{To: "alice@example.com"}); err == nil {
+		t.Error("Send into an unusable directory succeeded")
+	}
+}
@@ -0,0 +1,129 @@
+// Package mail sends the emails of the UMS, such as verification links.
+//
+// Mailer has three implementations: SMTP delivers to a mail server, which
+// can be a local MailHog-style stand-in; File drops every message into a
+// directory as an .eml file; Memory keeps messages in memory.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Drivers accepted by New
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer sends messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Options configure the mailer returned by New
+type Options struct {
+	// Driver is smtp, file or memory
+	Driver string
+	// From is the sender, e.g. "UMS <no-reply@example.com>"
+	From string
+	// SMTPAddr is the host:port of the SMTP server
+	SMTPAddr string
+	// SMTPUsername and SMTPPassword enable PLAIN authentication when set
+	SMTPUsername string
+	SMTPPassword string
+	// Dir is where the file driver writes messages
+	Dir string
+}
+
+// New returns the mailer selected by opts.Driver
+func New(opts Options) (Mailer, error) {
+	if _, err := mail.ParseAddress(opts.From); err != nil {
+		return nil, fmt.Errorf("mail: invalid sender %q: %w", opts.From, err)
+	}
+
+	switch opts.Driver {
+	case DriverSMTP:
+		return &SMTP{Addr: opts.SMTPAddr, From: opts.From, Username: opts.SMTPUsername, Password: opts.SMTPPassword}, nil
+	case DriverFile:
+		return &File{Dir: opts.Dir, From: opts.From}, nil
+	case DriverMemory:
+		return NewMemory(), nil
+	}
+	return nil, fmt.Errorf("mail: unknown driver %q", opts.Driver)
+}
+
+// encode renders msg as an RFC 5322 message from sender
+func encode(msg Message, from string) ([]byte, error) {
+	if strings.ContainsAny(from, "\r\n") {
+		return nil, errors.New("mail: line break in header")
+	}
+	if err := msg.check(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", from)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(from))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(msg.Text))
+	qp.Close()
+	return buf.Bytes(), nil
+}
+
+// check rejects messages that cannot be sent: an invalid recipient or a
+// line break in a header value, which could inject headers
+func (msg Message) check() error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return errors.New("mail: line break in header")
+	}
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return fmt.Errorf("mail: invalid recipient %q: %w", msg.To, err)
+	}
+	return nil
+}
+
+// messageID returns a unique Message-ID in the domain of from
+func messageID(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if at := strings.LastIndexByte(addr.Address, '@'); at >= 0 {
+			domain = addr.Address[at+1:]
+		}
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), randomHex(8), domain)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
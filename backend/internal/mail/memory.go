@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory keeps sent messages in memory, for development and tests
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemory returns an empty in-memory mailer
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Send records msg
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	if err := msg.check(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
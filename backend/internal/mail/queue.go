@@ -0,0 +1,125 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"time"
+)
+
+// DefaultQueueSize is how many jobs a Queue holds before Enqueue fails
+const DefaultQueueSize = 256
+
+// JobTimeout bounds a single job, including the delivery of its message
+const JobTimeout = 30 * time.Second
+
+// SendAttempts is how often a Queue tries to deliver a message before giving
+// up on it
+const SendAttempts = 3
+
+// DefaultRetryDelay is the wait before the second delivery attempt. It doubles
+// after every further failure.
+const DefaultRetryDelay = time.Second
+
+// ErrQueueFull is returned by Enqueue when the queue cannot take more jobs
+var ErrQueueFull = errors.New("mail: queue full")
+
+// Job is a unit of work of a Queue. It typically prepares a message, for
+// example by issuing the token the message carries, and sends it with m.
+// Sends through m are retried, so a job only sees errors that persisted;
+// jobs log their own failures.
+type Job func(ctx context.Context, m Mailer)
+
+// Queue runs mail jobs on a single background worker, so that requests never
+// wait on the mail server. Run delivers until its context is cancelled and
+// then drains the jobs still queued. Jobs that do not fit in the queue are
+// dropped.
+type Queue struct {
+	mailer     Mailer
+	jobs       chan Job
+	retryDelay time.Duration
+}
+
+// NewQueue returns a queue delivering through m that holds up to size jobs
+func NewQueue(m Mailer, size int) *Queue {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	return &Queue{mailer: m, jobs: make(chan Job, size), retryDelay: DefaultRetryDelay}
+}
+
+// Enqueue adds job to the queue without blocking. It returns ErrQueueFull if
+// the queue is full.
+func (q *Queue) Enqueue(job Job) error {
+	select {
+	case q.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run runs queued jobs until ctx is cancelled, then runs the jobs queued by
+// then and returns. Jobs get a context of their own, so draining is not cut
+// short by the cancellation; callers stop enqueueing before cancelling ctx.
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case job := <-q.jobs:
+			q.run(job)
+		case <-ctx.Done():
+			for {
+				select {
+				case job := <-q.jobs:
+					q.run(job)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (q *Queue) run(job Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), JobTimeout)
+	defer cancel()
+	job(ctx, retrying{mailer: q.mailer, delay: q.retryDelay})
+}
+
+// retrying sends through mailer, trying failed deliveries up to SendAttempts
+// times unless the failure is permanent
+type retrying struct {
+	mailer Mailer
+	delay  time.Duration
+}
+
+func (r retrying) Send(ctx context.Context, msg Message) error {
+	// A malformed message fails the same way every time
+	if err := msg.check(); err != nil {
+		return err
+	}
+
+	delay := r.delay
+	for attempt := 1; ; attempt++ {
+		err := r.mailer.Send(ctx, msg)
+		if err == nil || attempt == SendAttempts || permanent(err) {
+			return err
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// permanent reports whether err is an SMTP reply rejecting the message for
+// good, such as an unknown mailbox
+func permanent(err error) bool {
+	var reply *textproto.Error
+	return errors.As(err, &reply) && reply.Code >= 500
+}
@@ -0,0 +1,169 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sendJob returns a job sending a message to addr
+func sendJob(addr string) Job {
+	return func(ctx context.Context, m Mailer) {
+		m.Send(ctx, Message{To: addr, Subject: "Hello"})
+	}
+}
+
+func TestQueueRun(t *testing.T) {
+	mem := NewMemory()
+	q := NewQueue(mem, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+
+	delivered := make(chan struct{})
+	q.Enqueue(sendJob("a@example.com"))
+	q.Enqueue(func(ctx context.Context, m Mailer) { close(delivered) })
+	<-delivered
+	if got := len(mem.Messages()); got != 1 {
+		t.Errorf("%d messages delivered, want 1", got)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestQueueDrainsOnStop(t *testing.T) {
+	mem := NewMemory()
+	q := NewQueue(mem, 4)
+	for _, addr := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if err := q.Enqueue(sendJob(addr)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	q.Run(ctx)
+
+	msgs := mem.Messages()
+	if len(msgs) != 3 || msgs[0].To != "a@example.com" || msgs[2].To != "c@example.com" {
+		t.Errorf("delivered %+v, want the three queued messages in order", msgs)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	mem := NewMemory()
+	q := NewQueue(mem, 1)
+	if err := q.Enqueue(sendJob("a@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Enqueue(sendJob("b@example.com")); err != ErrQueueFull {
+		t.Errorf("Enqueue on a full queue = %v, want ErrQueueFull", err)
+	}
+
+	// The job that did not fit is dropped
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	q.Run(ctx)
+	if msgs := mem.Messages(); len(msgs) != 1 || msgs[0].To != "a@example.com" {
+		t.Errorf("delivered %+v, want only the first message", msgs)
+	}
+}
+
+// flakyMailer fails its first failures sends with err
+type flakyMailer struct {
+	*Memory
+	err      error
+	failures int
+
+	mu       sync.Mutex
+	attempts int
+}
+
+func (m *flakyMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	m.attempts++
+	fail := m.attempts <= m.failures
+	m.mu.Unlock()
+	if fail {
+		return m.err
+	}
+	return m.Memory.Send(ctx, msg)
+}
+
+func TestQueueRetry(t *testing.T) {
+	temporary := errors.New("connection refused")
+	rejected := &textproto.Error{Code: 550, Msg: "No such user"}
+	tests := []struct {
+		name         string
+		err          error
+		failures     int
+		msg          Message
+		wantAttempts int
+		wantErr      error
+	}{
+		{"delivered", temporary, 0, Message{To: "a@example.com"}, 1, nil},
+		{"delivered on retry", temporary, SendAttempts - 1, Message{To: "a@example.com"}, SendAttempts, nil},
+		{"gives up", temporary, SendAttempts, Message{To: "a@example.com"}, SendAttempts, temporary},
+		{"permanent failure", rejected, 1, Message{To: "a@example.com"}, 1, rejected},
+	}
+	for _, tt := range tests {
+		m := &flakyMailer{Memory: NewMemory(), err: tt.err, failures: tt.failures}
+		q := NewQueue(m, 1)
+		q.retryDelay = time.Millisecond
+		var sendErr error
+		q.Enqueue(func(ctx context.Context, m Mailer) { sendErr = m.Send(ctx, tt.msg) })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		q.Run(ctx)
+
+		if m.attempts != tt.wantAttempts {
+			t.Errorf("%s: %d attempts, want %d", tt.name, m.attempts, tt.wantAttempts)
+		}
+		if delivered := len(m.Messages()) == 1; sendErr != tt.wantErr || delivered != (tt.wantErr == nil) {
+			t.Errorf("%s: error = %v, delivered = %v; want %v", tt.name, sendErr, delivered, tt.wantErr)
+		}
+	}
+
+	// A malformed message is not worth another attempt
+	m := &flakyMailer{Memory: NewMemory()}
+	send := retrying{mailer: m, delay: time.Millisecond}
+	if err := send.Send(context.Background(), Message{To: "not an address"}); err == nil || m.attempts != 0 {
+		t.Errorf("invalid message: error = %v after %d attempts", err, m.attempts)
+	}
+}
+
+func TestQueueRetryStopsAtDeadline(t *testing.T) {
+	m := &flakyMailer{Memory: NewMemory(), err: errors.New("connection refused"), failures: SendAttempts}
+	send := retrying{mailer: m, delay: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := send.Send(ctx, Message{To: "a@example.com"}); err == nil {
+		t.Fatal("Send succeeded")
+	}
+	if m.attempts != 1 {
+		t.Errorf("%d attempts before the deadline, want 1", m.attempts)
+	}
+}
+
+func TestQueueJobContext(t *testing.T) {
+	q := NewQueue(NewMemory(), 1)
+	var jobErr error
+	q.Enqueue(func(ctx context.Context, m Mailer) { jobErr = ctx.Err() })
+
+	// Draining after cancellation still gives jobs a live context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	q.Run(ctx)
+	if jobErr != nil {
+		t.Errorf("job context done while draining: %v", jobErr)
+	}
+}
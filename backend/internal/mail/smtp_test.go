@@ -0,0 +1,185 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// envelope is a message received by fakeSMTP
+type envelope struct {
+	auth string
+	from string
+	to   string
+	data string
+}
+
+// fakeSMTP is a minimal SMTP server accepting every message except those
+// to rejected addresses
+type fakeSMTP struct {
+	addr     string
+	rejected string
+
+	mu        sync.Mutex
+	envelopes []envelope
+}
+
+// newFakeSMTP starts a server on a random local port, stopped with the test
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &fakeSMTP{addr: ln.Addr().String()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+
+	var env envelope
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-localhost")
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			_, creds, _ := strings.Cut(arg, " ")
+			b, _ := base64.StdEncoding.DecodeString(creds)
+			env.auth = string(b)
+			tp.PrintfLine("235 Authenticated")
+		case "MAIL":
+			env.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			env.to = strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if env.to == s.rejected {
+				tp.PrintfLine("550 No such user")
+				continue
+			}
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			env.data = string(data)
+			s.mu.Lock()
+			s.envelopes = append(s.envelopes, env)
+			s.mu.Unlock()
+			tp.PrintfLine("250 Queued")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTP) received() []envelope {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]envelope(nil), s.envelopes...)
+}
+
+func TestSMTPSend(t *testing.T) {
+	srv := newFakeSMTP(t)
+	m := &SMTP{Addr: srv.addr, From: "UMS <no-reply@example.com>", Username: "ums", Password: "secret"}
+
+	msg := Message{To: "alice@example.com", Subject: "Verify your email", Text: "Open the link"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	got := srv.received()
+	if len(got) != 1 {
+		t.Fatalf("received %d messages, want 1", len(got))
+	}
+	env := got[0]
+	if env.from != "no-reply@example.com" || env.to != "alice@example.com" {
+		t.Errorf("envelope from %q to %q", env.from, env.to)
+	}
+	if env.auth != "\x00ums\x00secret" {
+		t.Errorf("auth = %q, want PLAIN credentials", env.auth)
+	}
+	for _, want := range []string{"From: UMS <no-reply@example.com>\n", "To: alice@example.com\n", "Subject: Verify your email\n", "Open the link"} {
+		if !strings.Contains(env.data, want) {
+			t.Errorf("message lacks %q:\n%s", want, env.data)
+		}
+	}
+}
+
+func TestSMTPSendRejected(t *testing.T) {
+	srv := newFakeSMTP(t)
+	srv.rejected = "nobody@example.com"
+	m := &SMTP{Addr: srv.addr, From: "no-reply@example.com"}
+
+	err := m.Send(context.Background(), Message{To: "nobody@example.com", Subject: "Hello"})
+	var reply *textproto.Error
+	if !errors.As(err, &reply) || reply.Code != 550 || !permanent(err) {
+		t.Errorf("Send to a rejected address = %v, want a permanent 550 reply", err)
+	}
+	if got := srv.received(); len(got) != 0 {
+		t.Errorf("received %+v", got)
+	}
+}
+
+func TestSMTPSendUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	m := &SMTP{Addr: addr, From: "no-reply@example.com"}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Send(ctx, Message{To: "alice@example.com", Subject: "Hello"}); err == nil || permanent(err) {
+		t.Errorf("Send without a server = %v, want a temporary error", err)
+	}
+}
+
+func TestSMTPSendInvalidMessage(t *testing.T) {
+	srv := newFakeSMTP(t)
+	m := &SMTP{Addr: srv.addr, From: "no-reply@example.com"}
+
+	for _, msg := range []Message{
+		{To: "not an address", Subject: "Hello"},
+		{To: "alice@example.com", Subject: "Hello\r\nBcc: mallory@example.com"},
+	} {
+		if err := m.Send(context.Background(), msg); err == nil {
+			t.Errorf("Send(%+v) succeeded", msg)
+		}
+	}
+	if got := srv.received(); len(got) != 0 {
+		t.Errorf("received %+v", got)
+	}
+}
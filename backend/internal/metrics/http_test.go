@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// newInstrumentedRouter returns a router serving /api/items/{id} behind
+// RouteLabel and Instrument, as the server wires them
+func newInstrumentedRouter(handler http.HandlerFunc) http.Handler {
+	r := mux.NewRouter()
+	r.Use(RouteLabel)
+	r.HandleFunc("/api/items/{id}", handler).Methods("GET", "PUT")
+	return Instrument(r)
+}
+
+// routes returns the route labels of the request counter
+func routes(t *testing.T) map[string]bool {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 100)
+	go func() {
+		httpRequests.Collect(ch)
+		close(ch)
+	}()
+	seen := map[string]bool{}
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range pb.GetLabel() {
+			if l.GetName() == "route" {
+				seen[l.GetValue()] = true
+			}
+		}
+	}
+	return seen
+}
+
+func requests(method, route, status string) float64 {
+	return testutil.ToFloat64(httpRequests.With(prometheus.Labels{"method": method, "route": route, "status": status}))
+}
+
+func TestInstrumentRouteTemplate(t *testing.T) {
+	h := newInstrumentedRouter(func(w http.ResponseWriter, r *http.Request) {})
+	before := requests("GET", "/api/items/{id}", "200")
+
+	for _, path := range []string{"/api/items/1", "/api/items/2", "/api/items/abc"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := requests("GET", "/api/items/{id}", "200") - before; got != 3 {
+		t.Errorf("%v requests counted under the route template, want 3", got)
+	}
+	for route := range routes(t) {
+		if route == "/api/items/1" || route == "/api/items/abc" {
+			t.Errorf("raw path %s used as a route label", route)
+		}
+	}
+}
+
+func TestInstrumentUnmatched(t *testing.T) {
+	h := newInstrumentedRouter(func(w http.ResponseWriter, r *http.Request) {})
+	before := requests("GET", unmatchedRoute, "404")
+	beforeOther := requests("other", unmatchedRoute, "404")
+	labels := len(routes(t))
+
+	for _, path := range []string{"/wp-admin", "/.env", "/api/items", "/api/items/1/2"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s: status = %d", path, w.Code)
+		}
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PROPFIND", "/random", nil))
+
+	if got := requests("GET", unmatchedRoute, "404") - before; got != 4 {
+		t.Errorf("%v unmatched requests counted, want 4", got)
+	}
+	if got := requests("other", unmatchedRoute, "404") - beforeOther; got != 1 {
+		t.Errorf("%v requests with an unknown method counted, want 1", got)
+	}
+	// The probes add no route labels besides the one for unmatched requests
+	if got := len(routes(t)); got > labels+1 {
+		t.Errorf("%d route labels after the probes, had %d", got, labels)
+	}
+}
+
+func TestInstrumentStatusAndDuration(t *testing.T) {
+	h := newInstrumentedRouter(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+		// Later status codes are ignored by net/http and by the metrics
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	labels := prometheus.Labels{"method": "PUT", "route": "/api/items/{id}", "status": "201"}
+	before := testutil.ToFloat64(httpRequests.With(labels))
+	samplesBefore, sumBefore := durations(t, labels)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/api/items/1", nil))
+
+	if got := testutil.ToFloat64(httpRequests.With(labels)) - before; got != 1 {
+		t.Errorf("%v requests counted with status 201, want 1", got)
+	}
+	if got := requests("PUT", "/api/items/{id}", "500"); got != 0 {
+		t.Errorf("%v requests counted with the ignored status 500", got)
+	}
+
+	samples, sum := durations(t, labels)
+	if samples-samplesBefore != 1 || sum-sumBefore < 0.02 {
+		t.Errorf("%d durations observed summing to %vs, want one of at least 20ms", samples-samplesBefore, sum-sumBefore)
+	}
+}
+
+// durations returns the number and sum of the request durations observed
+// with labels
+func durations(t *testing.T, labels prometheus.Labels) (uint64, float64) {
+	t.Helper()
+	var pb dto.Metric
+	if err := httpDuration.With(labels).(prometheus.Metric).Write(&pb); err != nil {
+		t.Fatal(err)
+	}
+	return pb.GetHistogram().GetSampleCount(), pb.GetHistogram().GetSampleSum()
+}
+
+func TestInstrumentDefaultStatus(t *testing.T) {
+	// A handler that only writes a body answers 200
+	h := newInstrumentedRouter(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	before := requests("GET", "/api/items/{id}", "200")
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/items/1", nil))
+
+	if got := requests("GET", "/api/items/{id}", "200") - before; got != 1 {
+		t.Errorf("%v requests counted with status 200, want 1", got)
+	}
+}
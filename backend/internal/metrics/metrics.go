@@ -0,0 +1,112 @@
+// Package metrics exposes Prometheus metrics for the HTTP API, sign-ins,
+// registrations and the database connection pool.
+//
+// The collectors are package globals so handlers can record events without
+// having a registry threaded through; NewRegistry gathers them together with
+// the Go runtime, process and sql.DBStats collectors.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Login failure reasons
+const (
+	LoginInvalidRequest = "invalid_request"
+	LoginUnknownUser    = "unknown_user"
+	LoginInactive       = "inactive"
+	LoginBadPassword    = "bad_password"
+	LoginUnverified     = "unverified"
+	LoginBadMFACode     = "bad_mfa_code"
+	LoginMFAExpired     = "mfa_expired"
+	LoginMFALocked      = "mfa_locked"
+	LoginBadPasskey     = "bad_passkey"
+	LoginNeedsPasskey   = "passkey_required"
+	LoginError          = "error"
+)
+
+// Registration methods
+const (
+	RegisterSelf       = "self"
+	RegisterAdmin      = "admin"
+	RegisterInvitation = "invitation"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ums",
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "ums",
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ums",
+		Name:      "logins_total",
+		Help:      "Sign-in attempts by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ums",
+		Name:      "registrations_total",
+		Help:      "Created accounts by method: self-registration, admin or invitation.",
+	}, []string{"method"})
+)
+
+func init() {
+	// Start every known series at zero so rates work from the first scrape
+	logins.WithLabelValues("success", "")
+	for _, reason := range []string{LoginInvalidRequest, LoginUnknownUser, LoginInactive, LoginBadPassword, LoginUnverified, LoginBadMFACode, LoginMFAExpired, LoginMFALocked, LoginBadPasskey, LoginNeedsPasskey, LoginError} {
+		logins.WithLabelValues("failure", reason)
+	}
+	for _, method := range []string{RegisterSelf, RegisterAdmin, RegisterInvitation} {
+		registrations.WithLabelValues(method)
+	}
+}
+
+// LoginSucceeded counts a successful sign-in
+func LoginSucceeded() {
+	logins.WithLabelValues("success", "").Inc()
+}
+
+// LoginFailed counts a failed sign-in; reason is one of the Login* constants
+func LoginFailed(reason string) {
+	logins.WithLabelValues("failure", reason).Inc()
+}
+
+// Registered counts a created account; method is one of the Register* constants
+func Registered(method string) {
+	registrations.WithLabelValues(method).Inc()
+}
+
+// NewRegistry returns a registry holding the UMS metrics, the Go runtime and
+// process metrics and the connection pool statistics of db
+func NewRegistry(db *sql.DB) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "ums"),
+		httpRequests,
+		httpDuration,
+		logins,
+		registrations,
+	)
+	return reg
+}
+
+// Handler serves the metrics in reg in the Prometheus exposition format
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,41 @@
+package mfa
+
+import "testing"
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != RecoveryCodeCount || len(hashes) != RecoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), RecoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("code %q is not formatted like k3j9q-xw2ma", code)
+		}
+		if IsCode(code) {
+			t.Errorf("recovery code %q looks like a TOTP code", code)
+		}
+		if hashes[i] != HashRecoveryCode(code) {
+			t.Errorf("hash of %q does not match", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCodeIgnoresFormatting(t *testing.T) {
+	want := HashRecoveryCode("k3j9q-xw2ma")
+	for _, code := range []string{"K3J9Q-XW2MA", "k3j9qxw2ma", "k3j9q xw2ma"} {
+		if got := HashRecoveryCode(code); got != want {
+			t.Errorf("HashRecoveryCode(%q) differs from the canonical form", code)
+		}
+	}
+	if HashRecoveryCode("k3j9q-xw2mb") == want {
+		t.Error("different codes hash alike")
+	}
+}
@@ -0,0 +1,102 @@
+// Package mfa implements the second factor of sign-in: RFC 6238 time-based
+// one-time passwords and single-use recovery codes.
+package mfa
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	// Period is the lifetime of a code in seconds
+	Period = 30
+	// Skew is how many periods before and after the current one are
+	// accepted, to allow for clock drift
+	Skew = 1
+	// CodeLength is the number of digits of a code
+	CodeLength = 6
+
+	// qrSize is the width and height of the enrollment QR code in pixels
+	qrSize = 256
+)
+
+// Key is a new TOTP secret ready to be added to an authenticator app
+type Key struct {
+	// Secret is the base32 secret, for manual entry
+	Secret string
+	// URL is the otpauth:// URI encoded in the QR code
+	URL string
+	key *otp.Key
+}
+
+// NewKey generates a random secret for account at issuer, e.g. the username
+// at "UMS"
+func NewKey(issuer, account string) (Key, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: account,
+		Period:      Period,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{Secret: key.Secret(), URL: key.URL(), key: key}, nil
+}
+
+// QRCode returns the otpauth URI as a PNG QR code in a data: URL
+func (k Key) QRCode() (string, error) {
+	img, err := k.key.Image(qrSize, qrSize)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// IsCode reports whether s looks like a TOTP code rather than a recovery
+// code
+func IsCode(s string) bool {
+	s = strings.TrimSpace(s)
+	if len(s) != CodeLength {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks code against secret at time t and returns the time step it
+// belongs to. Steps at or before lastStep are rejected so that a code cannot
+// be replayed; callers store the returned step as the new lastStep.
+func Validate(secret, code string, t time.Time, lastStep int64) (step int64, ok bool) {
+	code = strings.TrimSpace(code)
+	current := t.Unix() / Period
+	for s := current - Skew; s <= current+Skew; s++ {
+		if s <= lastStep {
+			continue
+		}
+		valid, err := hotp.ValidateCustom(code, uint64(s), secret, hotp.ValidateOpts{
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err == nil && valid {
+			return s, true
+		}
+	}
+	return 0, false
+}
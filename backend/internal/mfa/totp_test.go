@@ -0,0 +1,102 @@
+package mfa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// codeAt returns the code of secret at t
+func codeAt(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := totp.GenerateCodeCustom(secret, at, totp.ValidateOpts{
+		Period:    Period,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestNewKey(t *testing.T) {
+	key, err := NewKey("UMS", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Secret == "" || key.URL == "" {
+		t.Fatalf("key = %+v", key)
+	}
+	qr, err := key.QRCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(qr) < 100 || qr[:22] != "data:image/png;base64," {
+		t.Errorf("QRCode() = %.40q", qr)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	key, err := NewKey("UMS", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	step := now.Unix() / Period
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		lastStep int64
+		want     bool
+		wantStep int64
+	}{
+		{"current period", now, 0, true, step},
+		{"previous period", now.Add(-Period * time.Second), 0, true, step - 1},
+		{"next period", now.Add(Period * time.Second), 0, true, step + 1},
+		{"too old", now.Add(-2 * Period * time.Second), 0, false, 0},
+		{"too new", now.Add(2 * Period * time.Second), 0, false, 0},
+		{"replayed", now, step, false, 0},
+		{"older than last use", now.Add(-Period * time.Second), step, false, 0},
+		{"newer than last use", now.Add(Period * time.Second), step, true, step + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Validate(key.Secret, codeAt(t, key.Secret, tt.at), now, tt.lastStep)
+			if ok != tt.want || got != tt.wantStep {
+				t.Errorf("Validate() = %d, %v, want %d, %v", got, ok, tt.wantStep, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsOtherSecrets(t *testing.T) {
+	a, _ := NewKey("UMS", "alice")
+	b, _ := NewKey("UMS", "bob")
+	now := time.Now()
+	if _, ok := Validate(a.Secret, codeAt(t, b.Secret, now), now, 0); ok {
+		t.Error("code of another secret accepted")
+	}
+	if _, ok := Validate(a.Secret, "12345", now, 0); ok {
+		t.Error("short code accepted")
+	}
+}
+
+func TestIsCode(t *testing.T) {
+	tests := map[string]bool{
+		"123456":      true,
+		" 123456 ":    true,
+		"12345":       false,
+		"1234567":     false,
+		"12345a":      false,
+		"k3j9q-xw2ma": false,
+	}
+	for s, want := range tests {
+		if got := IsCode(s); got != want {
+			t.Errorf("IsCode(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrVerificationInvalid is returned when a verification token is unknown,
+// used, expired or was sent to an address the user no longer has
+var ErrVerificationInvalid = errors.New("email verification invalid or expired")
+
+// EmailVerification is a single-use token proving that a user receives mail
+// at Email. Only the SHA-256 hash of the token is stored.
+type EmailVerification struct {
+	TokenHash string
+	UserID    string
+	Email     string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// EmailVerificationStore persists email verification tokens
+type EmailVerificationStore interface {
+	// Create stores v and discards any earlier unused verification of the
+	// same user, so only the most recent link works
+	Create(ctx context.Context, v EmailVerification) error
+	// Verify redeems the verification with the given token hash: it is
+	// marked used and the email address of its user is marked verified,
+	// provided the user still has the address the token was sent to
+	Verify(ctx context.Context, tokenHash string) (User, error)
+	// DeleteExpired removes verifications that expired before cutoff
+	DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error)
+}
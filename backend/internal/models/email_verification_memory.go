@@ -0,0 +1,43 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// MemoryEmailVerificationStore is an EmailVerificationStore kept in process
+// memory, for tests and development without a database. Verifying marks the
+// email address of the user in users verified. It is safe for concurrent use.
+type MemoryEmailVerificationStore struct {
+	table *memoryTokenTable
+	users *MemoryUserRepository
+}
+
+// NewMemoryEmailVerificationStore returns an empty in-memory store for the
+// users kept in users
+func NewMemoryEmailVerificationStore(users *MemoryUserRepository) *MemoryEmailVerificationStore {
+	return &MemoryEmailVerificationStore{table: newMemoryTokenTable(ErrVerificationInvalid), users: users}
+}
+
+func (m *MemoryEmailVerificationStore) Create(ctx context.Context, v EmailVerification) error {
+	m.table.create(v.TokenHash, memoryToken{UserID: v.UserID, Extra: v.Email, ExpiresAt: v.ExpiresAt})
+	return nil
+}
+
+func (m *MemoryEmailVerificationStore) Verify(ctx context.Context, tokenHash string) (User, error) {
+	return m.table.redeem(tokenHash, func(token memoryToken) (User, error) {
+		return m.users.modify(token.UserID, func(u *User, now time.Time) error {
+			if u.Email != token.Extra {
+				return ErrUserNotFound
+			}
+			if u.EmailVerifiedAt == nil {
+				u.EmailVerifiedAt = &now
+			}
+			return nil
+		})
+	})
+}
+
+func (m *MemoryEmailVerificationStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	return m.table.deleteExpired(cutoff), nil
+}
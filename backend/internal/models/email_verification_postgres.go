@@ -0,0 +1,36 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PostgresEmailVerificationStore is an EmailVerificationStore backed by the
+// email_verifications table
+type PostgresEmailVerificationStore struct {
+	table tokenTable
+}
+
+// NewPostgresEmailVerificationStore returns a store using db
+func NewPostgresEmailVerificationStore(db *sql.DB) *PostgresEmailVerificationStore {
+	return &PostgresEmailVerificationStore{table: tokenTable{db: db, name: "email_verifications", invalid: ErrVerificationInvalid}}
+}
+
+func (p *PostgresEmailVerificationStore) Create(ctx context.Context, v EmailVerification) error {
+	return p.table.create(ctx, v.TokenHash, v.UserID, v.ExpiresAt, v.CreatedAt, tokenColumn{"email", v.Email})
+}
+
+func (p *PostgresEmailVerificationStore) Verify(ctx context.Context, tokenHash string) (User, error) {
+	var email string
+	return p.table.redeem(ctx, tokenHash, func(tx *sql.Tx, now time.Time, userID string) (User, error) {
+		return scanUser(tx.QueryRowContext(ctx, `
+			UPDATE users SET email_verified_at = COALESCE(email_verified_at, $1), updated_at = $1
+			WHERE id = $2 AND email = $3
+			RETURNING `+userColumns, now, userID, email))
+	}, tokenColumn{"email", &email})
+}
+
+func (p *PostgresEmailVerificationStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	return p.table.deleteExpired(ctx, cutoff)
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvitationInvalid is returned when an invite token is unknown, used or expired
+var ErrInvitationInvalid = errors.New("invitation invalid or expired")
+
+// Invitation is a single-use token letting a pending user set their password.
+// Only the SHA-256 hash of the token is stored.
+type Invitation struct {
+	TokenHash string
+	UserID    string
+	CreatedBy string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// InvitationStore persists the invitations of pending users
+type InvitationStore interface {
+	// Create stores inv and discards any earlier unused invitation of the
+	// same user, so only the most recent invite link works
+	Create(ctx context.Context, inv Invitation) error
+	// Accept redeems the invitation with the given token hash: it is marked
+	// used and its pending user is activated with passwordHash. The invite
+	// link reached the user, so their email address counts as verified.
+	Accept(ctx context.Context, tokenHash, passwordHash string) (User, error)
+	// DeleteExpired removes invitations that expired before cutoff
+	DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error)
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// MemoryInvitationStore is an InvitationStore kept in process memory, for
+// tests and development without a database. Accepting an invitation
+// activates the user in users and marks their address verified. It is safe
+// for concurrent use.
+type MemoryInvitationStore struct {
+	table *memoryTokenTable
+	users *MemoryUserRepository
+}
+
+// NewMemoryInvitationStore returns an empty in-memory store for the users
+// kept in users
+func NewMemoryInvitationStore(users *MemoryUserRepository) *MemoryInvitationStore {
+	return &MemoryInvitationStore{table: newMemoryTokenTable(ErrInvitationInvalid), users: users}
+}
+
+func (m *MemoryInvitationStore) Create(ctx context.Context, inv Invitation) error {
+	m.table.create(inv.TokenHash, memoryToken{UserID: inv.UserID, Extra: inv.CreatedBy, ExpiresAt: inv.ExpiresAt})
+	return nil
+}
+
+func (m *MemoryInvitationStore) Accept(ctx context.Context, tokenHash, passwordHash string) (User, error) {
+	return m.table.redeem(tokenHash, func(token memoryToken) (User, error) {
+		return m.users.modify(token.UserID, func(u *User, now time.Time) error {
+			if u.Status != UserStatusPending {
+				return ErrUserNotFound
+			}
+			u.Password = passwordHash
+			u.Status = UserStatusActive
+			if u.EmailVerifiedAt == nil {
+				u.EmailVerifiedAt = &now
+			}
+			return nil
+		})
+	})
+}
+
+func (m *MemoryInvitationStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	return m.table.deleteExpired(cutoff), nil
+}
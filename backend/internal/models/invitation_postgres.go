@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PostgresInvitationStore is an InvitationStore backed by the invitations
+// table
+type PostgresInvitationStore struct {
+	table tokenTable
+}
+
+// NewPostgresInvitationStore returns a store using db
+func NewPostgresInvitationStore(db *sql.DB) *PostgresInvitationStore {
+	return &PostgresInvitationStore{table: tokenTable{db: db, name: "invitations", invalid: ErrInvitationInvalid}}
+}
+
+func (p *PostgresInvitationStore) Create(ctx context.Context, inv Invitation) error {
+	var createdBy interface{}
+	if inv.CreatedBy != "" {
+		createdBy = inv.CreatedBy
+	}
+	return p.table.create(ctx, inv.TokenHash, inv.UserID, inv.ExpiresAt, inv.CreatedAt, tokenColumn{"created_by", createdBy})
+}
+
+func (p *PostgresInvitationStore) Accept(ctx context.Context, tokenHash, passwordHash string) (User, error) {
+	return p.table.redeem(ctx, tokenHash, func(tx *sql.Tx, now time.Time, userID string) (User, error) {
+		return scanUser(tx.QueryRowContext(ctx, `
+			UPDATE users SET password = $1, status = $2,
+				email_verified_at = COALESCE(email_verified_at, $3), updated_at = $3
+			WHERE id = $4 AND status = $5
+			RETURNING `+userColumns, passwordHash, UserStatusActive, now, userID, UserStatusPending))
+	})
+}
+
+func (p *PostgresInvitationStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	return p.table.deleteExpired(ctx, cutoff)
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Login is an entry of the login history of a user
+type Login struct {
+	UserID    string
+	IP        string
+	UserAgent string
+	CreatedAt time.Time
+}
+
+// LoginHistory records successful sign-ins
+type LoginHistory interface {
+	// RecordLogin adds an entry to the login history of a user
+	RecordLogin(ctx context.Context, userID, ip, userAgent string) error
+}
+
+// PostgresLoginHistory is a LoginHistory backed by the login_history table,
+// which the dashboard statistics read
+type PostgresLoginHistory struct {
+	db *sql.DB
+}
+
+// NewPostgresLoginHistory returns a login history using db
+func NewPostgresLoginHistory(db *sql.DB) *PostgresLoginHistory {
+	return &PostgresLoginHistory{db: db}
+}
+
+func (p *PostgresLoginHistory) RecordLogin(ctx context.Context, userID, ip, userAgent string) error {
+	query := `
+		INSERT INTO login_history (user_id, ip, user_agent, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := p.db.ExecContext(ctx, query, userID, ip, userAgent, time.Now())
+	return err
+}
+
+// MemoryLoginHistory is a LoginHistory kept in process memory, for tests and
+// development without a database. It is safe for concurrent use.
+type MemoryLoginHistory struct {
+	mu     sync.Mutex
+	logins []Login
+}
+
+// NewMemoryLoginHistory returns an empty in-memory login history
+func NewMemoryLoginHistory() *MemoryLoginHistory {
+	return &MemoryLoginHistory{}
+}
+
+func (m *MemoryLoginHistory) RecordLogin(ctx context.Context, userID, ip, userAgent string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logins = append(m.logins, Login{UserID: userID, IP: ip, UserAgent: userAgent, CreatedAt: time.Now()})
+	return nil
+}
+
+// Logins returns the recorded sign-ins, oldest first
+func (m *MemoryLoginHistory) Logins() []Login {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Login(nil), m.logins...)
+}
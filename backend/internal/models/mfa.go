@@ -0,0 +1,114 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrMFANotFound is returned when a user has not started enrolling a
+	// second factor
+	ErrMFANotFound = errors.New("mfa not enrolled")
+	// ErrMFAEnabled is returned when enrolling a user whose second factor is
+	// already confirmed
+	ErrMFAEnabled = errors.New("mfa already enabled")
+	// ErrMFAChallengeInvalid is returned when a challenge token is unknown,
+	// expired, already used or out of attempts
+	ErrMFAChallengeInvalid = errors.New("mfa challenge invalid or expired")
+	// ErrMFALocked is returned while a user is locked out of their second
+	// factor after MaxMFAFailures wrong codes
+	ErrMFALocked = errors.New("mfa locked")
+)
+
+// MaxMFAAttempts is how many wrong codes a challenge tolerates before it is
+// discarded and the user has to enter their password again
+const MaxMFAAttempts = 5
+
+// MaxMFAFailures is how many codes in a row a user may get wrong before their
+// second factor is locked for MFALockout. The budget is shared by every
+// challenge and every profile endpoint that asks for a code, so signing in
+// again does not buy more guesses.
+const MaxMFAFailures = 5
+
+// MFALockout is how long a second factor stays locked after MaxMFAFailures
+// wrong codes
+const MFALockout = 15 * time.Minute
+
+// MFA is the TOTP second factor of a user. Secret is encrypted; the models
+// never see it in plain text.
+type MFA struct {
+	UserID      string
+	Secret      string
+	ConfirmedAt *time.Time
+	LastStep    int64
+	CreatedAt   time.Time
+}
+
+// Enabled reports whether the second factor has been confirmed
+func (m MFA) Enabled() bool {
+	return m.ConfirmedAt != nil
+}
+
+// MFAChallenge is a sign-in whose password was accepted and which waits for
+// the second factor. Only the SHA-256 hash of the token is stored.
+type MFAChallenge struct {
+	TokenHash string
+	UserID    string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// MFAStore persists second factors, recovery codes and pending MFA
+// challenges. Recovery codes are stored as hashes.
+type MFAStore interface {
+	// Get returns the second factor of a user, confirmed or not, or
+	// ErrMFANotFound
+	Get(ctx context.Context, userID string) (MFA, error)
+	// StartEnrollment stores a new unconfirmed secret for a user, replacing
+	// an earlier unconfirmed one. It returns ErrMFAEnabled if the user
+	// already has a confirmed second factor.
+	StartEnrollment(ctx context.Context, userID, secret string) error
+	// Confirm enables the second factor of a user after a code from step was
+	// accepted, and replaces their recovery codes with codeHashes
+	Confirm(ctx context.Context, userID string, step int64, codeHashes []string) error
+	// UseStep records that a code from step was accepted. It reports false
+	// if a code of the same or a later step was accepted before, which
+	// means the code is being replayed.
+	UseStep(ctx context.Context, userID string, step int64) (bool, error)
+	// Delete removes the second factor, recovery codes and pending
+	// challenges of a user. It reports whether the user had a second factor.
+	Delete(ctx context.Context, userID string) (bool, error)
+	// ReplaceRecoveryCodes discards the recovery codes of a user and stores
+	// codeHashes instead
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	// UseRecoveryCode marks a recovery code of a user used. It reports false
+	// if the code is unknown or was used before.
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	// UseAttempt counts an attempt at the second factor of a user before its
+	// code is checked, so concurrent guesses cannot exceed the limit. The
+	// MaxMFAFailures-th attempt since the last accepted code locks the second
+	// factor for MFALockout; while it is locked UseAttempt returns
+	// ErrMFALocked and the end of the lockout.
+	UseAttempt(ctx context.Context, userID string) (time.Time, error)
+	// ResetAttempts forgets the attempts of a user after a code was
+	// accepted, ending a lockout the accepted attempt started
+	ResetAttempts(ctx context.Context, userID string) error
+	// CountRecoveryCodes returns how many unused recovery codes a user has left
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+	// CreateChallenge stores c
+	CreateChallenge(ctx context.Context, c MFAChallenge) error
+	// UseChallengeAttempt counts an attempt against the pending challenge
+	// with the given token hash before its code is checked and returns the
+	// user it was issued to. It returns ErrMFAChallengeInvalid once the
+	// challenge has expired or MaxMFAAttempts attempts were made, so
+	// concurrent guesses cannot exceed the limit.
+	UseChallengeAttempt(ctx context.Context, tokenHash string) (string, error)
+	// CompleteChallenge deletes a challenge whose second factor was
+	// accepted. It returns ErrMFAChallengeInvalid if a concurrent request
+	// completed it first, so each challenge signs in at most once.
+	CompleteChallenge(ctx context.Context, tokenHash string) error
+	// DeleteExpiredChallenges removes challenges that expired before cutoff
+	DeleteExpiredChallenges(ctx context.Context, cutoff time.Time) (int64, error)
+}
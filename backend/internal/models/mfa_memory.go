@@ -0,0 +1,208 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryMFAStore is an MFAStore kept in process memory, for tests and
+// development without a database. It is safe for concurrent use.
+type MemoryMFAStore struct {
+	mu         sync.Mutex
+	factors    map[string]MFA
+	codes      map[string]map[string]bool // user ID -> code hash -> used
+	challenges map[string]MFAChallenge
+	attempts   map[string]mfaAttempts
+	now        func() time.Time
+}
+
+// mfaAttempts are the attempts of a user since their last accepted code
+type mfaAttempts struct {
+	count       int
+	lockedUntil time.Time
+}
+
+// NewMemoryMFAStore returns an empty in-memory store
+func NewMemoryMFAStore() *MemoryMFAStore {
+	return &MemoryMFAStore{
+		factors:    map[string]MFA{},
+		codes:      map[string]map[string]bool{},
+		challenges: map[string]MFAChallenge{},
+		attempts:   map[string]mfaAttempts{},
+		now:        time.Now,
+	}
+}
+
+func (m *MemoryMFAStore) Get(ctx context.Context, userID string) (MFA, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.factors[userID]
+	if !ok {
+		return MFA{}, ErrMFANotFound
+	}
+	return f, nil
+}
+
+func (m *MemoryMFAStore) StartEnrollment(ctx context.Context, userID, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.factors[userID]; ok && f.Enabled() {
+		return ErrMFAEnabled
+	}
+	m.factors[userID] = MFA{UserID: userID, Secret: secret, CreatedAt: m.now()}
+	return nil
+}
+
+func (m *MemoryMFAStore) Confirm(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.factors[userID]
+	if !ok || f.Enabled() {
+		return ErrMFAEnabled
+	}
+	now := m.now()
+	f.ConfirmedAt = &now
+	f.LastStep = step
+	m.factors[userID] = f
+	m.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+func (m *MemoryMFAStore) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.factors[userID]
+	if !ok || !f.Enabled() || f.LastStep >= step {
+		return false, nil
+	}
+	f.LastStep = step
+	m.factors[userID] = f
+	return true, nil
+}
+
+func (m *MemoryMFAStore) Delete(ctx context.Context, userID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, had := m.factors[userID]
+	delete(m.factors, userID)
+	delete(m.codes, userID)
+	delete(m.attempts, userID)
+	for hash, c := range m.challenges {
+		if c.UserID == userID {
+			delete(m.challenges, hash)
+		}
+	}
+	return had, nil
+}
+
+func (m *MemoryMFAStore) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+// replaceRecoveryCodes stores codeHashes as the unused recovery codes of a
+// user. The caller holds m.mu.
+func (m *MemoryMFAStore) replaceRecoveryCodes(userID string, codeHashes []string) {
+	codes := map[string]bool{}
+	for _, hash := range codeHashes {
+		codes[hash] = false
+	}
+	m.codes[userID] = codes
+}
+
+func (m *MemoryMFAStore) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	used, ok := m.codes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	m.codes[userID][codeHash] = true
+	return true, nil
+}
+
+func (m *MemoryMFAStore) UseAttempt(ctx context.Context, userID string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.factors[userID]; !ok {
+		return time.Time{}, ErrMFANotFound
+	}
+	now := m.now()
+	a := m.attempts[userID]
+	if !a.lockedUntil.IsZero() {
+		if now.Before(a.lockedUntil) {
+			return a.lockedUntil, ErrMFALocked
+		}
+		a = mfaAttempts{}
+	}
+	a.count++
+	if a.count >= MaxMFAFailures {
+		a.lockedUntil = now.Add(MFALockout)
+	}
+	m.attempts[userID] = a
+	return time.Time{}, nil
+}
+
+func (m *MemoryMFAStore) ResetAttempts(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.attempts, userID)
+	return nil
+}
+
+func (m *MemoryMFAStore) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, used := range m.codes[userID] {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryMFAStore) CreateChallenge(ctx context.Context, c MFAChallenge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.challenges[c.TokenHash] = c
+	return nil
+}
+
+func (m *MemoryMFAStore) UseChallengeAttempt(ctx context.Context, tokenHash string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.challenges[tokenHash]
+	if !ok || !m.now().Before(c.ExpiresAt) || c.Attempts >= MaxMFAAttempts {
+		return "", ErrMFAChallengeInvalid
+	}
+	c.Attempts++
+	m.challenges[tokenHash] = c
+	return c.UserID, nil
+}
+
+func (m *MemoryMFAStore) CompleteChallenge(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.challenges[tokenHash]; !ok {
+		return ErrMFAChallengeInvalid
+	}
+	delete(m.challenges, tokenHash)
+	return nil
+}
+
+func (m *MemoryMFAStore) DeleteExpiredChallenges(ctx context.Context, cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for hash, c := range m.challenges {
+		if c.ExpiresAt.Before(cutoff) {
+			delete(m.challenges, hash)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryMFAStoreLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryMFAStore()
+	store.now = func() time.Time { return now }
+
+	if _, err := store.UseAttempt(ctx, "1"); !errors.Is(err, ErrMFANotFound) {
+		t.Errorf("UseAttempt without a second factor = %v, want ErrMFANotFound", err)
+	}
+	if err := store.StartEnrollment(ctx, "1", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The last allowed attempt starts the lockout; an accepted code ends it
+	for i := 0; i < MaxMFAFailures; i++ {
+		if _, err := store.UseAttempt(ctx, "1"); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+	if err := store.ResetAttempts(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < MaxMFAFailures; i++ {
+		if _, err := store.UseAttempt(ctx, "1"); err != nil {
+			t.Fatalf("attempt %d after the reset: %v", i+1, err)
+		}
+	}
+	now = now.Add(time.Minute)
+	until, err := store.UseAttempt(ctx, "1")
+	if !errors.Is(err, ErrMFALocked) || !until.Equal(now.Add(MFALockout-time.Minute)) {
+		t.Errorf("UseAttempt = %v, %v; want ErrMFALocked until %v", until, err, now.Add(MFALockout-time.Minute))
+	}
+
+	// The lockout expires and the count starts over
+	now = now.Add(MFALockout)
+	for i := 0; i < MaxMFAFailures; i++ {
+		if _, err := store.UseAttempt(ctx, "1"); err != nil {
+			t.Fatalf("attempt %d after the lockout: %v", i+1, err)
+		}
+	}
+	if _, err := store.UseAttempt(ctx, "1"); !errors.Is(err, ErrMFALocked) {
+		t.Errorf("UseAttempt = %v, want ErrMFALocked", err)
+	}
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgresMFAStore is an MFAStore backed by the user_mfa,
+// mfa_recovery_codes and mfa_challenges tables
+type PostgresMFAStore struct {
+	db *sql.DB
+}
+
+// NewPostgresMFAStore returns a store using db
+func NewPostgresMFAStore(db *sql.DB) *PostgresMFAStore {
+	return &PostgresMFAStore{db: db}
+}
+
+func (p *PostgresMFAStore) Get(ctx context.Context, userID string) (MFA, error) {
+	var m MFA
+	var confirmedAt sql.NullTime
+	err := p.db.QueryRowContext(ctx, `
+		SELECT user_id, secret, confirmed_at, last_step, created_at
+		FROM user_mfa WHERE user_id = $1
+	`, userID).Scan(&m.UserID, &m.Secret, &confirmedAt, &m.LastStep, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return MFA{}, ErrMFANotFound
+	}
+	if err != nil {
+		return MFA{}, err
+	}
+	if confirmedAt.Valid {
+		m.ConfirmedAt = &confirmedAt.Time
+	}
+	return m, nil
+}
+
+func (p *PostgresMFAStore) StartEnrollment(ctx context.Context, userID, secret string) error {
+	res, err := p.db.ExecContext(ctx, `
+		INSERT INTO user_mfa (user_id, secret, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_step = 0, created_at = EXCLUDED.created_at
+		WHERE user_mfa.confirmed_at IS NULL
+	`, userID, secret, time.Now())
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMFAEnabled
+	}
+	return nil
+}
+
+func (p *PostgresMFAStore) Confirm(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE user_mfa SET confirmed_at = $1, last_step = $2
+		WHERE user_id = $3 AND confirmed_at IS NULL
+	`, time.Now(), step, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMFAEnabled
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (p *PostgresMFAStore) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	res, err := p.db.ExecContext(ctx, `
+		UPDATE user_mfa SET last_step = $1
+		WHERE user_id = $2 AND confirmed_at IS NOT NULL AND last_step < $1
+	`, step, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (p *PostgresMFAStore) Delete(ctx context.Context, userID string) (bool, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE user_id = $1`, userID); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (p *PostgresMFAStore) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO mfa_recovery_codes (user_id, code_hash)
+		SELECT $1, unnest($2::text[])
+	`, userID, pq.Array(codeHashes))
+	return err
+}
+
+func (p *PostgresMFAStore) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	res, err := p.db.ExecContext(ctx, `
+		UPDATE mfa_recovery_codes SET used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL
+	`, time.Now(), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (p *PostgresMFAStore) UseAttempt(ctx context.Context, userID string) (time.Time, error) {
+	// An expired lockout starts a new count with this attempt
+	now := time.Now()
+	res, err := p.db.ExecContext(ctx, `
+		UPDATE user_mfa SET
+			failed_attempts = CASE WHEN locked_until <= $2 THEN 1 ELSE failed_attempts + 1 END,
+			locked_until = CASE
+				WHEN (CASE WHEN locked_until <= $2 THEN 1 ELSE failed_attempts + 1 END) >= $3 THEN $4::timestamp
+				WHEN locked_until <= $2 THEN NULL
+				ELSE locked_until
+			END
+		WHERE user_id = $1 AND (locked_until IS NULL OR locked_until <= $2)
+	`, userID, now, MaxMFAFailures, now.Add(MFALockout))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return time.Time{}, nil
+	}
+
+	var lockedUntil sql.NullTime
+	err = p.db.QueryRowContext(ctx, `SELECT locked_until FROM user_mfa WHERE user_id = $1`, userID).Scan(&lockedUntil)
+	if err == sql.ErrNoRows {
+		return time.Time{}, ErrMFANotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return lockedUntil.Time, ErrMFALocked
+}
+
+func (p *PostgresMFAStore) ResetAttempts(ctx context.Context, userID string) error {
+	_, err := p.db.ExecContext(ctx, `UPDATE user_mfa SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1`, userID)
+	return err
+}
+
+func (p *PostgresMFAStore) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := p.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&n)
+	return n, err
+}
+
+func (p *PostgresMFAStore) CreateChallenge(ctx context.Context, c MFAChallenge) error {
+	query := `
+		INSERT INTO mfa_challenges (token_hash, user_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := p.db.ExecContext(ctx, query, c.TokenHash, c.UserID, c.ExpiresAt, c.CreatedAt)
+	return err
+}
+
+func (p *PostgresMFAStore) UseChallengeAttempt(ctx context.Context, tokenHash string) (string, error) {
+	var userID string
+	err := p.db.QueryRowContext(ctx, `
+		UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE token_hash = $1 AND attempts < $2 AND expires_at > $3
+		RETURNING user_id
+	`, tokenHash, MaxMFAAttempts, time.Now()).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", ErrMFAChallengeInvalid
+	}
+	return userID, err
+}
+
+func (p *PostgresMFAStore) CompleteChallenge(ctx context.Context, tokenHash string) error {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMFAChallengeInvalid
+	}
+	return nil
+}
+
+func (p *PostgresMFAStore) DeleteExpiredChallenges(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE expires_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeUsername trims s and converts it to Unicode NFKC, so visually
+// identical names such as "ｊｏｈｎ" and "john" are stored the same way
+func NormalizeUsername(s string) string {
+	return norm.NFKC.String(strings.TrimSpace(s))
+}
+
+// NormalizeEmail trims s, converts it to NFKC and lowercases the domain,
+// which is case-insensitive. The local part is kept as entered.
+func NormalizeEmail(s string) string {
+	s = norm.NFKC.String(strings.TrimSpace(s))
+	if at := strings.LastIndexByte(s, '@'); at >= 0 {
+		s = s[:at] + strings.ToLower(s[at:])
+	}
+	return s
+}
+
+// FoldKey returns the key usernames and emails are unique by: s case-folded
+// and normalized, so "John" and "JOHN" cannot both be registered
+func FoldKey(s string) string {
+	return norm.NFKC.String(cases.Fold().String(norm.NFKC.String(s)))
+}
+
+// Normalize applies NormalizeUsername and NormalizeEmail to u
+func (u *User) Normalize() {
+	u.Username = NormalizeUsername(u.Username)
+	u.Email = NormalizeEmail(u.Email)
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFoldKey(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"John", "john"},
+		{"JOHN@Example.COM", "john@example.com"},
+		{"Straße", "strasse"},
+		{"STRASSE", "strasse"},
+		{"ＪＯＨＮ", "john"},
+		{"ǅemal", "džemal"},
+		{"ΣΊΣΥΦΟΣ", "σίσυφοσ"},
+		{"José", "josé"},
+	}
+	for _, tt := range tests {
+		if got := FoldKey(tt.in); got != tt.want {
+			t.Errorf("FoldKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// RefoldKeys only rewrites non-ASCII names: for ASCII, FoldKey is what SQL
+// lower() backfilled
+func TestFoldKeyASCII(t *testing.T) {
+	var b strings.Builder
+	for c := byte(1); c < 0x80; c++ {
+		b.WriteByte(c)
+	}
+	ascii := b.String()
+	if got, want := FoldKey(ascii), strings.ToLower(ascii); got != want {
+		t.Errorf("FoldKey(%q) = %q, want %q", ascii, got, want)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	u := User{Username: "  ｊｏｈｎ ", Email: " John.Doe@EXAMPLE.com "}
+	u.Normalize()
+	if u.Username != "john" {
+		t.Errorf("Username = %q, want john", u.Username)
+	}
+	if u.Email != "John.Doe@example.com" {
+		t.Errorf("Email = %q, want the local part kept and the domain lowercased", u.Email)
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrPasskeyNotFound is returned when a user has no passkey with the
+	// given credential ID
+	ErrPasskeyNotFound = errors.New("passkey not found")
+	// ErrPasskeyExists is returned when registering a credential ID that is
+	// already stored
+	ErrPasskeyExists = errors.New("passkey already registered")
+	// ErrPasskeyCeremonyInvalid is returned when a ceremony token is unknown,
+	// expired or already used
+	ErrPasskeyCeremonyInvalid = errors.New("passkey ceremony invalid or expired")
+)
+
+// Passkey ceremony kinds. An enrollment is handed to an admin who must use a
+// passkey but has none once they passed the password step of a sign-in; it
+// only starts an enrollment registration, whose completion signs them in.
+const (
+	PasskeyRegistration           = "registration"
+	PasskeyLogin                  = "login"
+	PasskeyEnrollment             = "enrollment"
+	PasskeyEnrollmentRegistration = "enrollment_create"
+)
+
+// Passkey is a WebAuthn credential of a user
+type Passkey struct {
+	ID              []byte
+	UserID          string
+	Name            string
+	PublicKey       []byte
+	AttestationType string
+	Transports      []string
+	AAGUID          []byte
+	SignCount       uint32
+	CloneWarning    bool
+	BackupEligible  bool
+	BackupState     bool
+	CreatedAt       time.Time
+	LastUsedAt      *time.Time
+}
+
+// PasskeyCeremony is a registration or sign-in waiting for the response of
+// the authenticator. Data is the serialized WebAuthn session holding the
+// challenge; UserID is empty for sign-ins. Only the SHA-256 hash of the
+// token is stored.
+type PasskeyCeremony struct {
+	TokenHash string
+	Kind      string
+	UserID    string
+	Data      string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// PasskeyStore persists passkeys and pending passkey ceremonies
+type PasskeyStore interface {
+	// List returns the passkeys of a user, oldest first
+	List(ctx context.Context, userID string) ([]Passkey, error)
+	// Count returns how many passkeys a user has
+	Count(ctx context.Context, userID string) (int, error)
+	// Create stores p. It returns ErrPasskeyExists if the credential ID is
+	// already registered, to this or another user.
+	Create(ctx context.Context, p Passkey) error
+	// Use records a sign-in with a passkey, with the signature counter and
+	// backup state the authenticator reported
+	Use(ctx context.Context, id []byte, signCount uint32, backupState bool) error
+	// MarkCloned flags a passkey whose signature counter did not increase.
+	// Flagged passkeys are refused from then on.
+	MarkCloned(ctx context.Context, id []byte) error
+	// Delete removes a passkey of a user. It returns ErrPasskeyNotFound if
+	// the user has no passkey with that ID.
+	Delete(ctx context.Context, userID string, id []byte) error
+	// CreateCeremony stores c
+	CreateCeremony(ctx context.Context, c PasskeyCeremony) error
+	// TakeCeremony deletes and returns the pending ceremony of the given
+	// kind with the given token hash, so each ceremony is completed at most
+	// once
+	TakeCeremony(ctx context.Context, tokenHash, kind string) (PasskeyCeremony, error)
+	// DeleteExpiredCeremonies removes ceremonies that expired before cutoff
+	DeleteExpiredCeremonies(ctx context.Context, cutoff time.Time) (int64, error)
+}
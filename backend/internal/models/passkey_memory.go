@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryPasskeyStore is a PasskeyStore kept in process memory, for tests and
+// development without a database. It is safe for concurrent use.
+type MemoryPasskeyStore struct {
+	mu sync.Mutex
+	// passkeys are keyed by credential ID
+	passkeys   map[string]Passkey
+	ceremonies map[string]PasskeyCeremony
+	now        func() time.Time
+}
+
+// NewMemoryPasskeyStore returns an empty in-memory store
+func NewMemoryPasskeyStore() *MemoryPasskeyStore {
+	return &MemoryPasskeyStore{passkeys: map[string]Passkey{}, ceremonies: map[string]PasskeyCeremony{}, now: time.Now}
+}
+
+func (m *MemoryPasskeyStore) List(ctx context.Context, userID string) ([]Passkey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	passkeys := []Passkey{}
+	for _, k := range m.passkeys {
+		if k.UserID == userID {
+			passkeys = append(passkeys, k)
+		}
+	}
+	sort.Slice(passkeys, func(i, j int) bool { return passkeys[i].CreatedAt.Before(passkeys[j].CreatedAt) })
+	return passkeys, nil
+}
+
+func (m *MemoryPasskeyStore) Count(ctx context.Context, userID string) (int, error) {
+	passkeys, err := m.List(ctx, userID)
+	return len(passkeys), err
+}
+
+func (m *MemoryPasskeyStore) Create(ctx context.Context, k Passkey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.passkeys[string(k.ID)]; ok {
+		return ErrPasskeyExists
+	}
+	m.passkeys[string(k.ID)] = k
+	return nil
+}
+
+func (m *MemoryPasskeyStore) Use(ctx context.Context, id []byte, signCount uint32, backupState bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if k, ok := m.passkeys[string(id)]; ok {
+		now := m.now()
+		k.SignCount = signCount
+		k.BackupState = backupState
+		k.LastUsedAt = &now
+		m.passkeys[string(id)] = k
+	}
+	return nil
+}
+
+func (m *MemoryPasskeyStore) MarkCloned(ctx context.Context, id []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if k, ok := m.passkeys[string(id)]; ok {
+		k.CloneWarning = true
+		m.passkeys[string(id)] = k
+	}
+	return nil
+}
+
+func (m *MemoryPasskeyStore) Delete(ctx context.Context, userID string, id []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.passkeys[string(id)]
+	if !ok || k.UserID != userID {
+		return ErrPasskeyNotFound
+	}
+	delete(m.passkeys, string(id))
+	return nil
+}
+
+func (m *MemoryPasskeyStore) CreateCeremony(ctx context.Context, c PasskeyCeremony) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ceremonies[c.TokenHash] = c
+	return nil
+}
+
+func (m *MemoryPasskeyStore) TakeCeremony(ctx context.Context, tokenHash, kind string) (PasskeyCeremony, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.ceremonies[tokenHash]
+	if !ok || c.Kind != kind || !m.now().Before(c.ExpiresAt) {
+		return PasskeyCeremony{}, ErrPasskeyCeremonyInvalid
+	}
+	delete(m.ceremonies, tokenHash)
+	return c, nil
+}
+
+func (m *MemoryPasskeyStore) DeleteExpiredCeremonies(ctx context.Context, cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for hash, c := range m.ceremonies {
+		if c.ExpiresAt.Before(cutoff) {
+			delete(m.ceremonies, hash)
+			n++
+		}
+	}
+	return n, nil
+}
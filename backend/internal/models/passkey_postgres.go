@@ -0,0 +1,128 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgresPasskeyStore is a PasskeyStore backed by the webauthn_credentials
+// and webauthn_ceremonies tables
+type PostgresPasskeyStore struct {
+	db *sql.DB
+}
+
+// NewPostgresPasskeyStore returns a store using db
+func NewPostgresPasskeyStore(db *sql.DB) *PostgresPasskeyStore {
+	return &PostgresPasskeyStore{db: db}
+}
+
+func (p *PostgresPasskeyStore) List(ctx context.Context, userID string) ([]Passkey, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, user_id, name, public_key, attestation_type, transports, aaguid, sign_count,
+			clone_warning, backup_eligible, backup_state, created_at, last_used_at
+		FROM webauthn_credentials WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys := []Passkey{}
+	for rows.Next() {
+		var k Passkey
+		var signCount int64
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.PublicKey, &k.AttestationType, pq.Array(&k.Transports),
+			&k.AAGUID, &signCount, &k.CloneWarning, &k.BackupEligible, &k.BackupState, &k.CreatedAt, &lastUsedAt); err != nil {
+			return nil, err
+		}
+		k.SignCount = uint32(signCount)
+		if lastUsedAt.Valid {
+			k.LastUsedAt = &lastUsedAt.Time
+		}
+		passkeys = append(passkeys, k)
+	}
+	return passkeys, rows.Err()
+}
+
+func (p *PostgresPasskeyStore) Count(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := p.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = $1`, userID).Scan(&n)
+	return n, err
+}
+
+func (p *PostgresPasskeyStore) Create(ctx context.Context, k Passkey) error {
+	query := `
+		INSERT INTO webauthn_credentials (id, user_id, name, public_key, attestation_type, transports, aaguid,
+			sign_count, backup_eligible, backup_state, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := p.db.ExecContext(ctx, query, k.ID, k.UserID, k.Name, k.PublicKey, k.AttestationType, pq.Array(k.Transports),
+		k.AAGUID, int64(k.SignCount), k.BackupEligible, k.BackupState, k.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrPasskeyExists
+	}
+	return err
+}
+
+func (p *PostgresPasskeyStore) Use(ctx context.Context, id []byte, signCount uint32, backupState bool) error {
+	_, err := p.db.ExecContext(ctx, `
+		UPDATE webauthn_credentials SET sign_count = $1, backup_state = $2, last_used_at = $3
+		WHERE id = $4
+	`, int64(signCount), backupState, time.Now(), id)
+	return err
+}
+
+func (p *PostgresPasskeyStore) MarkCloned(ctx context.Context, id []byte) error {
+	_, err := p.db.ExecContext(ctx, `UPDATE webauthn_credentials SET clone_warning = TRUE WHERE id = $1`, id)
+	return err
+}
+
+func (p *PostgresPasskeyStore) Delete(ctx context.Context, userID string, id []byte) error {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM webauthn_credentials WHERE user_id = $1 AND id = $2`, userID, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
+
+func (p *PostgresPasskeyStore) CreateCeremony(ctx context.Context, c PasskeyCeremony) error {
+	query := `
+		INSERT INTO webauthn_ceremonies (token_hash, kind, user_id, data, expires_at, created_at)
+		VALUES ($1, $2, NULLIF($3, '')::integer, $4, $5, $6)
+	`
+
+	_, err := p.db.ExecContext(ctx, query, c.TokenHash, c.Kind, c.UserID, c.Data, c.ExpiresAt, c.CreatedAt)
+	return err
+}
+
+func (p *PostgresPasskeyStore) TakeCeremony(ctx context.Context, tokenHash, kind string) (PasskeyCeremony, error) {
+	var c PasskeyCeremony
+	var userID sql.NullString
+	err := p.db.QueryRowContext(ctx, `
+		DELETE FROM webauthn_ceremonies
+		WHERE token_hash = $1 AND kind = $2 AND expires_at > $3
+		RETURNING token_hash, kind, user_id, data, expires_at, created_at
+	`, tokenHash, kind, time.Now()).Scan(&c.TokenHash, &c.Kind, &userID, &c.Data, &c.ExpiresAt, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return PasskeyCeremony{}, ErrPasskeyCeremonyInvalid
+	}
+	c.UserID = userID.String
+	return c, err
+}
+
+func (p *PostgresPasskeyStore) DeleteExpiredCeremonies(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM webauthn_ceremonies WHERE expires_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
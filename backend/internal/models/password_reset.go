@@ -0,0 +1,34 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPasswordResetInvalid is returned when a reset token is unknown, used or
+// expired, or its user can no longer sign in
+var ErrPasswordResetInvalid = errors.New("password reset invalid or expired")
+
+// PasswordReset is a single-use token allowing a user to set a new password.
+// Only the SHA-256 hash of the token is stored.
+type PasswordReset struct {
+	TokenHash string
+	UserID    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// PasswordResetStore persists password reset tokens
+type PasswordResetStore interface {
+	// Create stores r and discards any earlier unused reset of the same
+	// user, so only the most recent link works
+	Create(ctx context.Context, r PasswordReset) error
+	// Reset redeems the reset with the given token hash: it is marked used,
+	// the password of its user is replaced by passwordHash and any other
+	// outstanding reset of the user is discarded. Only active users can
+	// reset their password; invited users accept their invitation instead.
+	Reset(ctx context.Context, tokenHash, passwordHash string) (User, error)
+	// DeleteExpired removes resets that expired before cutoff
+	DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error)
+}
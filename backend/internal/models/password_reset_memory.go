@@ -0,0 +1,45 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// MemoryPasswordResetStore is a PasswordResetStore kept in process memory,
+// for tests and development without a database. Resetting replaces the
+// password of the user in users. It is safe for concurrent use.
+type MemoryPasswordResetStore struct {
+	table *memoryTokenTable
+	users *MemoryUserRepository
+}
+
+// NewMemoryPasswordResetStore returns an empty in-memory store for the users
+// kept in users
+func NewMemoryPasswordResetStore(users *MemoryUserRepository) *MemoryPasswordResetStore {
+	return &MemoryPasswordResetStore{table: newMemoryTokenTable(ErrPasswordResetInvalid), users: users}
+}
+
+func (m *MemoryPasswordResetStore) Create(ctx context.Context, r PasswordReset) error {
+	m.table.create(r.TokenHash, memoryToken{UserID: r.UserID, ExpiresAt: r.ExpiresAt})
+	return nil
+}
+
+func (m *MemoryPasswordResetStore) Reset(ctx context.Context, tokenHash, passwordHash string) (User, error) {
+	return m.table.redeem(tokenHash, func(token memoryToken) (User, error) {
+		user, err := m.users.modify(token.UserID, func(u *User, now time.Time) error {
+			if u.Status != UserStatusActive {
+				return ErrUserNotFound
+			}
+			u.Password = passwordHash
+			return nil
+		})
+		if err == nil {
+			m.table.discardUnused(token.UserID)
+		}
+		return user, err
+	})
+}
+
+func (m *MemoryPasswordResetStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	return m.table.deleteExpired(cutoff), nil
+}
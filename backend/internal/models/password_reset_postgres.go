@@ -0,0 +1,39 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PostgresPasswordResetStore is a PasswordResetStore backed by the
+// password_resets table
+type PostgresPasswordResetStore struct {
+	table tokenTable
+}
+
+// NewPostgresPasswordResetStore returns a store using db
+func NewPostgresPasswordResetStore(db *sql.DB) *PostgresPasswordResetStore {
+	return &PostgresPasswordResetStore{table: tokenTable{db: db, name: "password_resets", invalid: ErrPasswordResetInvalid}}
+}
+
+func (p *PostgresPasswordResetStore) Create(ctx context.Context, r PasswordReset) error {
+	return p.table.create(ctx, r.TokenHash, r.UserID, r.ExpiresAt, r.CreatedAt)
+}
+
+func (p *PostgresPasswordResetStore) Reset(ctx context.Context, tokenHash, passwordHash string) (User, error) {
+	return p.table.redeem(ctx, tokenHash, func(tx *sql.Tx, now time.Time, userID string) (User, error) {
+		user, err := scanUser(tx.QueryRowContext(ctx, `
+			UPDATE users SET password = $1, updated_at = $2
+			WHERE id = $3 AND status = $4
+			RETURNING `+userColumns, passwordHash, now, userID, UserStatusActive))
+		if err != nil {
+			return User{}, err
+		}
+		return user, p.table.discardUnused(ctx, tx, userID)
+	})
+}
+
+func (p *PostgresPasswordResetStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	return p.table.deleteExpired(ctx, cutoff)
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrRefreshTokenInvalid is returned for unknown, expired or revoked refresh tokens
+var ErrRefreshTokenInvalid = errors.New("refresh token invalid")
+
+// ErrRefreshTokenReused is returned when an already rotated refresh token is
+// presented again. The whole token family has been revoked by then.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// RefreshToken is a stored refresh token. Only the SHA-256 hash of the token
+// is persisted. Tokens descending from the same login share a FamilyID.
+type RefreshToken struct {
+	ID        string       `json:"id" db:"id"`
+	UserID    string       `json:"user_id" db:"user_id"`
+	FamilyID  string       `json:"family_id" db:"family_id"`
+	TokenHash string       `json:"-" db:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at" db:"expires_at"`
+	UsedAt    sql.NullTime `json:"-" db:"used_at"`
+	RevokedAt sql.NullTime `json:"-" db:"revoked_at"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+}
+
+// RefreshTokenStore persists refresh tokens
+type RefreshTokenStore interface {
+	// Create stores a new refresh token and sets its ID and CreatedAt
+	Create(ctx context.Context, token *RefreshToken) error
+	// Use marks the token with the given hash as used and returns it. It
+	// returns ErrRefreshTokenInvalid for unknown, expired or revoked tokens.
+	// Presenting a token that was already used revokes every token in its
+	// family and returns ErrRefreshTokenReused.
+	Use(ctx context.Context, tokenHash string) (RefreshToken, error)
+	// RevokeFamily revokes every active token descending from the same login
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeUser revokes every active refresh token of a user
+	RevokeUser(ctx context.Context, userID string) error
+	// RevokeOthers revokes every active refresh token of a user outside
+	// the given family
+	RevokeOthers(ctx context.Context, userID, keepFamilyID string) error
+	// DeleteExpired removes tokens that expired before cutoff
+	DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error)
+}
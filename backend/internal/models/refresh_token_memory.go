@@ -0,0 +1,101 @@
+package models
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryRefreshTokenStore is a RefreshTokenStore kept in process memory, for
+// tests and development without a database. It is safe for concurrent use.
+type MemoryRefreshTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]RefreshToken
+	nextID int
+	now    func() time.Time
+}
+
+// NewMemoryRefreshTokenStore returns an empty in-memory store
+func NewMemoryRefreshTokenStore() *MemoryRefreshTokenStore {
+	return &MemoryRefreshTokenStore{tokens: map[string]RefreshToken{}, nextID: 1, now: time.Now}
+}
+
+func (m *MemoryRefreshTokenStore) Create(ctx context.Context, token *RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token.ID = strconv.Itoa(m.nextID)
+	m.nextID++
+	token.CreatedAt = m.now()
+	m.tokens[token.TokenHash] = *token
+	return nil
+}
+
+func (m *MemoryRefreshTokenStore) Use(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	}
+	if token.UsedAt.Valid {
+		m.revoke(func(t RefreshToken) bool { return t.FamilyID == token.FamilyID })
+		return token, ErrRefreshTokenReused
+	}
+	if token.RevokedAt.Valid {
+		return token, ErrRefreshTokenInvalid
+	}
+
+	now := m.now()
+	token.UsedAt.Time, token.UsedAt.Valid = now, true
+	m.tokens[tokenHash] = token
+	if now.After(token.ExpiresAt) {
+		return token, ErrRefreshTokenInvalid
+	}
+	return token, nil
+}
+
+func (m *MemoryRefreshTokenStore) RevokeFamily(ctx context.Context, familyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoke(func(t RefreshToken) bool { return t.FamilyID == familyID })
+	return nil
+}
+
+func (m *MemoryRefreshTokenStore) RevokeUser(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoke(func(t RefreshToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (m *MemoryRefreshTokenStore) RevokeOthers(ctx context.Context, userID, keepFamilyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoke(func(t RefreshToken) bool { return t.UserID == userID && t.FamilyID != keepFamilyID })
+	return nil
+}
+
+func (m *MemoryRefreshTokenStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for hash, t := range m.tokens {
+		if t.ExpiresAt.Before(cutoff) {
+			delete(m.tokens, hash)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// revoke revokes the active tokens matching match. The caller holds m.mu.
+func (m *MemoryRefreshTokenStore) revoke(match func(RefreshToken) bool) {
+	now := m.now()
+	for hash, t := range m.tokens {
+		if !t.RevokedAt.Valid && match(t) {
+			t.RevokedAt.Time, t.RevokedAt.Valid = now, true
+			m.tokens[hash] = t
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PostgresRefreshTokenStore is a RefreshTokenStore backed by the
+// refresh_tokens table
+type PostgresRefreshTokenStore struct {
+	db *sql.DB
+}
+
+// NewPostgresRefreshTokenStore returns a store using db
+func NewPostgresRefreshTokenStore(db *sql.DB) *PostgresRefreshTokenStore {
+	return &PostgresRefreshTokenStore{db: db}
+}
+
+func (p *PostgresRefreshTokenStore) Create(ctx context.Context, token *RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	return p.db.QueryRowContext(ctx,
+		query,
+		token.UserID,
+		token.FamilyID,
+		token.TokenHash,
+		token.ExpiresAt,
+		time.Now(),
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (p *PostgresRefreshTokenStore) Use(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	var token RefreshToken
+
+	query := `
+		UPDATE refresh_tokens
+		SET used_at = $1
+		WHERE token_hash = $2 AND used_at IS NULL AND revoked_at IS NULL
+		RETURNING id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at
+	`
+
+	now := time.Now()
+	err := p.db.QueryRowContext(ctx, query, now, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return p.handleMiss(ctx, tokenHash)
+	}
+	if err != nil {
+		return token, err
+	}
+
+	if now.After(token.ExpiresAt) {
+		return token, ErrRefreshTokenInvalid
+	}
+
+	return token, nil
+}
+
+// handleMiss revokes the family of a token that exists but was already used,
+// returning the token so callers can see its family. A token that was
+// revoked without being used, by a logout for example, is merely invalid.
+func (p *PostgresRefreshTokenStore) handleMiss(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	var token RefreshToken
+	err := p.db.QueryRowContext(ctx,
+		`SELECT id, user_id, family_id, used_at, revoked_at FROM refresh_tokens WHERE token_hash = $1`,
+		tokenHash,
+	).Scan(&token.ID, &token.UserID, &token.FamilyID, &token.UsedAt, &token.RevokedAt)
+	if err == sql.ErrNoRows {
+		return token, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return token, err
+	}
+	if !token.UsedAt.Valid {
+		return token, ErrRefreshTokenInvalid
+	}
+
+	if err := p.RevokeFamily(ctx, token.FamilyID); err != nil {
+		return token, err
+	}
+	return token, ErrRefreshTokenReused
+}
+
+func (p *PostgresRefreshTokenStore) RevokeFamily(ctx context.Context, familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL`
+
+	_, err := p.db.ExecContext(ctx, query, time.Now(), familyID)
+	return err
+}
+
+func (p *PostgresRefreshTokenStore) RevokeUser(ctx context.Context, userID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+
+	_, err := p.db.ExecContext(ctx, query, time.Now(), userID)
+	return err
+}
+
+func (p *PostgresRefreshTokenStore) RevokeOthers(ctx context.Context, userID, keepFamilyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND family_id <> $3 AND revoked_at IS NULL`
+
+	_, err := p.db.ExecContext(ctx, query, time.Now(), userID, keepFamilyID)
+	return err
+}
+
+func (p *PostgresRefreshTokenStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := p.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
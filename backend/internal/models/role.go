@@ -0,0 +1,93 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Names of the built-in roles
+const (
+	AdminRole = "admin"
+	UserRole  = "user"
+)
+
+// ErrRoleNotFound is returned when a role does not exist
+var ErrRoleNotFound = errors.New("role not found")
+
+// ErrRoleExists is returned when a role name is already taken
+var ErrRoleExists = errors.New("role already exists")
+
+// ErrBuiltInRole is returned when deleting or renaming a built-in role
+var ErrBuiltInRole = errors.New("built-in roles cannot be deleted or renamed")
+
+type Role struct {
+	ID          string    `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	BuiltIn     bool      `json:"built_in" db:"built_in"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type Permission struct {
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+}
+
+// RoleRepository persists roles, the permissions they grant and the roles
+// assigned to users. Users without any assignment hold the built-in user
+// role. Get, Update and Delete return ErrRoleNotFound for unknown roles.
+type RoleRepository interface {
+	// Permissions returns every known permission, ordered by name
+	Permissions(ctx context.Context) ([]Permission, error)
+	// List returns every role with its permissions
+	List(ctx context.Context) ([]Role, error)
+	// Get returns a role with its permissions
+	Get(ctx context.Context, id string) (Role, error)
+	// Create adds a custom role and sets its ID and timestamps. It returns
+	// ErrRoleExists if the name is taken.
+	Create(ctx context.Context, role *Role) error
+	// Update changes a role's name, description and permissions. Built-in
+	// roles cannot be renamed.
+	Update(ctx context.Context, role Role) (Role, error)
+	// Delete removes a custom role and its assignments
+	Delete(ctx context.Context, id string) error
+	// Grants maps every role name to the permissions it grants
+	Grants(ctx context.Context) (map[string][]string, error)
+	// UserRoles returns the names of the roles of a user, ordered by name
+	UserRoles(ctx context.Context, userID string) ([]string, error)
+	// SetUserRoles replaces the roles of a user by name and keeps the
+	// user's admin flag in sync with membership of the admin role. It
+	// returns ErrRoleNotFound, changing nothing, if a name is unknown.
+	SetUserRoles(ctx context.Context, userID string, names []string) error
+}
+
+// SetUserAdmin grants or revokes the admin role of a user, keeping their
+// other roles
+func SetUserAdmin(ctx context.Context, roles RoleRepository, userID string, isAdmin bool) error {
+	names, err := roles.UserRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	updated := []string{}
+	for _, name := range names {
+		if name != AdminRole {
+			updated = append(updated, name)
+		}
+	}
+	if isAdmin {
+		updated = append(updated, AdminRole)
+	}
+	return roles.SetUserRoles(ctx, userID, updated)
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
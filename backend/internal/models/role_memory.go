@@ -0,0 +1,219 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultPermissions are the permissions created by the migrations
+var defaultPermissions = []Permission{
+	{Name: "mfa:reset", Description: "Remove the two-factor authentication of other users"},
+	{Name: "roles:assign", Description: "Assign roles to users"},
+	{Name: "roles:manage", Description: "Create, update and delete roles"},
+	{Name: "stats:read", Description: "View dashboard statistics"},
+	{Name: "users:create", Description: "Create and invite users"},
+	{Name: "users:delete", Description: "Delete any user"},
+	{Name: "users:list", Description: "List all users"},
+	{Name: "users:read", Description: "Read any user"},
+	{Name: "users:read:own", Description: "Read your own user record"},
+	{Name: "users:update", Description: "Update any user"},
+	{Name: "users:update:own", Description: "Update your own user record"},
+}
+
+// MemoryRoleRepository is a RoleRepository kept in process memory, for tests
+// and development without a database. It starts with the built-in roles and
+// permissions of the migrations. It is safe for concurrent use.
+type MemoryRoleRepository struct {
+	mu       sync.RWMutex
+	roles    map[string]Role
+	assigned map[string]map[string]bool
+	nextID   int
+	users    *MemoryUserRepository
+	now      func() time.Time
+}
+
+// NewMemoryRoleRepository returns a repository holding the built-in roles.
+// The admin flag of users in users, if not nil, follows the admin role.
+func NewMemoryRoleRepository(users *MemoryUserRepository) *MemoryRoleRepository {
+	m := &MemoryRoleRepository{
+		roles:    map[string]Role{},
+		assigned: map[string]map[string]bool{},
+		nextID:   1,
+		users:    users,
+		now:      time.Now,
+	}
+
+	var all []string
+	for _, p := range defaultPermissions {
+		all = append(all, p.Name)
+	}
+	m.add(Role{Name: AdminRole, Description: "Full access to every resource", BuiltIn: true, Permissions: all})
+	m.add(Role{Name: UserRole, Description: "Default role of registered users", BuiltIn: true,
+		Permissions: []string{"users:read:own", "users:update:own"}})
+	return m
+}
+
+// add stores role under the next ID. The caller holds m.mu or has not
+// shared m yet.
+func (m *MemoryRoleRepository) add(role Role) Role {
+	role.ID = strconv.Itoa(m.nextID)
+	m.nextID++
+	role.CreatedAt = m.now()
+	role.UpdatedAt = role.CreatedAt
+	role.Permissions = sortedPermissions(role.Permissions)
+	m.roles[role.ID] = role
+	return role
+}
+
+func (m *MemoryRoleRepository) Permissions(ctx context.Context) ([]Permission, error) {
+	return append([]Permission(nil), defaultPermissions...), nil
+}
+
+func (m *MemoryRoleRepository) List(ctx context.Context) ([]Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var roles []Role
+	for _, role := range m.roles {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		a, _ := strconv.Atoi(roles[i].ID)
+		b, _ := strconv.Atoi(roles[j].ID)
+		return a < b
+	})
+	return roles, nil
+}
+
+func (m *MemoryRoleRepository) Get(ctx context.Context, id string) (Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	role, ok := m.roles[id]
+	if !ok {
+		return Role{}, ErrRoleNotFound
+	}
+	return role, nil
+}
+
+func (m *MemoryRoleRepository) Create(ctx context.Context, role *Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.byName(role.Name); ok {
+		return ErrRoleExists
+	}
+	role.BuiltIn = false
+	*role = m.add(*role)
+	return nil
+}
+
+func (m *MemoryRoleRepository) Update(ctx context.Context, role Role) (Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.roles[role.ID]
+	if !ok {
+		return role, ErrRoleNotFound
+	}
+	if existing.BuiltIn && existing.Name != role.Name {
+		return role, ErrBuiltInRole
+	}
+	if other, ok := m.byName(role.Name); ok && other.ID != role.ID {
+		return role, ErrRoleExists
+	}
+
+	existing.Name = role.Name
+	existing.Description = role.Description
+	existing.Permissions = sortedPermissions(role.Permissions)
+	existing.UpdatedAt = m.now()
+	m.roles[role.ID] = existing
+	return existing, nil
+}
+
+func (m *MemoryRoleRepository) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	role, ok := m.roles[id]
+	if !ok {
+		return ErrRoleNotFound
+	}
+	if role.BuiltIn {
+		return ErrBuiltInRole
+	}
+	delete(m.roles, id)
+	for _, ids := range m.assigned {
+		delete(ids, id)
+	}
+	return nil
+}
+
+func (m *MemoryRoleRepository) Grants(ctx context.Context) (map[string][]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	grants := map[string][]string{}
+	for _, role := range m.roles {
+		if len(role.Permissions) > 0 {
+			grants[role.Name] = append([]string(nil), role.Permissions...)
+		}
+	}
+	return grants, nil
+}
+
+func (m *MemoryRoleRepository) UserRoles(ctx context.Context, userID string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var names []string
+	for id := range m.assigned[userID] {
+		names = append(names, m.roles[id].Name)
+	}
+	if len(names) == 0 {
+		return []string{UserRole}, nil
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m *MemoryRoleRepository) SetUserRoles(ctx context.Context, userID string, names []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := map[string]bool{}
+	isAdmin := false
+	for _, name := range names {
+		role, ok := m.byName(name)
+		if !ok {
+			return ErrRoleNotFound
+		}
+		ids[role.ID] = true
+		isAdmin = isAdmin || name == AdminRole
+	}
+
+	m.assigned[userID] = ids
+	if m.users != nil {
+		m.users.setAdmin(userID, isAdmin)
+	}
+	return nil
+}
+
+// byName returns the role with the given name. The caller holds m.mu.
+func (m *MemoryRoleRepository) byName(name string) (Role, bool) {
+	for _, role := range m.roles {
+		if role.Name == name {
+			return role, true
+		}
+	}
+	return Role{}, false
+}
+
+// sortedPermissions returns a sorted copy of permissions without duplicates
+func sortedPermissions(permissions []string) []string {
+	seen := map[string]bool{}
+	sorted := []string{}
+	for _, p := range permissions {
+		if !seen[p] {
+			seen[p] = true
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,309 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgresRoleRepository is a RoleRepository backed by the roles,
+// role_permissions and user_roles tables
+type PostgresRoleRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRoleRepository returns a repository using db
+func NewPostgresRoleRepository(db *sql.DB) *PostgresRoleRepository {
+	return &PostgresRoleRepository{db: db}
+}
+
+func (p *PostgresRoleRepository) Permissions(ctx context.Context) ([]Permission, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []Permission
+	for rows.Next() {
+		var p Permission
+		if err := rows.Scan(&p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+func (p *PostgresRoleRepository) List(ctx context.Context) ([]Role, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.built_in, r.created_at, r.updated_at,
+			COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		GROUP BY r.id
+		ORDER BY r.id
+	`
+
+	rows, err := p.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []Role
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(
+			&role.ID,
+			&role.Name,
+			&role.Description,
+			&role.BuiltIn,
+			&role.CreatedAt,
+			&role.UpdatedAt,
+			pq.Array(&role.Permissions),
+		); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// parseRoleID converts a role ID to the type of roles.id. Like user IDs, IDs
+// that are not integers name no role.
+func parseRoleID(id string) (int32, bool) {
+	n, err := strconv.ParseInt(id, 10, 32)
+	return int32(n), err == nil
+}
+
+func (p *PostgresRoleRepository) Get(ctx context.Context, id string) (Role, error) {
+	n, ok := parseRoleID(id)
+	if !ok {
+		return Role{}, ErrRoleNotFound
+	}
+	query := `
+		SELECT r.id, r.name, r.description, r.built_in, r.created_at, r.updated_at,
+			COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		WHERE r.id = $1
+		GROUP BY r.id
+	`
+
+	var role Role
+	err := p.db.QueryRowContext(ctx, query, n).Scan(
+		&role.ID,
+		&role.Name,
+		&role.Description,
+		&role.BuiltIn,
+		&role.CreatedAt,
+		&role.UpdatedAt,
+		pq.Array(&role.Permissions),
+	)
+	if err == sql.ErrNoRows {
+		return role, ErrRoleNotFound
+	}
+	return role, err
+}
+
+func (p *PostgresRoleRepository) Create(ctx context.Context, role *Role) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO roles (name, description, built_in, created_at, updated_at)
+		VALUES ($1, $2, FALSE, $3, $3)
+		RETURNING id, created_at, updated_at`,
+		role.Name, role.Description, now,
+	).Scan(&role.ID, &role.CreatedAt, &role.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrRoleExists
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := replaceRolePermissions(ctx, tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (p *PostgresRoleRepository) Update(ctx context.Context, role Role) (Role, error) {
+	if _, ok := parseRoleID(role.ID); !ok {
+		return role, ErrRoleNotFound
+	}
+	existing, err := p.Get(ctx, role.ID)
+	if err != nil {
+		return role, err
+	}
+	if existing.BuiltIn && existing.Name != role.Name {
+		return role, ErrBuiltInRole
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return role, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE roles SET name = $1, description = $2, updated_at = $3 WHERE id = $4`,
+		role.Name, role.Description, time.Now(), role.ID,
+	)
+	if isUniqueViolation(err) {
+		return role, ErrRoleExists
+	}
+	if err != nil {
+		return role, err
+	}
+
+	if err := replaceRolePermissions(ctx, tx, role.ID, role.Permissions); err != nil {
+		return role, err
+	}
+	if err := tx.Commit(); err != nil {
+		return role, err
+	}
+	return p.Get(ctx, role.ID)
+}
+
+func (p *PostgresRoleRepository) Delete(ctx context.Context, id string) error {
+	if _, ok := parseRoleID(id); !ok {
+		return ErrRoleNotFound
+	}
+	role, err := p.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return ErrBuiltInRole
+	}
+
+	_, err = p.db.ExecContext(ctx, `DELETE FROM roles WHERE id = $1`, id)
+	return err
+}
+
+func replaceRolePermissions(ctx context.Context, tx *sql.Tx, roleID string, permissions []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return err
+	}
+	for _, p := range permissions {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO role_permissions (role_id, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			roleID, p,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PostgresRoleRepository) Grants(ctx context.Context) (map[string][]string, error) {
+	query := `
+		SELECT r.name, rp.permission
+		FROM roles r
+		JOIN role_permissions rp ON rp.role_id = r.id
+	`
+
+	rows, err := p.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := map[string][]string{}
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, err
+		}
+		grants[role] = append(grants[role], permission)
+	}
+	return grants, rows.Err()
+}
+
+func (p *PostgresRoleRepository) UserRoles(ctx context.Context, userID string) ([]string, error) {
+	query := `
+		SELECT r.name
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1
+		ORDER BY r.name
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(names) == 0 {
+		names = []string{UserRole}
+	}
+	return names, nil
+}
+
+func (p *PostgresRoleRepository) SetUserRoles(ctx context.Context, userID string, names []string) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	isAdmin := false
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO user_roles (user_id, role_id)
+			SELECT $1, id FROM roles WHERE name = $2
+			ON CONFLICT DO NOTHING`,
+			userID, name,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrRoleNotFound
+		}
+		if name == AdminRole {
+			isAdmin = true
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET is_admin = $1, updated_at = $2 WHERE id = $3`,
+		isAdmin, time.Now(), userID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
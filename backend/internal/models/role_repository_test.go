@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+// testNonNumericRoleID checks that repo treats IDs that are not integers as
+// unknown roles, as the RoleRepository contract requires
+func testNonNumericRoleID(t *testing.T, repo RoleRepository) {
+	ctx := context.Background()
+	for _, id := range []string{"abc", "", "1.5", "1; DROP TABLE roles", "99999999999"} {
+		if _, err := repo.Get(ctx, id); !errors.Is(err, ErrRoleNotFound) {
+			t.Errorf("Get(%q) error = %v, want ErrRoleNotFound", id, err)
+		}
+		if _, err := repo.Update(ctx, Role{ID: id, Name: "support"}); !errors.Is(err, ErrRoleNotFound) {
+			t.Errorf("Update(%q) error = %v, want ErrRoleNotFound", id, err)
+		}
+		if err := repo.Delete(ctx, id); !errors.Is(err, ErrRoleNotFound) {
+			t.Errorf("Delete(%q) error = %v, want ErrRoleNotFound", id, err)
+		}
+	}
+}
+
+func TestMemoryRoleRepositoryNonNumericID(t *testing.T) {
+	testNonNumericRoleID(t, NewMemoryRoleRepository(NewMemoryUserRepository()))
+}
+
+// The database is unreachable, so the repository passes only if it rejects
+// the IDs without querying
+func TestPostgresRoleRepositoryNonNumericID(t *testing.T) {
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	testNonNumericRoleID(t, NewPostgresRoleRepository(db))
+}
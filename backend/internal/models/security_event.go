@@ -0,0 +1,82 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Security events
+const (
+	EventPasswordReset        = "password_reset"
+	EventMFAEnabled           = "mfa_enabled"
+	EventMFADisabled          = "mfa_disabled"
+	EventMFAReset             = "mfa_reset"
+	EventRecoveryCodeUsed     = "mfa_recovery_code_used"
+	EventRecoveryCodesRenewed = "mfa_recovery_codes_renewed"
+	EventPasskeyAdded         = "passkey_added"
+	EventPasskeyRemoved       = "passkey_removed"
+	EventPasskeyCloned        = "passkey_clone_warning"
+)
+
+// SecurityEvent is an entry of the security log of a user
+type SecurityEvent struct {
+	UserID    string
+	Event     string
+	IP        string
+	UserAgent string
+	CreatedAt time.Time
+}
+
+// SecurityLog records security-relevant changes to accounts
+type SecurityLog interface {
+	// RecordSecurityEvent adds an entry to the security log of a user
+	RecordSecurityEvent(ctx context.Context, userID, event, ip, userAgent string) error
+}
+
+// PostgresSecurityLog is a SecurityLog backed by the security_events table
+type PostgresSecurityLog struct {
+	db *sql.DB
+}
+
+// NewPostgresSecurityLog returns a security log using db
+func NewPostgresSecurityLog(db *sql.DB) *PostgresSecurityLog {
+	return &PostgresSecurityLog{db: db}
+}
+
+func (p *PostgresSecurityLog) RecordSecurityEvent(ctx context.Context, userID, event, ip, userAgent string) error {
+	query := `
+		INSERT INTO security_events (user_id, event, ip, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := p.db.ExecContext(ctx, query, userID, event, ip, userAgent, time.Now())
+	return err
+}
+
+// MemorySecurityLog is a SecurityLog kept in process memory, for tests and
+// development without a database. It is safe for concurrent use.
+type MemorySecurityLog struct {
+	mu     sync.Mutex
+	events []SecurityEvent
+}
+
+// NewMemorySecurityLog returns an empty in-memory security log
+func NewMemorySecurityLog() *MemorySecurityLog {
+	return &MemorySecurityLog{}
+}
+
+func (m *MemorySecurityLog) RecordSecurityEvent(ctx context.Context, userID, event, ip, userAgent string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, SecurityEvent{UserID: userID, Event: event, IP: ip, UserAgent: userAgent, CreatedAt: time.Now()})
+	return nil
+}
+
+// Events returns the recorded events, oldest first
+func (m *MemorySecurityLog) Events() []SecurityEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]SecurityEvent(nil), m.events...)
+}
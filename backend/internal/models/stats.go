@@ -0,0 +1,34 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// DashboardStats are aggregate figures about users. JSON names match the
+// frontend dashboard.
+type DashboardStats struct {
+	TotalUsers     int           `json:"totalUsers"`
+	AdminUsers     int           `json:"adminUsers"`
+	RegularUsers   int           `json:"regularUsers"`
+	ActiveUsers    int           `json:"activeUsers"`
+	WindowDays     int           `json:"windowDays"`
+	SignupsPerDay  []SignupCount `json:"signupsPerDay"`
+	SignupsPerWeek []SignupCount `json:"signupsPerWeek"`
+	GeneratedAt    time.Time     `json:"generatedAt"`
+}
+
+// SignupCount is the number of users created in the period starting at Period
+type SignupCount struct {
+	Period time.Time `json:"period"`
+	Count  int       `json:"count"`
+}
+
+// StatsRepository computes the dashboard statistics
+type StatsRepository interface {
+	// DashboardStats computes user totals, signups per day and week and the
+	// number of users who logged in during the last windowDays days. Signups
+	// are counted for the last windowDays days and the last windowDays/7
+	// weeks, rounded up, oldest first and including periods without signups.
+	DashboardStats(ctx context.Context, windowDays int) (DashboardStats, error)
+}
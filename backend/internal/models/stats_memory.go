@@ -0,0 +1,81 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// MemoryStatsRepository computes the dashboard statistics from in-memory
+// users and login history, for tests and development without a database.
+// Days and weeks, which start on Monday like in Postgres, are those of the
+// local time zone.
+type MemoryStatsRepository struct {
+	users  *MemoryUserRepository
+	logins *MemoryLoginHistory
+	now    func() time.Time
+}
+
+// NewMemoryStatsRepository returns a stats repository over users and logins
+func NewMemoryStatsRepository(users *MemoryUserRepository, logins *MemoryLoginHistory) *MemoryStatsRepository {
+	return &MemoryStatsRepository{users: users, logins: logins, now: time.Now}
+}
+
+func (m *MemoryStatsRepository) DashboardStats(ctx context.Context, windowDays int) (DashboardStats, error) {
+	now := m.now()
+	stats := DashboardStats{WindowDays: windowDays, GeneratedAt: now}
+
+	today := startOfDay(now)
+	thisWeek := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	weeks := (windowDays + 6) / 7
+	stats.SignupsPerDay = signupPeriods(today, windowDays, 1)
+	stats.SignupsPerWeek = signupPeriods(thisWeek, weeks, 7)
+
+	m.users.mu.RLock()
+	for _, u := range m.users.users {
+		stats.TotalUsers++
+		if u.IsAdmin {
+			stats.AdminUsers++
+		}
+		day := startOfDay(u.CreatedAt)
+		countSignup(stats.SignupsPerDay, day)
+		countSignup(stats.SignupsPerWeek, day.AddDate(0, 0, -(int(day.Weekday())+6)%7))
+	}
+	m.users.mu.RUnlock()
+	stats.RegularUsers = stats.TotalUsers - stats.AdminUsers
+
+	since := now.AddDate(0, 0, -windowDays)
+	active := map[string]bool{}
+	for _, l := range m.logins.Logins() {
+		if !l.CreatedAt.Before(since) {
+			active[l.UserID] = true
+		}
+	}
+	stats.ActiveUsers = len(active)
+
+	return stats, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// signupPeriods returns n empty counts of periods of the given number of
+// days, the last one starting at last
+func signupPeriods(last time.Time, n, days int) []SignupCount {
+	counts := make([]SignupCount, n)
+	for i := range counts {
+		counts[i].Period = last.AddDate(0, 0, -(n-1-i)*days)
+	}
+	return counts
+}
+
+// countSignup adds a signup to the period starting at period, if listed
+func countSignup(counts []SignupCount, period time.Time) {
+	for i := range counts {
+		if counts[i].Period.Equal(period) {
+			counts[i].Count++
+			return
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStatsRepository(t *testing.T) {
+	ctx := context.Background()
+	// A Wednesday
+	now := time.Date(2026, 3, 18, 15, 0, 0, 0, time.Local)
+
+	users := NewMemoryUserRepository()
+	create := func(name string, created time.Time) User {
+		t.Helper()
+		users.now = func() time.Time { return created }
+		u := User{Username: name, Email: name + "@example.com", Password: "$argon2id$stub"}
+		if err := users.Create(ctx, &u); err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	admin := create("admin", now.AddDate(0, 0, -20))
+	users.setAdmin(admin.ID, true)
+	jane := create("jane", now.AddDate(0, 0, -2))
+	create("john", now.Add(-time.Hour))
+	create("joan", time.Date(2026, 3, 17, 23, 59, 0, 0, time.Local))
+
+	logins := NewMemoryLoginHistory()
+	logins.logins = []Login{
+		{UserID: admin.ID, CreatedAt: now.AddDate(0, 0, -10)},
+		{UserID: jane.ID, CreatedAt: now.AddDate(0, 0, -1)},
+		{UserID: jane.ID, CreatedAt: now.Add(-time.Minute)},
+	}
+
+	stats := NewMemoryStatsRepository(users, logins)
+	stats.now = func() time.Time { return now }
+
+	got, err := stats.DashboardStats(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TotalUsers != 4 || got.AdminUsers != 1 || got.RegularUsers != 3 {
+		t.Errorf("users = %d total, %d admin, %d regular, want 4, 1, 3", got.TotalUsers, got.AdminUsers, got.RegularUsers)
+	}
+	if got.ActiveUsers != 1 {
+		t.Errorf("ActiveUsers = %d, want 1 within 7 days", got.ActiveUsers)
+	}
+	if got.WindowDays != 7 || !got.GeneratedAt.Equal(now) {
+		t.Errorf("WindowDays, GeneratedAt = %d, %s", got.WindowDays, got.GeneratedAt)
+	}
+
+	if len(got.SignupsPerDay) != 7 {
+		t.Fatalf("%d days, want 7", len(got.SignupsPerDay))
+	}
+	wantDays := map[int]int{16: 1, 17: 1, 18: 1}
+	for i, c := range got.SignupsPerDay {
+		want := time.Date(2026, 3, 12+i, 0, 0, 0, 0, time.Local)
+		if !c.Period.Equal(want) {
+			t.Errorf("day %d starts %s, want %s", i, c.Period, want)
+		}
+		if c.Count != wantDays[c.Period.Day()] {
+			t.Errorf("%s: %d signups, want %d", c.Period.Format(time.DateOnly), c.Count, wantDays[c.Period.Day()])
+		}
+	}
+
+	// Weeks start on Monday
+	want := []SignupCount{{Period: time.Date(2026, 3, 16, 0, 0, 0, 0, time.Local), Count: 3}}
+	if len(got.SignupsPerWeek) != len(want) {
+		t.Fatalf("weeks = %+v, want %+v", got.SignupsPerWeek, want)
+	}
+	for i := range want {
+		if !got.SignupsPerWeek[i].Period.Equal(want[i].Period) || got.SignupsPerWeek[i].Count != want[i].Count {
+			t.Errorf("week %d = %+v, want %+v", i, got.SignupsPerWeek[i], want[i])
+		}
+	}
+
+	got, err = stats.DashboardStats(ctx, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ActiveUsers != 2 || len(got.SignupsPerDay) != 30 || len(got.SignupsPerWeek) != 5 {
+		t.Errorf("30 days: %d active, %d days, %d weeks, want 2, 30, 5", got.ActiveUsers, len(got.SignupsPerDay), len(got.SignupsPerWeek))
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PostgresStatsRepository is a StatsRepository reading the users and
+// login_history tables
+type PostgresStatsRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresStatsRepository returns a stats repository using db
+func NewPostgresStatsRepository(db *sql.DB) *PostgresStatsRepository {
+	return &PostgresStatsRepository{db: db}
+}
+
+func (p *PostgresStatsRepository) DashboardStats(ctx context.Context, windowDays int) (DashboardStats, error) {
+	stats := DashboardStats{WindowDays: windowDays, GeneratedAt: time.Now()}
+
+	err := p.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COUNT(*) FILTER (WHERE is_admin) FROM users`,
+	).Scan(&stats.TotalUsers, &stats.AdminUsers)
+	if err != nil {
+		return stats, err
+	}
+	stats.RegularUsers = stats.TotalUsers - stats.AdminUsers
+
+	err = p.db.QueryRowContext(ctx,
+		`SELECT COUNT(DISTINCT user_id) FROM login_history WHERE created_at >= NOW() - make_interval(days => $1)`,
+		windowDays,
+	).Scan(&stats.ActiveUsers)
+	if err != nil {
+		return stats, err
+	}
+
+	weeks := (windowDays + 6) / 7
+	if stats.SignupsPerDay, err = p.signupsPer(ctx, "day", windowDays); err != nil {
+		return stats, err
+	}
+	if stats.SignupsPerWeek, err = p.signupsPer(ctx, "week", weeks); err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+// signupsPer counts signups in each of the last n periods of the given unit
+// ("day" or "week"), including periods without signups
+func (p *PostgresStatsRepository) signupsPer(ctx context.Context, unit string, n int) ([]SignupCount, error) {
+	query := `
+		SELECT p.period, COUNT(u.id)
+		FROM generate_series(
+			date_trunc($1, NOW()) - ($2 - 1) * ('1 ' || $1)::interval,
+			date_trunc($1, NOW()),
+			('1 ' || $1)::interval
+		) AS p(period)
+		LEFT JOIN users u ON date_trunc($1, u.created_at) = p.period
+		GROUP BY p.period
+		ORDER BY p.period
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, unit, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []SignupCount{}
+	for rows.Next() {
+		var c SignupCount
+		if err := rows.Scan(&c.Period, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenColumn is a column of a token table besides the common ones, with the
+// value to insert or the destination to scan into
+type tokenColumn struct {
+	name  string
+	value interface{}
+}
+
+// tokenTable is one of the tables of single-use tokens mailed to users:
+// invitations, email_verifications and password_resets. Each has the columns
+// token_hash, user_id, expires_at, used_at and created_at; only the hash of a
+// token is stored.
+type tokenTable struct {
+	db   *sql.DB
+	name string
+	// invalid is returned for tokens that cannot be redeemed
+	invalid error
+}
+
+// create stores a token after discarding the unused tokens of the same user,
+// so only the most recent link works
+func (t tokenTable) create(ctx context.Context, tokenHash, userID string, expiresAt, createdAt time.Time, extra ...tokenColumn) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+t.name+` WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return err
+	}
+
+	columns := []string{"token_hash", "user_id", "expires_at", "created_at"}
+	args := []interface{}{tokenHash, userID, expiresAt, createdAt}
+	for _, c := range extra {
+		columns = append(columns, c.name)
+		args = append(args, c.value)
+	}
+	placeholders := make([]string, len(args))
+	for i := range placeholders {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+	}
+
+	query := `INSERT INTO ` + t.name + ` (` + strings.Join(columns, ", ") + `) VALUES (` + strings.Join(placeholders, ", ") + `)`
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// redeem marks the unused, unexpired token with tokenHash used and scans its
+// extra columns, then lets apply update the user in the same transaction.
+// ErrUserNotFound from apply means the user can no longer redeem the token
+// and is returned as t.invalid.
+func (t tokenTable) redeem(ctx context.Context, tokenHash string, apply func(tx *sql.Tx, now time.Time, userID string) (User, error), extra ...tokenColumn) (User, error) {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return User{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	var userID string
+	columns := []string{"user_id"}
+	dest := []interface{}{&userID}
+	for _, c := range extra {
+		columns = append(columns, c.name)
+		dest = append(dest, c.value)
+	}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE `+t.name+` SET used_at = $1
+		WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1
+		RETURNING `+strings.Join(columns, ", "), now, tokenHash).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return User{}, t.invalid
+	}
+	if err != nil {
+		return User{}, err
+	}
+
+	user, err := apply(tx, now, userID)
+	if errors.Is(err, ErrUserNotFound) {
+		return User{}, t.invalid
+	}
+	if err != nil {
+		return User{}, err
+	}
+
+	return user, tx.Commit()
+}
+
+// discardUnused deletes the unused tokens of a user within tx
+func (t tokenTable) discardUnused(ctx context.Context, tx *sql.Tx, userID string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM `+t.name+` WHERE user_id = $1 AND used_at IS NULL`, userID)
+	return err
+}
+
+// deleteExpired removes tokens that expired before cutoff
+func (t tokenTable) deleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := t.db.ExecContext(ctx, `DELETE FROM `+t.name+` WHERE expires_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// memoryToken is a token kept by memoryTokenTable. Extra holds the one
+// table-specific value the memory stores need, such as the email address a
+// verification was sent to.
+type memoryToken struct {
+	UserID    string
+	Extra     string
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// memoryTokenTable is the in-memory counterpart of tokenTable
+type memoryTokenTable struct {
+	mu      sync.Mutex
+	tokens  map[string]memoryToken
+	invalid error
+	now     func() time.Time
+}
+
+func newMemoryTokenTable(invalid error) *memoryTokenTable {
+	return &memoryTokenTable{tokens: map[string]memoryToken{}, invalid: invalid, now: time.Now}
+}
+
+func (m *memoryTokenTable) create(tokenHash string, token memoryToken) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.discardUnused(token.UserID)
+	m.tokens[tokenHash] = token
+}
+
+// redeem marks the unused, unexpired token with tokenHash used once apply
+// has updated its user. apply runs under the table's lock.
+func (m *memoryTokenTable) redeem(tokenHash string, apply func(token memoryToken) (User, error)) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.tokens[tokenHash]
+	if !ok || token.Used || !m.now().Before(token.ExpiresAt) {
+		return User{}, m.invalid
+	}
+
+	user, err := apply(token)
+	if errors.Is(err, ErrUserNotFound) {
+		return User{}, m.invalid
+	}
+	if err != nil {
+		return User{}, err
+	}
+	token.Used = true
+	m.tokens[tokenHash] = token
+	return user, nil
+}
+
+// discardUnused deletes the unused tokens of a user. The caller holds m.mu.
+func (m *memoryTokenTable) discardUnused(userID string) {
+	for hash, t := range m.tokens {
+		if t.UserID == userID && !t.Used {
+			delete(m.tokens, hash)
+		}
+	}
+}
+
+func (m *memoryTokenTable) deleteExpired(cutoff time.Time) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for hash, t := range m.tokens {
+		if t.ExpiresAt.Before(cutoff) {
+			delete(m.tokens, hash)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,326 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryUserRepository is a UserRepository kept in process memory, for tests
+// and development without a database. It is safe for concurrent use.
+type MemoryUserRepository struct {
+	mu     sync.RWMutex
+	users  map[int]User
+	nextID int
+	now    func() time.Time
+}
+
+// NewMemoryUserRepository returns an empty in-memory repository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: map[int]User{}, nextID: 1, now: time.Now}
+}
+
+func (m *MemoryUserRepository) Get(ctx context.Context, id string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return User{}, ErrUserNotFound
+	}
+	user, ok := m.users[n]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (m *MemoryUserRepository) GetByUsername(ctx context.Context, username string) (User, error) {
+	key := FoldKey(NormalizeUsername(username))
+	return m.find(func(u User) bool { return FoldKey(u.Username) == key })
+}
+
+func (m *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (User, error) {
+	key := FoldKey(NormalizeEmail(email))
+	return m.find(func(u User) bool { return FoldKey(u.Email) == key })
+}
+
+func (m *MemoryUserRepository) find(match func(User) bool) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, u := range m.users {
+		if match(u) {
+			return u, nil
+		}
+	}
+	return User{}, ErrUserNotFound
+}
+
+func (m *MemoryUserRepository) List(ctx context.Context, q UserQuery) (UserPage, error) {
+	q, err := q.normalize()
+	if err != nil {
+		return UserPage{}, err
+	}
+
+	var cursor userCursor
+	var after interface{}
+	if q.Cursor != "" {
+		if cursor, after, err = q.cursor(); err != nil {
+			return UserPage{}, err
+		}
+	}
+
+	m.mu.RLock()
+	var matched []User
+	for _, u := range m.users {
+		if q.Filter.matches(u) {
+			matched = append(matched, u)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return compareUsers(q.Sort, matched[i], matched[j], q.Desc) < 0
+	})
+
+	page := UserPage{Users: []User{}, Limit: q.Limit, Offset: q.Offset, Total: len(matched)}
+	rest := matched
+	if after != nil {
+		rest = nil
+		for _, u := range matched {
+			if compareToCursor(q.Sort, u, after, cursor.ID, q.Desc) > 0 {
+				rest = append(rest, u)
+			}
+		}
+	}
+	if q.Offset < len(rest) {
+		rest = rest[q.Offset:]
+	} else {
+		rest = nil
+	}
+	if len(rest) > q.Limit+1 {
+		rest = rest[:q.Limit+1]
+	}
+	page.Users = append(page.Users, rest...)
+
+	page.finish(q)
+	return page, nil
+}
+
+func (m *MemoryUserRepository) Create(ctx context.Context, user *User) error {
+	if err := user.BeforeSave(); err != nil {
+		return err
+	}
+	if user.Status == "" {
+		user.Status = UserStatusActive
+	}
+	user.Normalize()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.taken(*user, 0); err != nil {
+		return err
+	}
+
+	id := m.nextID
+	m.nextID++
+	now := m.now()
+	user.ID = strconv.Itoa(id)
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	m.users[id] = *user
+	return nil
+}
+
+func (m *MemoryUserRepository) Update(ctx context.Context, user User) (User, error) {
+	user.Normalize()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, _ := strconv.Atoi(user.ID)
+	stored, ok := m.users[id]
+	if !ok {
+		return user, ErrUserNotFound
+	}
+	if err := m.taken(user, id); err != nil {
+		return user, err
+	}
+
+	if stored.Email != user.Email {
+		stored.EmailVerifiedAt = nil
+	}
+	stored.Username = user.Username
+	stored.Email = user.Email
+	stored.UpdatedAt = m.now()
+	m.users[id] = stored
+	return stored, nil
+}
+
+func (m *MemoryUserRepository) UpdatePassword(ctx context.Context, id, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, _ := strconv.Atoi(id)
+	stored, ok := m.users[n]
+	if !ok {
+		return ErrUserNotFound
+	}
+	stored.Password = hash
+	stored.UpdatedAt = m.now()
+	m.users[n] = stored
+	return nil
+}
+
+func (m *MemoryUserRepository) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, _ := strconv.Atoi(id)
+	if _, ok := m.users[n]; !ok {
+		return ErrUserNotFound
+	}
+	delete(m.users, n)
+	return nil
+}
+
+// setAdmin sets the admin flag of a user, which MemoryRoleRepository keeps
+// in sync with the admin role
+func (m *MemoryUserRepository) setAdmin(id string, isAdmin bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, _ := strconv.Atoi(id)
+	if stored, ok := m.users[n]; ok {
+		stored.IsAdmin = isAdmin
+		stored.UpdatedAt = m.now()
+		m.users[n] = stored
+	}
+}
+
+// modify applies fn to the stored user with the given ID and stores the
+// result. fn returns ErrUserNotFound to leave a user it does not apply to
+// unchanged; the token stores use this to activate and verify users.
+func (m *MemoryUserRepository) modify(id string, fn func(u *User, now time.Time) error) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, _ := strconv.Atoi(id)
+	stored, ok := m.users[n]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	now := m.now()
+	if err := fn(&stored, now); err != nil {
+		return User{}, err
+	}
+	stored.UpdatedAt = now
+	m.users[n] = stored
+	return stored, nil
+}
+
+// taken returns ErrUsernameTaken or ErrEmailTaken if another user than
+// exceptID has user's username or email, compared by FoldKey. The caller
+// holds m.mu.
+func (m *MemoryUserRepository) taken(user User, exceptID int) error {
+	username, email := FoldKey(user.Username), FoldKey(user.Email)
+	for id, u := range m.users {
+		if id == exceptID {
+			continue
+		}
+		if FoldKey(u.Username) == username {
+			return ErrUsernameTaken
+		}
+		if FoldKey(u.Email) == email {
+			return ErrEmailTaken
+		}
+	}
+	return nil
+}
+
+// matches reports whether u satisfies f, with the same semantics as the SQL
+// conditions added by apply
+func (f UserFilter) matches(u User) bool {
+	if f.UsernamePrefix != "" && !strings.HasPrefix(strings.ToLower(u.Username), strings.ToLower(f.UsernamePrefix)) {
+		return false
+	}
+	if f.EmailDomain != "" {
+		domain := ""
+		if i := strings.Index(u.Email, "@"); i >= 0 {
+			domain = u.Email[i+1:]
+			if j := strings.Index(domain, "@"); j >= 0 {
+				domain = domain[:j]
+			}
+		}
+		if !strings.EqualFold(domain, strings.TrimPrefix(f.EmailDomain, "@")) {
+			return false
+		}
+	}
+	if f.IsAdmin != nil && u.IsAdmin != *f.IsAdmin {
+		return false
+	}
+	if f.Status != "" && u.Status != f.Status {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && u.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !u.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// compareUsers orders a and b by the sort field, then by ID, reversed if desc
+func compareUsers(field string, a, b User, desc bool) int {
+	bid, _ := strconv.Atoi(b.ID)
+	return compareToCursor(field, a, sortValue(field, b), bid, desc)
+}
+
+// compareToCursor orders u relative to the position (value, id) in the sort
+// order: negative if u comes first, positive if it comes after
+func compareToCursor(field string, u User, value interface{}, id int, desc bool) int {
+	uid, _ := strconv.Atoi(u.ID)
+	c := 0
+	switch v := sortValue(field, u).(type) {
+	case int:
+		c = compareInts(v, value.(int))
+	case string:
+		c = strings.Compare(v, value.(string))
+	case time.Time:
+		switch {
+		case v.Before(value.(time.Time)):
+			c = -1
+		case v.After(value.(time.Time)):
+			c = 1
+		}
+	}
+	if c == 0 {
+		c = compareInts(uid, id)
+	}
+	if desc {
+		c = -c
+	}
+	return c
+}
+
+// sortValue returns the value of u's sort field with the type cursorValue yields
+func sortValue(field string, u User) interface{} {
+	switch field {
+	case "username":
+		return u.Username
+	case "email":
+		return u.Email
+	case "created_at":
+		return u.CreatedAt
+	default:
+		id, _ := strconv.Atoi(u.ID)
+		return id
+	}
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newListUsers returns a repository holding users with repeated creation
+// times and email addresses in two domains, so sorting relies on the ID tie
+// breaker
+func newListUsers(t *testing.T) *MemoryUserRepository {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	users := NewMemoryUserRepository()
+	for i, name := range []string{"mallory", "alice", "bob", "carol", "alex", "dave", "erin", "albert"} {
+		users.now = func() time.Time { return base.Add(time.Duration(i/2) * time.Hour) }
+		domain := "example.com"
+		if i%3 == 0 {
+			domain = "corp.example"
+		}
+		u := User{Username: name, Email: name + "@" + domain, Password: "$argon2id$stub"}
+		if i%4 == 3 {
+			u.Status = UserStatusPending
+		}
+		if err := users.Create(ctx, &u); err != nil {
+			t.Fatal(err)
+		}
+		if i%3 == 1 {
+			users.setAdmin(u.ID, true)
+		}
+	}
+	return users
+}
+
+// before reports whether a strictly precedes b in the order of field, with
+// ties broken by ID
+func before(field string, a, b User, desc bool) bool {
+	var c int
+	switch field {
+	case "username":
+		c = strings.Compare(a.Username, b.Username)
+	case "email":
+		c = strings.Compare(a.Email, b.Email)
+	case "created_at":
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		ai, _ := strconv.Atoi(a.ID)
+		bi, _ := strconv.Atoi(b.ID)
+		c = ai - bi
+	}
+	if desc {
+		c = -c
+	}
+	return c < 0
+}
+
+func TestMemoryUserRepositoryListCursor(t *testing.T) {
+	ctx := context.Background()
+	users := newListUsers(t)
+
+	for field := range UserSortFields {
+		for _, desc := range []bool{false, true} {
+			q := UserQuery{Sort: field, Desc: desc, Limit: 3}
+			var got []User
+			for pages := 0; ; pages++ {
+				if pages > 8 {
+					t.Fatalf("%s desc=%v: cursor does not advance", field, desc)
+				}
+				page, err := users.List(ctx, q)
+				if err != nil {
+					t.Fatalf("%s desc=%v: %v", field, desc, err)
+				}
+				if page.Total != 8 || len(page.Users) > 3 {
+					t.Fatalf("%s desc=%v: %d users of %d on a page", field, desc, len(page.Users), page.Total)
+				}
+				got = append(got, page.Users...)
+				if page.NextCursor == "" {
+					break
+				}
+				q.Cursor = page.NextCursor
+			}
+
+			if len(got) != 8 {
+				t.Fatalf("%s desc=%v: %d users over all pages, want 8", field, desc, len(got))
+			}
+			for i := 1; i < len(got); i++ {
+				if !before(field, got[i-1], got[i], desc) {
+					t.Errorf("%s desc=%v: %s (%s) listed before %s (%s)", field, desc, got[i-1].Username, got[i-1].ID, got[i].Username, got[i].ID)
+				}
+			}
+		}
+	}
+}
+
+func TestMemoryUserRepositoryListInvalidCursor(t *testing.T) {
+	ctx := context.Background()
+	users := newListUsers(t)
+
+	page, err := users.List(ctx, UserQuery{Sort: "username", Limit: 2})
+	if err != nil || page.NextCursor == "" {
+		t.Fatalf("first page: %+v, %v", page, err)
+	}
+
+	tests := []struct {
+		name string
+		q    UserQuery
+	}{
+		{"other sort", UserQuery{Sort: "email", Cursor: page.NextCursor}},
+		{"other order", UserQuery{Sort: "username", Desc: true, Cursor: page.NextCursor}},
+		{"not base64", UserQuery{Sort: "username", Cursor: "!!!"}},
+		{"not json", UserQuery{Sort: "username", Cursor: base64.RawURLEncoding.EncodeToString([]byte("not json"))}},
+		{"bad value", UserQuery{Sort: "created_at", Cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"created_at","v":"yesterday","id":1}`))}},
+	}
+	for _, tt := range tests {
+		if _, err := users.List(ctx, tt.q); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: error = %v, want ErrInvalidCursor", tt.name, err)
+		}
+	}
+}
+
+func TestMemoryUserRepositoryListFilters(t *testing.T) {
+	ctx := context.Background()
+	users := newListUsers(t)
+	yes, no := true, false
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter UserFilter
+		want   []string
+	}{
+		{"username prefix", UserFilter{UsernamePrefix: "AL"}, []string{"alice", "alex", "albert"}},
+		{"email domain", UserFilter{EmailDomain: "@corp.example"}, []string{"mallory", "carol", "erin"}},
+		{"admins", UserFilter{IsAdmin: &yes}, []string{"alice", "alex", "albert"}},
+		{"non-admins", UserFilter{IsAdmin: &no}, []string{"mallory", "bob", "carol", "dave", "erin"}},
+		{"pending", UserFilter{Status: UserStatusPending}, []string{"carol", "albert"}},
+		{"active", UserFilter{Status: UserStatusActive}, []string{"mallory", "alice", "bob", "alex", "dave", "erin"}},
+		{"created range", UserFilter{CreatedAfter: base.Add(time.Hour), CreatedBefore: base.Add(3 * time.Hour)}, []string{"bob", "carol", "alex", "dave"}},
+		{"combined", UserFilter{UsernamePrefix: "al", Status: UserStatusActive}, []string{"alice", "alex"}},
+		{"no match", UserFilter{UsernamePrefix: "zed"}, nil},
+	}
+	for _, tt := range tests {
+		// A page smaller than the matches shows that Total counts them all
+		page, err := users.List(ctx, UserQuery{Filter: tt.filter, Limit: 1})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if page.Total != len(tt.want) {
+			t.Errorf("%s: total = %d, want %d", tt.name, page.Total, len(tt.want))
+		}
+
+		page, err = users.List(ctx, UserQuery{Filter: tt.filter})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, u := range page.Users {
+			got = append(got, u.Username)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: users = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUserQueryLimit(t *testing.T) {
+	tests := []struct{ limit, want int }{
+		{0, DefaultUserPageSize},
+		{-1, DefaultUserPageSize},
+		{1, 1},
+		{MaxUserPageSize, MaxUserPageSize},
+		{MaxUserPageSize + 1, MaxUserPageSize},
+	}
+	for _, tt := range tests {
+		q, err := UserQuery{Limit: tt.limit}.normalize()
+		if err != nil || q.Limit != tt.want {
+			t.Errorf("limit %d normalized to %d, %v; want %d", tt.limit, q.Limit, err, tt.want)
+		}
+	}
+	if _, err := (UserQuery{Sort: "password"}).normalize(); err == nil {
+		t.Error("unknown sort field accepted")
+	}
+}
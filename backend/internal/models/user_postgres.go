@@ -0,0 +1,329 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// userColumns are the columns scanned by scanUser
+const userColumns = `id, username, password, email, is_admin, status, email_verified_at, created_at, updated_at`
+
+// PostgresUserRepository is a UserRepository backed by the users table
+type PostgresUserRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresUserRepository returns a repository using db
+func NewPostgresUserRepository(db *sql.DB) *PostgresUserRepository {
+	return &PostgresUserRepository{db: db}
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (User, error) {
+	var user User
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Password,
+		&user.Email,
+		&user.IsAdmin,
+		&user.Status,
+		&user.EmailVerifiedAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return user, ErrUserNotFound
+	}
+	return user, err
+}
+
+// parseUserID converts a user ID to the type of users.id. IDs that are not
+// integers name no user; passing them to Postgres would fail with an invalid
+// input error instead.
+func parseUserID(id string) (int32, bool) {
+	n, err := strconv.ParseInt(id, 10, 32)
+	return int32(n), err == nil
+}
+
+func (p *PostgresUserRepository) Get(ctx context.Context, id string) (User, error) {
+	n, ok := parseUserID(id)
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	return scanUser(p.db.QueryRowContext(ctx, query, n))
+}
+
+func (p *PostgresUserRepository) GetByUsername(ctx context.Context, username string) (User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE username_fold = $1`
+	return scanUser(p.db.QueryRowContext(ctx, query, FoldKey(NormalizeUsername(username))))
+}
+
+func (p *PostgresUserRepository) GetByEmail(ctx context.Context, email string) (User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email_fold = $1`
+	return scanUser(p.db.QueryRowContext(ctx, query, FoldKey(NormalizeEmail(email))))
+}
+
+func (p *PostgresUserRepository) List(ctx context.Context, q UserQuery) (UserPage, error) {
+	q, err := q.normalize()
+	if err != nil {
+		return UserPage{}, err
+	}
+
+	var w whereBuilder
+	q.Filter.apply(&w)
+
+	page := UserPage{Users: []User{}, Limit: q.Limit, Offset: q.Offset}
+	err = p.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+w.clause(), w.args...).Scan(&page.Total)
+	if err != nil {
+		return page, err
+	}
+
+	column := UserSortFields[q.Sort]
+	if q.Cursor != "" {
+		c, value, err := q.cursor()
+		if err != nil {
+			return page, err
+		}
+		op := ">"
+		if q.Desc {
+			op = "<"
+		}
+		w.add(fmt.Sprintf("(%s, id) %s (%s, %s)", column, op, w.arg(value), w.arg(c.ID)))
+	}
+
+	dir := "ASC"
+	if q.Desc {
+		dir = "DESC"
+	}
+	// Fetch one extra row to learn whether another page follows
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM users%s
+		ORDER BY %s %s, id %s
+		LIMIT %s OFFSET %s
+	`, userColumns, w.clause(), column, dir, dir, w.arg(q.Limit+1), w.arg(q.Offset))
+
+	rows, err := p.db.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return page, err
+		}
+		page.Users = append(page.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	page.finish(q)
+	return page, nil
+}
+
+func (p *PostgresUserRepository) Create(ctx context.Context, user *User) error {
+	if err := user.BeforeSave(); err != nil {
+		return err
+	}
+	if user.Status == "" {
+		user.Status = UserStatusActive
+	}
+	user.Normalize()
+
+	query := `
+		INSERT INTO users (username, password, email, is_admin, status, created_at, updated_at, username_fold, email_fold)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	now := time.Now()
+
+	err := p.db.QueryRowContext(
+		ctx,
+		query,
+		user.Username,
+		user.Password,
+		user.Email,
+		user.IsAdmin,
+		user.Status,
+		now,
+		now,
+		FoldKey(user.Username),
+		FoldKey(user.Email),
+	).Scan(&user.ID)
+	if isUniqueViolation(err) {
+		return userExists(err)
+	}
+	if err != nil {
+		return err
+	}
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	return nil
+}
+
+func (p *PostgresUserRepository) Update(ctx context.Context, user User) (User, error) {
+	id, ok := parseUserID(user.ID)
+	if !ok {
+		return user, ErrUserNotFound
+	}
+	user.Normalize()
+	query := `
+		UPDATE users
+		SET username = $1, email = $2, updated_at = $3, username_fold = $4, email_fold = $5,
+			email_verified_at = CASE WHEN email = $2 THEN email_verified_at END
+		WHERE id = $6
+		RETURNING ` + userColumns
+
+	updated, err := scanUser(p.db.QueryRowContext(ctx, query,
+		user.Username, user.Email, time.Now(), FoldKey(user.Username), FoldKey(user.Email), id))
+	if isUniqueViolation(err) {
+		return user, userExists(err)
+	}
+	return updated, err
+}
+
+func (p *PostgresUserRepository) UpdatePassword(ctx context.Context, id, hash string) error {
+	n, ok := parseUserID(id)
+	if !ok {
+		return ErrUserNotFound
+	}
+	query := `UPDATE users SET password = $1, updated_at = $2 WHERE id = $3`
+
+	result, err := p.db.ExecContext(ctx, query, hash, time.Now(), n)
+	if err != nil {
+		return err
+	}
+	return userAffected(result)
+}
+
+func (p *PostgresUserRepository) Delete(ctx context.Context, id string) error {
+	n, ok := parseUserID(id)
+	if !ok {
+		return ErrUserNotFound
+	}
+	query := `DELETE FROM users WHERE id = $1`
+
+	result, err := p.db.ExecContext(ctx, query, n)
+	if err != nil {
+		return err
+	}
+	return userAffected(result)
+}
+
+// userAffected returns ErrUserNotFound if a statement on a single user
+// changed no row
+func userAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// RefoldConflict is a user whose fold key RefoldKeys could not rewrite
+// because another user already has it. One of the two must be renamed.
+type RefoldConflict struct {
+	ID       string
+	Username string
+	Err      error
+}
+
+// RefoldKeys rewrites, within tx, the username_fold and email_fold keys that
+// differ from FoldKey. Migration 0010 backfills the keys of existing users
+// with SQL lower(), which folds non-ASCII characters such as "ß" or
+// fullwidth letters differently or, under the C collation, not at all, so
+// those users could not be found by name; RefoldKeys runs right after it.
+// ASCII names fold the same either way and are skipped. It returns the number
+// of users rewritten and those left unchanged because their new key is
+// taken. Every rewrite has its own savepoint, so a conflict does not abort
+// tx.
+func RefoldKeys(ctx context.Context, tx *sql.Tx) (int, []RefoldConflict, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, username, email, username_fold, email_fold FROM users
+		WHERE octet_length(username) <> char_length(username)
+			OR octet_length(email) <> char_length(email)
+	`)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	type refold struct {
+		id, username, usernameFold, emailFold string
+	}
+	var todo []refold
+	for rows.Next() {
+		var id, username, email, usernameFold, emailFold string
+		if err := rows.Scan(&id, &username, &email, &usernameFold, &emailFold); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		wantUsername := FoldKey(NormalizeUsername(username))
+		wantEmail := FoldKey(NormalizeEmail(email))
+		if wantUsername != usernameFold || wantEmail != emailFold {
+			todo = append(todo, refold{id, username, wantUsername, wantEmail})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	var refolded int
+	var conflicts []RefoldConflict
+	for _, r := range todo {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT refold`); err != nil {
+			return refolded, conflicts, err
+		}
+		_, err := tx.ExecContext(ctx,
+			`UPDATE users SET username_fold = $1, email_fold = $2 WHERE id = $3`,
+			r.usernameFold, r.emailFold, r.id,
+		)
+		switch {
+		case isUniqueViolation(err):
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT refold`); err != nil {
+				return refolded, conflicts, err
+			}
+			conflicts = append(conflicts, RefoldConflict{ID: r.id, Username: r.username, Err: userExists(err)})
+		case err != nil:
+			return refolded, conflicts, err
+		default:
+			refolded++
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT refold`); err != nil {
+			return refolded, conflicts, err
+		}
+	}
+	return refolded, conflicts, nil
+}
+
+// userExists tells which unique constraint of the users table err violated
+func userExists(err error) error {
+	var pqErr *pq.Error
+	errors.As(err, &pqErr)
+	switch pqErr.Constraint {
+	case "users_username_key", "users_username_fold_key":
+		return ErrUsernameTaken
+	case "users_email_key", "users_email_fold_key":
+		return ErrEmailTaken
+	}
+	return ErrUserExists
+}
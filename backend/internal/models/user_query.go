@@ -0,0 +1,200 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page size limits for UserRepository.List
+const (
+	DefaultUserPageSize = 50
+	MaxUserPageSize     = 200
+)
+
+// ErrInvalidCursor is returned when a cursor is malformed or was issued for a
+// different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// UserSortFields maps the sort fields accepted by UserRepository.List to their columns
+var UserSortFields = map[string]string{
+	"id":         "id",
+	"username":   "username",
+	"email":      "email",
+	"created_at": "created_at",
+}
+
+// UserFilter narrows the users returned by UserRepository.List. Zero values match
+// every user.
+type UserFilter struct {
+	UsernamePrefix string
+	EmailDomain    string
+	IsAdmin        *bool
+	Status         string
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+}
+
+// UserQuery selects one page of users. A page is addressed either by Cursor
+// (keyset pagination) or by Offset; the two are mutually exclusive.
+type UserQuery struct {
+	Filter UserFilter
+	Sort   string
+	Desc   bool
+	Limit  int
+	Cursor string
+	Offset int
+}
+
+// UserPage is one page of users. NextCursor is empty on the last page.
+type UserPage struct {
+	Users      []User `json:"users"`
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// userCursor is the decoded form of a page cursor: the sort field and the
+// sort value and ID of the last user on the previous page
+type userCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// normalize applies the defaults of q and checks its sort field
+func (q UserQuery) normalize() (UserQuery, error) {
+	if q.Sort == "" {
+		q.Sort = "id"
+	}
+	if _, ok := UserSortFields[q.Sort]; !ok {
+		return q, fmt.Errorf("unknown sort field %q", q.Sort)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultUserPageSize
+	}
+	if q.Limit > MaxUserPageSize {
+		q.Limit = MaxUserPageSize
+	}
+	return q, nil
+}
+
+// cursor decodes q.Cursor, checking that it belongs to q's sort order
+func (q UserQuery) cursor() (userCursor, interface{}, error) {
+	c, err := decodeUserCursor(q.Cursor)
+	if err != nil || c.Sort != q.Sort || c.Desc != q.Desc {
+		return c, nil, ErrInvalidCursor
+	}
+	value, err := cursorValue(q.Sort, c.Value)
+	if err != nil {
+		return c, nil, ErrInvalidCursor
+	}
+	return c, value, nil
+}
+
+// finish trims a page fetched with one extra user to q.Limit users and sets
+// NextCursor if the extra user shows that another page follows
+func (page *UserPage) finish(q UserQuery) {
+	if len(page.Users) > q.Limit {
+		page.Users = page.Users[:q.Limit]
+		page.NextCursor = encodeUserCursor(q, page.Users[q.Limit-1])
+	}
+}
+
+// apply adds the conditions of f to w. Username and email domain matching is
+// case-insensitive and backed by expression indexes on users.
+func (f UserFilter) apply(w *whereBuilder) {
+	if f.UsernamePrefix != "" {
+		w.add("lower(username) LIKE " + w.arg(escapeLike(strings.ToLower(f.UsernamePrefix))+"%"))
+	}
+	if f.EmailDomain != "" {
+		w.add("lower(split_part(email, '@', 2)) = " + w.arg(strings.ToLower(strings.TrimPrefix(f.EmailDomain, "@"))))
+	}
+	if f.IsAdmin != nil {
+		w.add("is_admin = " + w.arg(*f.IsAdmin))
+	}
+	if f.Status != "" {
+		w.add("status = " + w.arg(f.Status))
+	}
+	if !f.CreatedAfter.IsZero() {
+		w.add("created_at >= " + w.arg(f.CreatedAfter))
+	}
+	if !f.CreatedBefore.IsZero() {
+		w.add("created_at < " + w.arg(f.CreatedBefore))
+	}
+}
+
+func encodeUserCursor(q UserQuery, last User) string {
+	c := userCursor{Sort: q.Sort, Desc: q.Desc}
+	c.ID, _ = strconv.Atoi(last.ID)
+	switch q.Sort {
+	case "username":
+		c.Value = last.Username
+	case "email":
+		c.Value = last.Email
+	case "created_at":
+		c.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		c.Value = last.ID
+	}
+
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeUserCursor(raw string) (userCursor, error) {
+	var c userCursor
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// cursorValue converts a cursor's sort value back to the column's type
+func cursorValue(sort, value string) (interface{}, error) {
+	switch sort {
+	case "id":
+		return strconv.Atoi(value)
+	case "created_at":
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		return value, nil
+	}
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// whereBuilder collects the conditions and positional arguments of a query
+type whereBuilder struct {
+	conds []string
+	args  []interface{}
+}
+
+// arg adds v as a query argument and returns its placeholder
+func (w *whereBuilder) arg(v interface{}) string {
+	w.args = append(w.args, v)
+	return "$" + strconv.Itoa(len(w.args))
+}
+
+func (w *whereBuilder) add(cond string) {
+	w.conds = append(w.conds, cond)
+}
+
+// clause returns the WHERE clause joining all conditions, or "" if there are none
+func (w *whereBuilder) clause() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
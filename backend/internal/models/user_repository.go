@@ -0,0 +1,30 @@
+package models
+
+import "context"
+
+// UserRepository persists users. Get, GetByUsername, GetByEmail, Update,
+// UpdatePassword and Delete return ErrUserNotFound for unknown users; Create
+// and Update return ErrUsernameTaken or ErrEmailTaken, which match
+// ErrUserExists, when the username or email is taken. Usernames and emails
+// are normalized before they are stored and are unique and looked up by
+// FoldKey, i.e. regardless of case.
+type UserRepository interface {
+	// Get returns the user with the given ID
+	Get(ctx context.Context, id string) (User, error)
+	// GetByUsername returns the user with the given username
+	GetByUsername(ctx context.Context, username string) (User, error)
+	// GetByEmail returns the user with the given email address
+	GetByEmail(ctx context.Context, email string) (User, error)
+	// List returns one page of users, see UserQuery
+	List(ctx context.Context, q UserQuery) (UserPage, error)
+	// Create stores a new user, hashing its password with BeforeSave, and
+	// sets its ID and timestamps. Roles are assigned separately.
+	Create(ctx context.Context, user *User) error
+	// Update changes a user's username and email. The admin flag follows
+	// role membership and is changed with SetUserAdmin or SetUserRoles.
+	Update(ctx context.Context, user User) (User, error)
+	// UpdatePassword replaces a user's stored password hash
+	UpdatePassword(ctx context.Context, id, hash string) error
+	// Delete removes a user
+	Delete(ctx context.Context, id string) error
+}
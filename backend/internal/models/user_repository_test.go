@@ -0,0 +1,45 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// testNonNumericUserID checks that repo treats IDs that are not integers as
+// unknown users, as the UserRepository contract requires
+func testNonNumericUserID(t *testing.T, repo UserRepository) {
+	ctx := context.Background()
+	for _, id := range []string{"abc", "", "1.5", "1; DROP TABLE users", "99999999999"} {
+		if _, err := repo.Get(ctx, id); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("Get(%q) error = %v, want ErrUserNotFound", id, err)
+		}
+		if _, err := repo.Update(ctx, User{ID: id, Username: "john", Email: "john@example.com"}); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("Update(%q) error = %v, want ErrUserNotFound", id, err)
+		}
+		if err := repo.UpdatePassword(ctx, id, "$argon2id$stub"); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("UpdatePassword(%q) error = %v, want ErrUserNotFound", id, err)
+		}
+		if err := repo.Delete(ctx, id); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("Delete(%q) error = %v, want ErrUserNotFound", id, err)
+		}
+	}
+}
+
+func TestMemoryUserRepositoryNonNumericID(t *testing.T) {
+	testNonNumericUserID(t, NewMemoryUserRepository())
+}
+
+// The database is unreachable, so the repository passes only if it rejects
+// the IDs without querying
+func TestPostgresUserRepositoryNonNumericID(t *testing.T) {
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	testNonNumericUserID(t, NewPostgresUserRepository(db))
+}
@@ -0,0 +1,103 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fastArgon2id keeps tests quick; only the encoding is under test
+var fastArgon2id = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idHashAndVerify(t *testing.T) {
+	h := NewArgon2id(fastArgon2id)
+	encoded, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("encoded = %q, want PHC format with the parameters", encoded)
+	}
+	if !h.Recognizes(encoded) {
+		t.Error("hash not recognized")
+	}
+
+	if ok, err := h.Verify(encoded, "correct horse"); err != nil || !ok {
+		t.Errorf("Verify(right password) = %v, %v", ok, err)
+	}
+	if ok, err := h.Verify(encoded, "wrong horse"); err != nil || ok {
+		t.Errorf("Verify(wrong password) = %v, %v", ok, err)
+	}
+
+	// Salts differ, so hashing twice gives different results
+	again, _ := h.Hash("correct horse")
+	if again == encoded {
+		t.Error("two hashes of the same password are equal")
+	}
+}
+
+// Encoded salts and keys of valid and invalid lengths
+const (
+	salt16 = "MDEyMzQ1Njc4OWFiY2RlZg"
+	salt65 = "c3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3Nzc3M"
+	key32  = "a2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2s"
+	key65  = "a2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2s"
+)
+
+func TestArgon2idMalformed(t *testing.T) {
+	h := NewArgon2id(fastArgon2id)
+	for _, encoded := range []string{
+		"$argon2id$",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$",
+		// Parameters out of bounds: argon2 panics on t=0 or p=0, and a
+		// huge m would be allocated on every login
+		"$argon2id$v=19$m=1024,t=0,p=1$" + salt16 + "$" + key32,
+		"$argon2id$v=19$m=1024,t=1,p=0$" + salt16 + "$" + key32,
+		"$argon2id$v=19$m=4294967295,t=1,p=1$" + salt16 + "$" + key32,
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$" + key32,
+		"$argon2id$v=19$m=1024,t=1,p=1$" + salt65 + "$" + key32,
+		"$argon2id$v=19$m=1024,t=1,p=1$" + salt16 + "$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$" + salt16 + "$" + key65,
+	} {
+		if _, err := h.Verify(encoded, "password"); !errors.Is(err, ErrMalformedHash) {
+			t.Errorf("Verify(%q) error = %v, want ErrMalformedHash", encoded, err)
+		}
+		if !h.NeedsRehash(encoded) {
+			t.Errorf("NeedsRehash(%q) = false for a malformed hash", encoded)
+		}
+	}
+}
+
+func TestArgon2idBounds(t *testing.T) {
+	// The bounds reject corrupt rows only: a well-formed hash at the limits
+	// still verifies
+	h := NewArgon2id(fastArgon2id)
+	encoded := "$argon2id$v=19$m=1024,t=1,p=1$" + salt16 + "$" + key32
+	if ok, err := h.Verify(encoded, "password"); err != nil || ok {
+		t.Errorf("Verify(in bounds) = %v, %v; want a mismatch without error", ok, err)
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	weak, err := NewArgon2id(fastArgon2id).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if NewArgon2id(fastArgon2id).NeedsRehash(weak) {
+		t.Error("hash with the current parameters needs a rehash")
+	}
+
+	stronger := fastArgon2id
+	stronger.Iterations = 2
+	if !NewArgon2id(stronger).NeedsRehash(weak) {
+		t.Error("hash with fewer iterations does not need a rehash")
+	}
+	longer := fastArgon2id
+	longer.KeyLength = 64
+	if !NewArgon2id(longer).NeedsRehash(weak) {
+		t.Error("hash with a shorter key does not need a rehash")
+	}
+}
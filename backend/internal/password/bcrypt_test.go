@@ -0,0 +1,35 @@
+package password
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcrypt(t *testing.T) {
+	h := NewBcrypt(bcrypt.MinCost)
+	encoded, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Recognizes(encoded) {
+		t.Errorf("hash %q not recognized", encoded)
+	}
+	if ok, err := h.Verify(encoded, "correct horse"); err != nil || !ok {
+		t.Errorf("Verify(right password) = %v, %v", ok, err)
+	}
+	if ok, err := h.Verify(encoded, "wrong horse"); err != nil || ok {
+		t.Errorf("Verify(wrong password) = %v, %v", ok, err)
+	}
+	if _, err := h.Verify("$2a$short", "correct horse"); !errors.Is(err, ErrMalformedHash) {
+		t.Errorf("Verify(malformed) error = %v, want ErrMalformedHash", err)
+	}
+
+	if h.NeedsRehash(encoded) {
+		t.Error("hash with the current cost needs a rehash")
+	}
+	if !NewBcrypt(bcrypt.MinCost + 1).NeedsRehash(encoded) {
+		t.Error("hash with a lower cost does not need a rehash")
+	}
+}
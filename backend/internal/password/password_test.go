@@ -0,0 +1,107 @@
+package password
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// useHashers makes a fast argon2id the default and bcrypt at its minimum cost
+// the other registered hasher for the duration of the test
+func useHashers(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	savedDefault, savedHashers := defaultHasher, hashers
+	defaultHasher = NewArgon2id(fastArgon2id)
+	hashers = []Hasher{defaultHasher, NewBcrypt(bcrypt.MinCost)}
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		defaultHasher, hashers = savedDefault, savedHashers
+		mu.Unlock()
+	})
+}
+
+func TestVerify(t *testing.T) {
+	useHashers(t)
+	current, err := Hash("password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := NewBcrypt(bcrypt.MinCost).Hash("password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weaker := fastArgon2id
+	weaker.Memory = 512
+	weak, err := NewArgon2id(weaker).Hash("password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		encoded    string
+		plain      string
+		ok, rehash bool
+	}{
+		{"current", current, "password1", true, false},
+		{"current wrong", current, "password2", false, false},
+		{"other hasher", legacy, "password1", true, true},
+		{"other hasher wrong", legacy, "password2", false, false},
+		{"weaker parameters", weak, "password1", true, true},
+		{"plaintext", "password1", "password1", true, true},
+		{"plaintext wrong", "password1", "password2", false, false},
+	}
+	for _, tt := range tests {
+		ok, rehash, err := Verify(tt.encoded, tt.plain)
+		if err != nil || ok != tt.ok || rehash != tt.rehash {
+			t.Errorf("%s: Verify = %v, %v, %v, want %v, %v", tt.name, ok, rehash, err, tt.ok, tt.rehash)
+		}
+	}
+}
+
+func TestIsHashed(t *testing.T) {
+	useHashers(t)
+	hashed, err := Hash("password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsHashed(hashed) {
+		t.Error("argon2id hash not recognized")
+	}
+	if IsHashed("password1") {
+		t.Error("plaintext counted as a hash")
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	useHashers(t)
+	argon, err := Hash("password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetDefault(NewBcrypt(bcrypt.MinCost))
+	if Default().ID() != "bcrypt" {
+		t.Fatalf("default = %s, want bcrypt", Default().ID())
+	}
+	hashed, err := Hash("password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !NewBcrypt(bcrypt.MinCost).Recognizes(hashed) {
+		t.Errorf("new hash %q is not bcrypt", hashed)
+	}
+
+	// Hashes of the previous default still verify but are upgraded
+	if ok, rehash, err := Verify(argon, "password1"); err != nil || !ok || !rehash {
+		t.Errorf("Verify(argon2id) = %v, %v, %v, want a match needing a rehash", ok, rehash, err)
+	}
+
+	// Registering a hasher with the same ID replaces it
+	Register(NewBcrypt(bcrypt.MinCost + 1))
+	if n := len(hashers); n != 2 {
+		t.Errorf("%d hashers registered, want 2", n)
+	}
+}
@@ -0,0 +1,52 @@
+package search
+
+import (
+	"context"
+
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// MemorySearcher ranks the users of a models.UserRepository in process. It
+// serves models.MemoryUserRepository in tests and development. Every search
+// reads all users, so it is not meant for a database; ranks match
+// PostgresSearcher's.
+type MemorySearcher struct {
+	users models.UserRepository
+}
+
+// NewMemorySearcher returns a searcher over the users of users
+func NewMemorySearcher(users models.UserRepository) *MemorySearcher {
+	return &MemorySearcher{users: users}
+}
+
+func (m *MemorySearcher) SearchUsers(ctx context.Context, query string, limit int) ([]Result, error) {
+	terms := Terms(query)
+	results := []Result{}
+	if len(terms) == 0 {
+		return results, nil
+	}
+	normalized := joinTerms(terms)
+
+	q := models.UserQuery{Limit: models.MaxUserPageSize}
+	for {
+		page, err := m.users.List(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range page.Users {
+			full := prefixMatch(u, terms)
+			r := rank(u, full, normalized)
+			if !full && r < SimilarityThreshold {
+				continue
+			}
+			u.Password = ""
+			results = append(results, Result{User: u, Rank: r, Highlights: highlights(u, terms)})
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+
+	return best(results, limit), nil
+}
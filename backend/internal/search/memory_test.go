@@ -0,0 +1,119 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/password"
+)
+
+// newSearcher returns a MemorySearcher over a repository holding users with
+// the given usernames
+func newSearcher(t *testing.T, usernames ...string) *MemorySearcher {
+	t.Helper()
+	// Hashing once keeps large user sets fast
+	hash, err := password.Hash("password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := models.NewMemoryUserRepository()
+	for _, name := range usernames {
+		u := models.User{Username: name, Email: name + "@example.com", Password: hash}
+		if err := users.Create(context.Background(), &u); err != nil {
+			t.Fatalf("creating %s: %v", name, err)
+		}
+	}
+	return NewMemorySearcher(users)
+}
+
+func usernames(results []Result) []string {
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.User.Username
+	}
+	return names
+}
+
+func TestMemorySearcherRanking(t *testing.T) {
+	s := newSearcher(t, "john_doe", "johnny", "jane_doe", "alice")
+
+	results, err := s.SearchUsers(context.Background(), "john", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both start with "john"; the more similar username ranks first
+	if got := usernames(results); fmt.Sprint(got) != "[john_doe johnny]" {
+		t.Fatalf("results = %v, want [john_doe johnny]", got)
+	}
+	if results[0].Rank < 1 || results[0].Rank < results[1].Rank {
+		t.Errorf("ranks = %v, %v, want prefix matches above 1 in descending order", results[0].Rank, results[1].Rank)
+	}
+	if h := results[0].Highlights["username"]; h != "<mark>john</mark>_doe" {
+		t.Errorf("username highlight = %q", h)
+	}
+	if results[0].User.Password != "" {
+		t.Error("results carry the password hash")
+	}
+}
+
+func TestMemorySearcherFuzzy(t *testing.T) {
+	s := newSearcher(t, "john_doe", "alice")
+
+	// A near miss is no prefix of any word but is similar enough
+	results, err := s.SearchUsers(context.Background(), "john_dow", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := usernames(results); fmt.Sprint(got) != "[john_doe]" {
+		t.Fatalf("results = %v, want [john_doe]", got)
+	}
+	if r := results[0].Rank; r >= 1 || r < SimilarityThreshold {
+		t.Errorf("rank = %v, want a fuzzy match below 1", r)
+	}
+
+	results, err = s.SearchUsers(context.Background(), "zzz", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("results = %v, want none", usernames(results))
+	}
+}
+
+func TestMemorySearcherEmptyQuery(t *testing.T) {
+	s := newSearcher(t, "alice")
+	results, err := s.SearchUsers(context.Background(), " -- ", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results == nil || len(results) != 0 {
+		t.Errorf("results = %v, want an empty list", results)
+	}
+}
+
+func TestMemorySearcherReadsEveryPage(t *testing.T) {
+	names := make([]string, models.MaxUserPageSize+5)
+	for i := range names {
+		names[i] = fmt.Sprintf("user%03d", i)
+	}
+	names[len(names)-1] = "zoe"
+	s := newSearcher(t, names...)
+
+	results, err := s.SearchUsers(context.Background(), "zoe", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := usernames(results); fmt.Sprint(got) != "[zoe]" {
+		t.Errorf("results = %v, want [zoe] from the last page", got)
+	}
+
+	results, err = s.SearchUsers(context.Background(), "user", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := usernames(results); fmt.Sprint(got) != "[user000 user001 user002]" {
+		t.Errorf("results = %v, want the first three by username", got)
+	}
+}
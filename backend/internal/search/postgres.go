@@ -0,0 +1,140 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// candidatesPerResult is how many users per requested result the full-text
+// fallback reads to rank in process
+const candidatesPerResult = 5
+
+// PostgresSearcher searches the users table using its search_vector column
+// and pg_trgm trigram indexes
+type PostgresSearcher struct {
+	db *sql.DB
+	// trigrams is false for databases without pg_trgm, where near misses
+	// are found by substring instead of trigram similarity
+	trigrams bool
+}
+
+// NewPostgresSearcher returns a searcher using db, which must have pg_trgm
+func NewPostgresSearcher(db *sql.DB) *PostgresSearcher {
+	return &PostgresSearcher{db: db, trigrams: true}
+}
+
+// NewPostgresFullTextSearcher returns a searcher using db for databases
+// without pg_trgm. It finds the same full-text matches as
+// NewPostgresSearcher, finds near misses only where a query word occurs
+// inside the username or email, and ranks the matches in process.
+func NewPostgresFullTextSearcher(db *sql.DB) *PostgresSearcher {
+	return &PostgresSearcher{db: db}
+}
+
+// TrigramsAvailable reports whether the pg_trgm extension PostgresSearcher
+// needs is installed in db. Migration 0009 skips it where it cannot be
+// created.
+func TrigramsAvailable(ctx context.Context, db *sql.DB) (bool, error) {
+	var ok bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')`).Scan(&ok)
+	return ok, err
+}
+
+func (p *PostgresSearcher) SearchUsers(ctx context.Context, query string, limit int) ([]Result, error) {
+	terms := Terms(query)
+	results := []Result{}
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	// Terms only contain letters and digits, so they are safe in a tsquery
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	tsquery := strings.Join(prefixes, " & ")
+	if !p.trigrams {
+		return p.searchFullText(ctx, terms, tsquery, limit)
+	}
+
+	// A full-text match ranks 1 plus the best trigram similarity of
+	// username or email, as in MemorySearcher
+	sqlQuery := `
+		SELECT id, username, email, is_admin, status, email_verified_at, created_at, updated_at, rank
+		FROM (
+			SELECT *,
+				(search_vector @@ to_tsquery('simple', $1))::INT
+					+ GREATEST(similarity(username, $2), similarity(email, $2)) AS rank
+			FROM users
+			WHERE search_vector @@ to_tsquery('simple', $1) OR username % $2 OR email % $2
+		) matches
+		ORDER BY rank DESC, username
+		LIMIT $3
+	`
+
+	rows, err := p.db.QueryContext(ctx, sqlQuery, tsquery, joinTerms(terms), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var res Result
+		u := &res.User
+		err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.IsAdmin, &u.Status, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt, &res.Rank)
+		if err != nil {
+			return nil, err
+		}
+		res.Highlights = highlights(res.User, terms)
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// searchFullText reads up to candidatesPerResult users per result that match
+// tsquery or contain a term, full-text matches first, and ranks them like
+// MemorySearcher
+func (p *PostgresSearcher) searchFullText(ctx context.Context, terms []string, tsquery string, limit int) ([]Result, error) {
+	// Terms only contain letters and digits, so they need no LIKE escaping
+	patterns := make([]string, len(terms))
+	for i, term := range terms {
+		patterns[i] = "%" + term + "%"
+	}
+
+	sqlQuery := `
+		SELECT id, username, email, is_admin, status, email_verified_at, created_at, updated_at,
+			search_vector @@ to_tsquery('simple', $1) AS full_text
+		FROM users
+		WHERE search_vector @@ to_tsquery('simple', $1)
+			OR username ILIKE ANY ($2) OR email ILIKE ANY ($2)
+		ORDER BY full_text DESC, username
+		LIMIT $3
+	`
+
+	rows, err := p.db.QueryContext(ctx, sqlQuery, tsquery, pq.Array(patterns), limit*candidatesPerResult)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []Result{}
+	normalized := joinTerms(terms)
+	for rows.Next() {
+		var u models.User
+		var full bool
+		err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.IsAdmin, &u.Status, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt, &full)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, Result{User: u, Rank: rank(u, full, normalized), Highlights: highlights(u, terms)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return best(results, limit), nil
+}
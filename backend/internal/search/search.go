@@ -0,0 +1,154 @@
+// Package search implements ranked user search.
+//
+// Searchers combine full-text matching of query words as prefixes of the
+// words in a user's username and email with trigram similarity, so both
+// partial input ("jo") and near misses ("john_dow") find "john_doe". The
+// Postgres implementation uses the pg_trgm extension where it is installed
+// and falls back to substring matching without it; MemorySearcher implements
+// the same behaviour in process over the in-memory repository.
+package search
+
+import (
+	"context"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/yourusername/ums/backend/internal/models"
+)
+
+// Result limits
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// SimilarityThreshold is the trigram similarity above which a field counts
+// as a fuzzy match. It equals pg_trgm's default similarity threshold.
+const SimilarityThreshold = 0.3
+
+// Result is a user matching a search, with a relevance rank and the matched
+// fields with matches wrapped in <mark> tags. Highlights are HTML-escaped.
+type Result struct {
+	User       models.User       `json:"user"`
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// UserSearcher finds users matching a free-text query, best matches first
+type UserSearcher interface {
+	SearchUsers(ctx context.Context, query string, limit int) ([]Result, error)
+}
+
+// Terms splits a query into lower-case words, dropping punctuation
+func Terms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// joinTerms returns the normalized query compared by trigram similarity
+func joinTerms(terms []string) string {
+	return strings.Join(terms, " ")
+}
+
+// Highlight returns value, HTML-escaped, with every case-insensitive
+// occurrence of the terms wrapped in <mark> tags, and whether any matched
+func Highlight(value string, terms []string) (string, bool) {
+	lower := strings.ToLower(value)
+	if len(lower) != len(value) {
+		// Lowering changed byte offsets, so matches cannot be mapped back
+		return html.EscapeString(value), false
+	}
+
+	marked := make([]bool, len(value))
+	found := false
+	for _, term := range terms {
+		for i := 0; term != "" && i+len(term) <= len(lower); {
+			j := strings.Index(lower[i:], term)
+			if j < 0 {
+				break
+			}
+			for k := i + j; k < i+j+len(term); k++ {
+				marked[k] = true
+			}
+			found = true
+			i += j + len(term)
+		}
+	}
+	if !found {
+		return html.EscapeString(value), false
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); {
+		j := i
+		for j < len(value) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(value[i:j]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(value[i:j]))
+		}
+		i = j
+	}
+	return b.String(), true
+}
+
+// prefixMatch reports whether every term is a prefix of a word of u's
+// username or email, which is what the full-text part of a search matches
+func prefixMatch(u models.User, terms []string) bool {
+	fields := append(Terms(u.Username), Terms(u.Email)...)
+	for _, term := range terms {
+		found := false
+		for _, word := range fields {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// highlights returns the highlighted username and email of u that matched terms
+func highlights(u models.User, terms []string) map[string]string {
+	h := map[string]string{}
+	if s, ok := Highlight(u.Username, terms); ok {
+		h["username"] = s
+	}
+	if s, ok := Highlight(u.Email, terms); ok {
+		h["email"] = s
+	}
+	return h
+}
+
+// rank returns the rank of u for the normalized query: its best trigram
+// similarity, plus 1 for a full-text match
+func rank(u models.User, fullText bool, normalized string) float64 {
+	r := math.Max(Similarity(u.Username, normalized), Similarity(u.Email, normalized))
+	if fullText {
+		r++
+	}
+	return r
+}
+
+// best sorts results best first, then by username, and keeps the first limit
+func best(results []Result, limit int) []Result {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].User.Username < results[j].User.Username
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
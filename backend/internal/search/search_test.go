@@ -0,0 +1,51 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"John Doe", []string{"john", "doe"}},
+		{"john.doe@example.com", []string{"john", "doe", "example", "com"}},
+		{"  ", []string{}},
+		{"<script>", []string{"script"}},
+	}
+	for _, tt := range tests {
+		got := Terms(tt.query)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		value string
+		terms []string
+		want  string
+		found bool
+	}{
+		{"John_Doe", []string{"jo", "doe"}, "<mark>Jo</mark>hn_<mark>Doe</mark>", true},
+		{"johnson", []string{"jo", "ohn"}, "<mark>john</mark>son", true},
+		{"anna", []string{"a"}, "<mark>a</mark>nn<mark>a</mark>", true},
+		{"a<b>@example.com", []string{"b"}, "a&lt;<mark>b</mark>&gt;@example.com", true},
+		{"alice", []string{"bob"}, "alice", false},
+		{"<alice>", []string{"bob"}, "&lt;alice&gt;", false},
+		// Lower-casing İ changes its length, so matches cannot be placed
+		{"İrem", []string{"rem"}, "İrem", false},
+	}
+	for _, tt := range tests {
+		got, found := Highlight(tt.value, tt.terms)
+		if got != tt.want || found != tt.found {
+			t.Errorf("Highlight(%q, %q) = %q, %v, want %q, %v", tt.value, tt.terms, got, found, tt.want, tt.found)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package search
+
+// trigrams returns the set of trigrams of s the way pg_trgm extracts them:
+// each lower-cased word is padded with two spaces in front and one behind
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range Terms(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// Similarity returns the trigram similarity of a and b, between 0 and 1,
+// matching pg_trgm's similarity()
+func Similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
@@ -0,0 +1,30 @@
+package search
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	// Expected values are what pg_trgm's similarity() returns
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"word", "two words", 4.0 / 11},
+		{"john_doe", "john_doe", 1},
+		{"John_Doe", "john doe", 1},
+		{"john_doe", "john_dow", 7.0 / 11},
+		{"alice", "bob", 0},
+		{"", "alice", 0},
+		{"!!!", "alice", 0},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got, back := Similarity(tt.a, tt.b), Similarity(tt.b, tt.a); got != back {
+			t.Errorf("Similarity(%q, %q) = %v but reversed %v", tt.a, tt.b, got, back)
+		}
+	}
+}
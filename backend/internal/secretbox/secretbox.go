@@ -0,0 +1,79 @@
+// Package secretbox encrypts small secrets, such as TOTP keys, before they
+// are stored in the database.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// KeySize is the length of the AES-256 key in bytes
+const KeySize = 32
+
+// ErrDecrypt is returned when a ciphertext was not sealed with the key or was
+// modified
+var ErrDecrypt = errors.New("secretbox: decryption failed")
+
+// Box seals and opens secrets with AES-256-GCM. Sealed values are the base64
+// encoding of a random nonce followed by the ciphertext.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New returns a box using key, which must be KeySize bytes
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secretbox: key is %d bytes, want %d", len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// ParseKey decodes a base64 key as written in the configuration
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("secretbox: key is not base64")
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secretbox: key is %d bytes, want %d", len(key), KeySize)
+	}
+	return key, nil
+}
+
+// Seal encrypts plaintext. additional is authenticated but not encrypted; it
+// binds the value to its owner, e.g. a user ID, so sealed values cannot be
+// swapped between rows.
+func (b *Box) Seal(plaintext, additional []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b.aead.Seal(nonce, nonce, plaintext, additional)), nil
+}
+
+// Open decrypts a value returned by Seal with the same additional data
+func (b *Box) Open(sealed string, additional []byte) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
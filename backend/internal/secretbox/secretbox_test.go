@@ -0,0 +1,113 @@
+package secretbox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func newBox(t *testing.T, fill byte) *Box {
+	t.Helper()
+	b, err := New(bytes.Repeat([]byte{fill}, KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSealOpen(t *testing.T) {
+	b := newBox(t, 1)
+	secret := []byte("JBSWY3DPEHPK3PXP")
+
+	sealed, err := b.Seal(secret, []byte("user-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains([]byte(sealed), secret) {
+		t.Errorf("sealed value %q contains the plaintext", sealed)
+	}
+	got, err := b.Open(sealed, []byte("user-1"))
+	if err != nil || !bytes.Equal(got, secret) {
+		t.Errorf("Open = %q, %v; want %q", got, err, secret)
+	}
+
+	// Every seal uses a fresh nonce
+	again, err := b.Seal(secret, []byte("user-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == sealed {
+		t.Error("sealing twice gave the same value")
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	b := newBox(t, 1)
+	sealed, err := b.Seal([]byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(sealed)
+
+	for name, value := range map[string]string{
+		"flipped bit": func() string {
+			d := append([]byte(nil), data...)
+			d[len(d)-1] ^= 1
+			return base64.StdEncoding.EncodeToString(d)
+		}(),
+		"truncated":            base64.StdEncoding.EncodeToString(data[:len(data)-1]),
+		"shorter than a nonce": base64.StdEncoding.EncodeToString(data[:4]),
+		"empty":                "",
+		"not base64":           "!" + sealed,
+	} {
+		if got, err := b.Open(value, nil); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%s: Open = %q, %v; want ErrDecrypt", name, got, err)
+		}
+	}
+}
+
+func TestOpenWrongKeyOrAdditionalData(t *testing.T) {
+	b := newBox(t, 1)
+	sealed, err := b.Seal([]byte("secret"), []byte("user-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newBox(t, 2).Open(sealed, []byte("user-1")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open with another key = %v, want ErrDecrypt", err)
+	}
+	// A value moved to another row does not open
+	for _, additional := range [][]byte{[]byte("user-2"), nil} {
+		if _, err := b.Open(sealed, additional); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("Open with additional data %q = %v, want ErrDecrypt", additional, err)
+		}
+	}
+}
+
+func TestNewInvalidKey(t *testing.T) {
+	for _, n := range []int{0, 16, KeySize - 1, KeySize + 1} {
+		if _, err := New(make([]byte, n)); err == nil {
+			t.Errorf("New accepted a %d byte key", n)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, KeySize)
+	got, err := ParseKey(base64.StdEncoding.EncodeToString(key))
+	if err != nil || !bytes.Equal(got, key) {
+		t.Errorf("ParseKey = %x, %v; want %x", got, err, key)
+	}
+
+	for _, s := range []string{
+		"",
+		"not base64!",
+		base64.StdEncoding.EncodeToString(key[:16]),
+		base64.StdEncoding.EncodeToString(append(key, 0)),
+	} {
+		if _, err := ParseKey(s); err == nil {
+			t.Errorf("ParseKey(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"github.com/gorilla/mux"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+	"github.com/yourusername/ums/backend/internal/health"
+	"github.com/yourusername/ums/backend/internal/metrics"
+)
+
+// routes builds the router: health probes at the root and the API under /api
+func (s *Server) routes() *mux.Router {
+	h := s.handlers
+	r := mux.NewRouter()
+	r.NotFoundHandler = apierror.NotFoundHandler()
+	r.MethodNotAllowedHandler = apierror.MethodNotAllowedHandler()
+
+	// Probes for the orchestrator, outside /api and without authentication
+	r.HandleFunc("/healthz", health.Live).Methods("GET")
+	r.Handle("/readyz", s.health).Methods("GET")
+	if s.metrics != nil {
+		r.Use(metrics.RouteLabel)
+		if s.cfg.Metrics.Addr == "" {
+			r.Handle("/metrics", s.metrics).Methods("GET")
+		}
+	}
+
+	api := r.PathPrefix("/api").Subrouter()
+
+	// Public routes
+	api.HandleFunc("/register", h.Register).Methods("POST")
+	api.HandleFunc("/login", h.Login).Methods("POST")
+	api.HandleFunc("/login/mfa", h.LoginMFA).Methods("POST")
+	api.HandleFunc("/login/passkey/options", h.BeginPasskeyLogin).Methods("POST")
+	api.HandleFunc("/login/passkey", h.LoginPasskey).Methods("POST")
+	api.HandleFunc("/login/passkey/enroll/options", h.BeginPasskeyEnrollment).Methods("POST")
+	api.HandleFunc("/login/passkey/enroll", h.EnrollPasskey).Methods("POST")
+	api.HandleFunc("/token/refresh", h.RefreshToken).Methods("POST")
+	api.HandleFunc("/invitations/accept", h.AcceptInvitation).Methods("POST")
+	api.HandleFunc("/email/verify", h.VerifyEmail).Methods("POST")
+	api.HandleFunc("/email/resend", h.ResendVerification).Methods("POST")
+	api.HandleFunc("/password/forgot", h.ForgotPassword).Methods("POST")
+	api.HandleFunc("/password/reset", h.ResetPassword).Methods("POST")
+
+	// Everything else requires a valid access token or session cookie
+	authed := api.NewRoute().Subrouter()
+	authed.Use(s.tokens.Middleware)
+
+	// Sessions
+	authed.HandleFunc("/logout", h.Logout).Methods("POST")
+	authed.HandleFunc("/logout/all", h.LogoutAll).Methods("POST")
+	authed.HandleFunc("/sessions", h.GetSessions).Methods("GET")
+	authed.HandleFunc("/sessions/{id}", h.RevokeSession).Methods("DELETE")
+
+	// Users. /users/search is registered before /users/{id} so it is not
+	// taken for a user ID.
+	authed.HandleFunc("/users", h.GetUsers).Methods("GET")
+	authed.HandleFunc("/users", h.CreateUser).Methods("POST")
+	authed.HandleFunc("/users/search", h.SearchUsers).Methods("GET")
+	authed.HandleFunc("/users/{id}", h.GetUser).Methods("GET")
+	authed.HandleFunc("/users/{id}", h.UpdateUser).Methods("PUT")
+	authed.HandleFunc("/users/{id}", h.DeleteUser).Methods("DELETE")
+	authed.HandleFunc("/users/{id}/roles", h.SetUserRoles).Methods("PUT")
+	authed.HandleFunc("/users/{id}/invitation", h.ResendInvitation).Methods("POST")
+	authed.HandleFunc("/users/{id}/mfa", h.ResetUserMFA).Methods("DELETE")
+
+	// Profile of the authenticated user
+	authed.HandleFunc("/profile", h.GetProfile).Methods("GET")
+	authed.HandleFunc("/profile", h.UpdateProfile).Methods("PUT")
+	authed.HandleFunc("/profile/password", h.ChangePassword).Methods("PUT")
+	authed.HandleFunc("/profile/mfa", h.GetMFAStatus).Methods("GET")
+	authed.HandleFunc("/profile/mfa", h.DisableMFA).Methods("DELETE")
+	authed.HandleFunc("/profile/mfa/enroll", h.EnrollMFA).Methods("POST")
+	authed.HandleFunc("/profile/mfa/confirm", h.ConfirmMFA).Methods("POST")
+	authed.HandleFunc("/profile/mfa/recovery-codes", h.RegenerateRecoveryCodes).Methods("POST")
+	authed.HandleFunc("/profile/passkeys", h.GetPasskeys).Methods("GET")
+	authed.HandleFunc("/profile/passkeys", h.CreatePasskey).Methods("POST")
+	authed.HandleFunc("/profile/passkeys/options", h.BeginPasskeyRegistration).Methods("POST")
+	authed.HandleFunc("/profile/passkeys/{id}", h.DeletePasskey).Methods("DELETE")
+
+	// Dashboard
+	authed.HandleFunc("/dashboard/stats", h.GetDashboardStats).Methods("GET")
+
+	// Roles and permissions
+	authed.HandleFunc("/permissions", h.GetPermissions).Methods("GET")
+	authed.HandleFunc("/roles", h.GetRoles).Methods("GET")
+	authed.HandleFunc("/roles", h.CreateRole).Methods("POST")
+	authed.HandleFunc("/roles/{id}", h.GetRole).Methods("GET")
+	authed.HandleFunc("/roles/{id}", h.UpdateRole).Methods("PUT")
+	authed.HandleFunc("/roles/{id}", h.DeleteRole).Methods("DELETE")
+
+	return r
+}
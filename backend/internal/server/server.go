@@ -0,0 +1,347 @@
+// Package server assembles the UMS HTTP API from the internal packages.
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/yourusername/ums/backend/internal/auth"
+	"github.com/yourusername/ums/backend/internal/config"
+	umsdb "github.com/yourusername/ums/backend/internal/db"
+	"github.com/yourusername/ums/backend/internal/handlers"
+	"github.com/yourusername/ums/backend/internal/health"
+	"github.com/yourusername/ums/backend/internal/logging"
+	"github.com/yourusername/ums/backend/internal/mail"
+	"github.com/yourusername/ums/backend/internal/metrics"
+	"github.com/yourusername/ums/backend/internal/models"
+	"github.com/yourusername/ums/backend/internal/search"
+	"github.com/yourusername/ums/backend/internal/secretbox"
+	"github.com/yourusername/ums/backend/internal/session"
+)
+
+// Server is the UMS API server
+type Server struct {
+	cfg      *config.Config
+	db       *sql.DB
+	tokens   *auth.TokenManager
+	sessions *session.Manager
+	// The stores below hold records the cleanup worker expires
+	refreshTokens      models.RefreshTokenStore
+	invitations        models.InvitationStore
+	emailVerifications models.EmailVerificationStore
+	passwordResets     models.PasswordResetStore
+	mfa                models.MFAStore
+	passkeys           models.PasskeyStore
+	// mail delivers the emails queued by the handlers
+	mail *mail.Queue
+	// handlers serves the API routes
+	handlers *handlers.Handlers
+	health   *health.Registry
+	// metrics serves /metrics; nil when metrics are disabled
+	metrics http.Handler
+	handler http.Handler
+	// ready is 1 while the server accepts traffic
+	ready int32
+}
+
+// New wires the internal packages to db and builds the API router
+func New(db *sql.DB, cfg *config.Config) (*Server, error) {
+	tokens, err := auth.LoadTokenManager(cfg.Auth.JWTKeys, cfg.Auth.TokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := session.NewManager(session.NewPostgresStore(db))
+	sessions.Secure = cfg.Server.CookieSecure
+	tokens.SetSessionBackend(sessions)
+
+	userRepo := models.NewPostgresUserRepository(db)
+	roles := models.NewPostgresRoleRepository(db)
+	refreshTokens := models.NewPostgresRefreshTokenStore(db)
+	mfaStore := models.NewPostgresMFAStore(db)
+	invitations := models.NewPostgresInvitationStore(db)
+	emailVerifications := models.NewPostgresEmailVerificationStore(db)
+	passwordResets := models.NewPostgresPasswordResetStore(db)
+	passkeys := models.NewPostgresPasskeyStore(db)
+
+	// Without pg_trgm, near misses are found by substring only
+	trigrams, err := search.TrigramsAvailable(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+	searcher := search.NewPostgresSearcher(db)
+	if !trigrams {
+		slog.Warn("pg_trgm is not installed; user search finds near misses by substring only")
+		searcher = search.NewPostgresFullTextSearcher(db)
+	}
+
+	mailer, err := mail.New(mail.Options{
+		Driver:       cfg.Mail.Driver,
+		From:         cfg.Mail.From,
+		SMTPAddr:     cfg.Mail.SMTPAddr,
+		SMTPUsername: cfg.Mail.SMTPUsername,
+		SMTPPassword: cfg.Mail.SMTPPassword,
+		Dir:          cfg.Mail.Dir,
+	})
+	if err != nil {
+		return nil, err
+	}
+	mailQueue := mail.NewQueue(mailer, mail.DefaultQueueSize)
+
+	mfaConfig := handlers.MFAConfig{Issuer: cfg.Auth.MFAIssuer}
+	if cfg.Auth.EncryptionKey != "" {
+		key, err := secretbox.ParseKey(cfg.Auth.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		if mfaConfig.Box, err = secretbox.New(key); err != nil {
+			return nil, err
+		}
+	} else {
+		slog.Warn("auth.encryption_key is not set; two-factor authentication is unavailable")
+	}
+
+	rp, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.Auth.PasskeyRPID,
+		RPDisplayName: cfg.Auth.MFAIssuer,
+		RPOrigins:     cfg.Auth.PasskeyOrigins,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := handlers.New(handlers.Deps{
+		Users:              userRepo,
+		Roles:              roles,
+		RefreshTokens:      refreshTokens,
+		LoginHistory:       models.NewPostgresLoginHistory(db),
+		Stats:              models.NewPostgresStatsRepository(db),
+		SecurityLog:        models.NewPostgresSecurityLog(db),
+		MFAStore:           mfaStore,
+		PasskeyStore:       passkeys,
+		Invitations:        invitations,
+		EmailVerifications: emailVerifications,
+		PasswordResets:     passwordResets,
+		Searcher:           searcher,
+		Tokens:             tokens,
+		Sessions:           sessions,
+		// Role grants are cached briefly so edits propagate across instances
+		Policy: auth.NewPolicy(func() (map[string][]string, error) {
+			return roles.Grants(context.Background())
+		}, 30*time.Second),
+		MailQueue: mailQueue,
+		EmailVerification: handlers.EmailVerification{
+			Required: cfg.Auth.RequireVerifiedEmail,
+			TTL:      cfg.Auth.VerificationTTL,
+			LinkURL:  strings.TrimRight(cfg.Mail.AppURL, "/") + "/verify-email",
+		},
+		PasswordReset: handlers.PasswordReset{
+			TTL:     cfg.Auth.PasswordResetTTL,
+			LinkURL: strings.TrimRight(cfg.Mail.AppURL, "/") + "/reset-password",
+		},
+		MFA:      mfaConfig,
+		Passkeys: handlers.PasskeyConfig{WebAuthn: rp, RequireForAdmins: cfg.Auth.RequireAdminPasskey},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := umsdb.NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		cfg:                cfg,
+		db:                 db,
+		tokens:             tokens,
+		sessions:           sessions,
+		refreshTokens:      refreshTokens,
+		invitations:        invitations,
+		emailVerifications: emailVerifications,
+		passwordResets:     passwordResets,
+		mfa:                mfaStore,
+		passkeys:           passkeys,
+		mail:               mailQueue,
+		handlers:           h,
+	}
+	s.health = health.NewRegistry(cfg.Health.Timeout)
+	s.health.Register(health.CheckerFunc("server", func(ctx context.Context) error {
+		if !s.Ready() {
+			return errors.New("shutting down")
+		}
+		return nil
+	}))
+	s.health.Register(health.Database(db))
+	s.health.Register(health.Migrations(migrator))
+	s.health.Register(health.Pool(db, cfg.Health.MaxPoolUsage))
+
+	if cfg.Metrics.Enabled {
+		s.metrics = metrics.Handler(metrics.NewRegistry(db))
+	}
+
+	s.handler = cors(cfg.Server.CORSOrigins, limitBody(int64(cfg.Server.MaxBodyBytes), s.routes()))
+	if cfg.Metrics.Enabled {
+		s.handler = metrics.Instrument(s.handler)
+	}
+	s.handler = logging.Middleware("/healthz", "/readyz", "/metrics")(s.handler)
+	return s, nil
+}
+
+// Health returns the readiness checks, so further dependencies can register
+func (s *Server) Health() *health.Registry {
+	return s.health
+}
+
+// Handler returns the API handler
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Ready reports whether the server accepts traffic. It turns false as soon
+// as shutdown begins.
+func (s *Server) Ready() bool {
+	return atomic.LoadInt32(&s.ready) == 1
+}
+
+// Run serves the API and runs the background workers until ctx is
+// cancelled, then shuts down in order: report not ready, keep serving for the
+// drain delay, stop accepting connections and wait for in-flight requests up
+// to the shutdown timeout, then stop the workers, letting the mail worker
+// deliver what is still queued. The database may be closed once Run returns.
+func (s *Server) Run(ctx context.Context) error {
+	// Listen before reporting ready so a taken port fails immediately
+	ln, err := net.Listen("tcp", s.cfg.Server.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve is Run on a listener the caller opened. It closes ln.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	cfg := s.cfg.Server
+	srv := &http.Server{
+		Handler:      s.handler,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	// Metrics on a separate admin address are kept off the API listener
+	var admin *http.Server
+	var adminLn net.Listener
+	var err error
+	if s.metrics != nil && s.cfg.Metrics.Addr != "" {
+		if adminLn, err = net.Listen("tcp", s.cfg.Metrics.Addr); err != nil {
+			ln.Close()
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", s.metrics)
+		admin = &http.Server{
+			Handler:      mux,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+			IdleTimeout:  cfg.IdleTimeout,
+		}
+	}
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	s.startWorkers(workerCtx, &workers)
+
+	errc := make(chan error, 2)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+	if admin != nil {
+		go func() {
+			errc <- admin.Serve(adminLn)
+		}()
+		slog.Info("serving metrics", "addr", adminLn.Addr().String())
+	}
+	atomic.StoreInt32(&s.ready, 1)
+	slog.Info("server is running", "addr", ln.Addr().String())
+
+	select {
+	case err := <-errc:
+		atomic.StoreInt32(&s.ready, 0)
+		srv.Close()
+		if admin != nil {
+			admin.Close()
+		}
+		stopWorkers()
+		workers.Wait()
+		return err
+	case <-ctx.Done():
+	}
+
+	atomic.StoreInt32(&s.ready, 0)
+	slog.Info("server is shutting down", "drain_delay", cfg.DrainDelay.String())
+	time.Sleep(cfg.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("requests still running after shutdown timeout, closing connections", "shutdown_timeout", cfg.ShutdownTimeout.String())
+		err = srv.Close()
+	}
+	// The admin listener closes last so metrics stay available while the API drains
+	if admin != nil {
+		admin.Close()
+	}
+
+	stopWorkers()
+	workers.Wait()
+	slog.Info("server stopped")
+	return err
+}
+
+// limitBody caps request bodies at max bytes. Reading past the limit fails
+// with *http.MaxBytesError, which handlers answer with 413.
+func limitBody(max int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cors adds CORS headers to every response and answers preflight requests.
+// Allowed origins are echoed back with credentials allowed so the session
+// cookie works cross-origin; other origins get a wildcard without credentials.
+func cors(allowed []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Allow-Origin", "*")
+		for _, o := range allowed {
+			if origin != "" && origin == strings.TrimSpace(o) {
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Set("Access-Control-Allow-Credentials", "true")
+				break
+			}
+		}
+		h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		h.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+logging.RequestIDHeader)
+		h.Set("Access-Control-Expose-Headers", logging.RequestIDHeader)
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
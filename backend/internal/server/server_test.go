@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/config"
+	"github.com/yourusername/ums/backend/internal/mail"
+)
+
+// events records the shutdown steps in the order they happen
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = append(e.list, event)
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.list...)
+}
+
+// recordingMailer records the messages it sends
+type recordingMailer struct {
+	events *events
+}
+
+func (m recordingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.events.add("mail sent to " + msg.To)
+	return nil
+}
+
+func TestServeGracefulShutdown(t *testing.T) {
+	var ev events
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	cfg := &config.Config{}
+	cfg.Server.DrainDelay = 50 * time.Millisecond
+	cfg.Server.ShutdownTimeout = 5 * time.Second
+	s := &Server{cfg: cfg, mail: mail.NewQueue(recordingMailer{&ev}, 0)}
+	// The in-flight request queues an email when it completes, as the
+	// handlers do, so the mail worker must outlive the HTTP drain
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		s.mail.Enqueue(func(ctx context.Context, m mail.Mailer) {
+			m.Send(ctx, mail.Message{To: "alice@example.com", Subject: "Hello"})
+		})
+		ev.add("request done")
+		io.WriteString(w, "ok")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx, ln)
+	}()
+
+	type response struct {
+		status int
+		body   string
+		err    error
+	}
+	responses := make(chan response, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			responses <- response{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- response{resp.StatusCode, string(body), err}
+	}()
+	<-started
+	if !s.Ready() {
+		t.Error("server not ready while serving")
+	}
+
+	cancel()
+	waitFor(t, "server to report not ready", func() bool { return !s.Ready() })
+	// Shutdown closes the listener while the request is still in flight
+	waitFor(t, "listener to close", func() bool {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			return true
+		}
+		conn.Close()
+		return false
+	})
+	ev.add("stopped accepting")
+	select {
+	case err := <-served:
+		t.Fatalf("Serve returned with a request in flight: %v", err)
+	default:
+	}
+
+	close(release)
+	resp := <-responses
+	if resp.err != nil || resp.status != http.StatusOK || resp.body != "ok" {
+		t.Fatalf("in-flight request = %d %q, %v; want 200 ok", resp.status, resp.body, resp.err)
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("Serve = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return")
+	}
+	// The database is closed by the caller once Serve returns
+	ev.add("db closed")
+
+	want := []string{"stopped accepting", "request done", "mail sent to alice@example.com", "db closed"}
+	if got := ev.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("shutdown steps = %q, want %q", got, want)
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// startWorkers starts the background workers, which run until ctx is
+// cancelled and are tracked by wg. The mail worker then drains its queue
+// before it returns.
+func (s *Server) startWorkers(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.mail.Run(ctx)
+	}()
+
+	if interval := s.cfg.Server.CleanupInterval; interval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.cleanup(ctx, interval)
+		}()
+	}
+}
+
+// cleanup deletes expired sessions, refresh tokens, invitations, email
+// verifications, password resets, MFA challenges and passkey ceremonies on
+// start and then every interval
+func (s *Server) cleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.deleteExpired(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) deleteExpired(ctx context.Context) {
+	now := time.Now()
+	tasks := []struct {
+		what string
+		run  func() (int64, error)
+	}{
+		{"sessions", func() (int64, error) { return s.sessions.Store().DeleteExpired(ctx, now) }},
+		{"refresh tokens", func() (int64, error) { return s.refreshTokens.DeleteExpired(ctx, now) }},
+		{"invitations", func() (int64, error) { return s.invitations.DeleteExpired(ctx, now) }},
+		{"email verifications", func() (int64, error) { return s.emailVerifications.DeleteExpired(ctx, now) }},
+		{"password resets", func() (int64, error) { return s.passwordResets.DeleteExpired(ctx, now) }},
+		{"mfa challenges", func() (int64, error) { return s.mfa.DeleteExpiredChallenges(ctx, now) }},
+		{"passkey ceremonies", func() (int64, error) { return s.passkeys.DeleteExpiredCeremonies(ctx, now) }},
+	}
+
+	for _, t := range tasks {
+		if ctx.Err() != nil {
+			return
+		}
+		n, err := t.run()
+		if err != nil {
+			slog.Error("failed to delete expired records", "error", err, "kind", t.what)
+			continue
+		}
+		if n > 0 {
+			slog.Info("deleted expired records", "kind", t.what, "count", n)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package session
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/ums/backend/internal/auth"
+)
+
+// CookieName is the name of the session cookie
+const CookieName = "ums_session"
+
+const (
+	// DefaultTTL is the absolute lifetime of a session
+	DefaultTTL = 30 * 24 * time.Hour
+	// DefaultIdleTimeout ends sessions that have not been used for this long
+	DefaultIdleTimeout = 7 * 24 * time.Hour
+	// touchInterval limits how often last-seen timestamps are written
+	touchInterval = time.Minute
+	// maxUserAgentLength matches the user_agent column
+	maxUserAgentLength = 255
+)
+
+// Manager creates sessions, manages the session cookie and checks sessions
+// referenced by access tokens. It implements auth.SessionBackend.
+type Manager struct {
+	store Store
+	now   func() time.Time
+
+	// TTL is the absolute session lifetime
+	TTL time.Duration
+	// IdleTimeout ends sessions without activity for this long; zero disables it
+	IdleTimeout time.Duration
+	// Secure marks the cookie as HTTPS-only. It is always set on TLS requests.
+	Secure bool
+}
+
+// NewManager returns a manager with default lifetimes storing sessions in store
+func NewManager(store Store) *Manager {
+	return &Manager{
+		store:       store,
+		now:         time.Now,
+		TTL:         DefaultTTL,
+		IdleTimeout: DefaultIdleTimeout,
+	}
+}
+
+// Store returns the underlying session store
+func (m *Manager) Store() Store {
+	return m.store
+}
+
+// Start creates a session for a user holding roles and sets the session cookie
+func (m *Manager) Start(w http.ResponseWriter, r *http.Request, userID string, roles []string) (Session, error) {
+	token, id, err := newToken()
+	if err != nil {
+		return Session{}, err
+	}
+
+	now := m.now()
+	s := Session{
+		ID:         id,
+		UserID:     userID,
+		Roles:      roles,
+		IP:         ClientIP(r),
+		UserAgent:  ClientUserAgent(r),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(m.TTL),
+	}
+	if err := m.store.Create(r.Context(), s); err != nil {
+		return Session{}, err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    token,
+		Path:     "/api",
+		Expires:  s.ExpiresAt,
+		HttpOnly: true,
+		Secure:   m.Secure || r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return s, nil
+}
+
+// Lookup returns an active session, ending it if it has been idle too long
+// and recording activity otherwise
+func (m *Manager) Lookup(ctx context.Context, id string) (Session, error) {
+	s, err := m.store.Get(ctx, id)
+	if err != nil {
+		return s, err
+	}
+
+	now := m.now()
+	if m.IdleTimeout > 0 && now.Sub(s.LastSeenAt) > m.IdleTimeout {
+		_ = m.store.Revoke(ctx, id)
+		return Session{}, ErrNotFound
+	}
+
+	if now.Sub(s.LastSeenAt) > touchInterval {
+		if err := m.store.Touch(ctx, id, now); err != nil {
+			return Session{}, err
+		}
+		s.LastSeenAt = now
+	}
+	return s, nil
+}
+
+// FromRequest returns the session identified by the request's session cookie
+func (m *Manager) FromRequest(r *http.Request) (Session, error) {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil || cookie.Value == "" {
+		return Session{}, ErrNotFound
+	}
+	return m.Lookup(r.Context(), IDFromToken(cookie.Value))
+}
+
+// ClearCookie removes the session cookie from the browser
+func (m *Manager) ClearCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/api",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   m.Secure || r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// CheckSession implements auth.SessionBackend
+func (m *Manager) CheckSession(ctx context.Context, id string) error {
+	_, err := m.Lookup(ctx, id)
+	return err
+}
+
+// CookiePrincipal implements auth.SessionBackend
+func (m *Manager) CookiePrincipal(r *http.Request) (auth.Principal, error) {
+	s, err := m.FromRequest(r)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	p := auth.NewPrincipal(s.UserID, s.Roles)
+	p.SessionID = s.ID
+	return p, nil
+}
+
+// ClientIP returns the IP address of the client that sent r
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ClientUserAgent returns the User-Agent of r, cut to fit the user_agent
+// columns
+func ClientUserAgent(r *http.Request) string {
+	return truncate(r.UserAgent(), maxUserAgentLength)
+}
+
+// truncate cuts s to at most n characters. VARCHAR limits count characters,
+// and cutting at a byte offset could split a multi-byte character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	i := 0
+	for j := range s {
+		if i == n {
+			return s[:j]
+		}
+		i++
+	}
+	return s
+}
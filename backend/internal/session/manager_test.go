@@ -0,0 +1,167 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// clock is a settable time source shared by a manager and its store
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newTestManager() (*Manager, *MemoryStore, *clock) {
+	c := &clock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = c.now
+	m := NewManager(store)
+	m.now = c.now
+	return m, store, c
+}
+
+// startSession starts a session for userID and returns it with its cookie
+func startSession(t *testing.T, m *Manager, userID string) (Session, *http.Cookie) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/login", nil)
+	r.Header.Set("User-Agent", "test-agent")
+	s, err := m.Start(w, r, userID, []string{"user"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != CookieName {
+		t.Fatalf("Start set cookies %v, want one %s cookie", cookies, CookieName)
+	}
+	return s, cookies[0]
+}
+
+func TestManagerStartAndFromRequest(t *testing.T) {
+	m, _, _ := newTestManager()
+	s, cookie := startSession(t, m, "u1")
+
+	if !cookie.HttpOnly || cookie.Path != "/api" {
+		t.Errorf("cookie = %+v, want HttpOnly on /api", cookie)
+	}
+	if cookie.Value == s.ID {
+		t.Error("cookie carries the session ID instead of the token")
+	}
+	if IDFromToken(cookie.Value) != s.ID {
+		t.Error("session ID is not derived from the cookie token")
+	}
+	if s.IP != "192.0.2.1" || s.UserAgent != "test-agent" {
+		t.Errorf("session IP/UA = %q/%q", s.IP, s.UserAgent)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/profile", nil)
+	r.AddCookie(cookie)
+	got, err := m.FromRequest(r)
+	if err != nil {
+		t.Fatalf("FromRequest: %v", err)
+	}
+	if got.ID != s.ID || got.UserID != "u1" {
+		t.Errorf("FromRequest = %+v, want session %s of u1", got, s.ID)
+	}
+
+	p, err := m.CookiePrincipal(r)
+	if err != nil {
+		t.Fatalf("CookiePrincipal: %v", err)
+	}
+	if p.UserID != "u1" || p.SessionID != s.ID {
+		t.Errorf("CookiePrincipal = %+v", p)
+	}
+}
+
+func TestManagerFromRequestWithoutCookie(t *testing.T) {
+	m, _, _ := newTestManager()
+	r := httptest.NewRequest(http.MethodGet, "/api/profile", nil)
+	if _, err := m.FromRequest(r); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FromRequest without cookie: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestManagerLookupIdleTimeout(t *testing.T) {
+	m, store, c := newTestManager()
+	m.IdleTimeout = time.Hour
+	s, _ := startSession(t, m, "u1")
+	ctx := context.Background()
+
+	c.t = c.t.Add(30 * time.Minute)
+	got, err := m.Lookup(ctx, s.ID)
+	if err != nil {
+		t.Fatalf("Lookup within idle timeout: %v", err)
+	}
+	if !got.LastSeenAt.Equal(c.t) {
+		t.Errorf("LastSeenAt = %v, want it touched to %v", got.LastSeenAt, c.t)
+	}
+
+	c.t = c.t.Add(2 * time.Hour)
+	if _, err := m.Lookup(ctx, s.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Lookup after idle timeout: err = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Get(ctx, s.ID); !errors.Is(err, ErrNotFound) {
+		t.Error("idle session was not revoked")
+	}
+}
+
+func TestManagerLookupExpired(t *testing.T) {
+	m, _, c := newTestManager()
+	m.IdleTimeout = 0
+	s, _ := startSession(t, m, "u1")
+
+	c.t = c.t.Add(m.TTL + time.Second)
+	if err := m.CheckSession(context.Background(), s.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("CheckSession after TTL: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestClientUserAgentTruncatesOnCharacters(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("User-Agent", "a"+strings.Repeat("é", 300))
+
+	ua := ClientUserAgent(r)
+	if !utf8.ValidString(ua) {
+		t.Fatalf("ClientUserAgent returned invalid UTF-8 %q", ua)
+	}
+	if n := utf8.RuneCountInString(ua); n != maxUserAgentLength {
+		t.Errorf("ClientUserAgent kept %d characters, want %d", n, maxUserAgentLength)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"", 3, ""},
+		{"abc", 3, "abc"},
+		{"abcd", 3, "abc"},
+		{"héllo", 2, "hé"},
+		{"日本語", 3, "日本語"},
+		{"日本語テキスト", 2, "日本"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "[2001:db8::1]:4321"
+	if got := ClientIP(r); got != "2001:db8::1" {
+		t.Errorf("ClientIP = %q, want 2001:db8::1", got)
+	}
+	r.RemoteAddr = "not-an-address"
+	if got := ClientIP(r); got != "not-an-address" {
+		t.Errorf("ClientIP without port = %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+package session
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store kept in process memory, for tests and single-node development
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]Session
+	now      func() time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: map[string]Session{}, now: time.Now}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, s Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[s.ID] = s
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[id]
+	if !ok || !s.Active(m.now()) {
+		return Session{}, ErrNotFound
+	}
+	return s, nil
+}
+
+func (m *MemoryStore) Touch(ctx context.Context, id string, seen time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || !s.Active(m.now()) {
+		return ErrNotFound
+	}
+	s.LastSeenAt = seen
+	m.sessions[id] = s
+	return nil
+}
+
+func (m *MemoryStore) ListByUser(ctx context.Context, userID string) ([]Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := m.now()
+	var list []Session
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.Active(now) {
+			list = append(list, s)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastSeenAt.After(list[j].LastSeenAt)
+	})
+	return list, nil
+}
+
+func (m *MemoryStore) Revoke(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || !s.Active(m.now()) {
+		return ErrNotFound
+	}
+	now := m.now()
+	s.RevokedAt = &now
+	m.sessions[id] = s
+	return nil
+}
+
+func (m *MemoryStore) RevokeAllForUser(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for id, s := range m.sessions {
+		if s.UserID == userID && s.Active(now) {
+			revoked := now
+			s.RevokedAt = &revoked
+			m.sessions[id] = s
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) RevokeOthers(ctx context.Context, userID, keepID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for id, s := range m.sessions {
+		if s.UserID == userID && id != keepID && s.Active(now) {
+			revoked := now
+			s.RevokedAt = &revoked
+			m.sessions[id] = s
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for id, s := range m.sessions {
+		if s.ExpiresAt.Before(cutoff) || (s.RevokedAt != nil && s.RevokedAt.Before(cutoff)) {
+			delete(m.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
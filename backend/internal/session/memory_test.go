@@ -0,0 +1,99 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreListByUser(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now()
+	for _, s := range []Session{
+		{ID: "old", UserID: "u1", LastSeenAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{ID: "new", UserID: "u1", LastSeenAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ID: "expired", UserID: "u1", LastSeenAt: now, ExpiresAt: now.Add(-time.Second)},
+		{ID: "other", UserID: "u2", LastSeenAt: now, ExpiresAt: now.Add(time.Hour)},
+	} {
+		if err := store.Create(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := store.ListByUser(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != "new" || list[1].ID != "old" {
+		t.Errorf("ListByUser = %v, want [new old]", ids(list))
+	}
+}
+
+func TestMemoryStoreRevoke(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	exp := time.Now().Add(time.Hour)
+	for _, id := range []string{"a", "b", "c"} {
+		store.Create(ctx, Session{ID: id, UserID: "u1", ExpiresAt: exp})
+	}
+	store.Create(ctx, Session{ID: "d", UserID: "u2", ExpiresAt: exp})
+
+	if err := store.Revoke(ctx, "a"); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if err := store.Revoke(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Revoke: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Revoke(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Revoke unknown: err = %v, want ErrNotFound", err)
+	}
+
+	if err := store.RevokeOthers(ctx, "u1", "b"); err != nil {
+		t.Fatal(err)
+	}
+	list, _ := store.ListByUser(ctx, "u1")
+	if len(list) != 1 || list[0].ID != "b" {
+		t.Errorf("after RevokeOthers sessions = %v, want [b]", ids(list))
+	}
+
+	if err := store.RevokeAllForUser(ctx, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := store.ListByUser(ctx, "u1"); len(list) != 0 {
+		t.Errorf("after RevokeAllForUser sessions = %v, want none", ids(list))
+	}
+	if _, err := store.Get(ctx, "d"); err != nil {
+		t.Errorf("session of another user was revoked: %v", err)
+	}
+}
+
+func TestMemoryStoreDeleteExpired(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now()
+	revoked := now.Add(-2 * time.Hour)
+	store.Create(ctx, Session{ID: "expired", ExpiresAt: now.Add(-2 * time.Hour)})
+	store.Create(ctx, Session{ID: "revoked", ExpiresAt: now.Add(time.Hour), RevokedAt: &revoked})
+	store.Create(ctx, Session{ID: "active", ExpiresAt: now.Add(time.Hour)})
+
+	n, err := store.DeleteExpired(ctx, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("DeleteExpired removed %d sessions, want 2", n)
+	}
+	if _, err := store.Get(ctx, "active"); err != nil {
+		t.Errorf("active session was removed: %v", err)
+	}
+}
+
+func ids(list []Session) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = s.ID
+	}
+	return out
+}
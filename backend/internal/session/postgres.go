@@ -0,0 +1,126 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgresStore is a Store backed by the sessions table
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore returns a store using db
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (p *PostgresStore) Create(ctx context.Context, s Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, roles, ip, user_agent, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := p.db.ExecContext(ctx, query,
+		s.ID, s.UserID, pq.Array(s.Roles), s.IP, s.UserAgent, s.CreatedAt, s.LastSeenAt, s.ExpiresAt,
+	)
+	return err
+}
+
+func (p *PostgresStore) Get(ctx context.Context, id string) (Session, error) {
+	query := `
+		SELECT id, user_id, roles, ip, user_agent, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > $2
+	`
+
+	var s Session
+	err := p.db.QueryRowContext(ctx, query, id, time.Now()).Scan(
+		&s.ID, &s.UserID, pq.Array(&s.Roles), &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return s, ErrNotFound
+	}
+	return s, err
+}
+
+func (p *PostgresStore) Touch(ctx context.Context, id string, seen time.Time) error {
+	query := `UPDATE sessions SET last_seen_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+
+	return expectRow(p.db.ExecContext(ctx, query, seen, id))
+}
+
+func (p *PostgresStore) ListByUser(ctx context.Context, userID string) ([]Session, error) {
+	query := `
+		SELECT id, user_id, roles, ip, user_agent, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Session
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(
+			&s.ID, &s.UserID, pq.Array(&s.Roles), &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+func (p *PostgresStore) Revoke(ctx context.Context, id string) error {
+	query := `UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+
+	return expectRow(p.db.ExecContext(ctx, query, time.Now(), id))
+}
+
+func (p *PostgresStore) RevokeAllForUser(ctx context.Context, userID string) error {
+	query := `UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+
+	_, err := p.db.ExecContext(ctx, query, time.Now(), userID)
+	return err
+}
+
+func (p *PostgresStore) RevokeOthers(ctx context.Context, userID, keepID string) error {
+	query := `UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND id <> $3 AND revoked_at IS NULL`
+
+	_, err := p.db.ExecContext(ctx, query, time.Now(), userID, keepID)
+	return err
+}
+
+func (p *PostgresStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM sessions WHERE expires_at < $1 OR revoked_at < $1`
+
+	result, err := p.db.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// expectRow turns an update that matched no rows into ErrNotFound
+func expectRow(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Package session implements server-side login sessions.
+//
+// The browser holds an opaque random token in an HttpOnly cookie; the server
+// only stores its SHA-256 hash, which doubles as the public session ID used
+// in access tokens and the sessions API. Knowing a session ID therefore does
+// not allow anyone to impersonate the session.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned for sessions that do not exist, expired or were revoked
+var ErrNotFound = errors.New("session not found")
+
+// Session is a login session of a user
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Roles      []string   `json:"-"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+// Active reports whether the session can still be used at now
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// Store persists sessions
+type Store interface {
+	// Create stores a new session
+	Create(ctx context.Context, s Session) error
+	// Get returns an active session or ErrNotFound
+	Get(ctx context.Context, id string) (Session, error)
+	// Touch records activity on a session
+	Touch(ctx context.Context, id string, seen time.Time) error
+	// ListByUser returns the active sessions of a user, most recently seen first
+	ListByUser(ctx context.Context, userID string) ([]Session, error)
+	// Revoke ends a session. Revoking an unknown session returns ErrNotFound.
+	Revoke(ctx context.Context, id string) error
+	// RevokeAllForUser ends every active session of a user
+	RevokeAllForUser(ctx context.Context, userID string) error
+	// RevokeOthers ends every active session of a user except keepID
+	RevokeOthers(ctx context.Context, userID, keepID string) error
+	// DeleteExpired removes sessions that expired or were revoked before cutoff
+	DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// newToken returns a random cookie token and the session ID derived from it
+func newToken() (token, id string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, IDFromToken(token), nil
+}
+
+// IDFromToken returns the session ID for a cookie token
+func IDFromToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,41 @@
+package validate
+
+import (
+	"net/mail"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+)
+
+// email accepts a bare address such as jane@example.com: no display name,
+// and a domain with at least one dot
+func email(value reflect.Value, _ string) *Violation {
+	invalid := &Violation{apierror.FieldInvalid, "Must be a valid email address."}
+
+	s := value.String()
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return invalid
+	}
+	domain := s[strings.LastIndexByte(s, '@')+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return invalid
+	}
+	return nil
+}
+
+// username accepts letters and digits in any script, combining marks and
+// '.', '_' and '-', starting with a letter or digit
+func username(value reflect.Value, _ string) *Violation {
+	for i, r := range value.String() {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+		case i > 0 && (unicode.Is(unicode.M, r) || r == '.' || r == '_' || r == '-'):
+		default:
+			return &Violation{apierror.FieldInvalid, "May only contain letters, digits, '.', '_' and '-', and must start with a letter or digit."}
+		}
+	}
+	return nil
+}
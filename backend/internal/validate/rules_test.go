@@ -0,0 +1,55 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEmail(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"jane@example.com", true},
+		{"jane.doe+tag@mail.example.co.uk", true},
+		{"jane@localhost", false},
+		{"Jane <jane@example.com>", false},
+		{"<jane@example.com>", false},
+		{" jane@example.com", false},
+		{"jane@.example.com", false},
+		{"jane@example.com.", false},
+		{"jane@", false},
+		{"jane", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := email(reflect.ValueOf(tt.in), "") == nil; got != tt.want {
+			t.Errorf("email(%q) valid = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUsername(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"jane", true},
+		{"jane.doe_99-x", true},
+		{"42jane", true},
+		{"Jos\u00e9", true},
+		{"Jose\u0301", true}, // combining acute accent
+		{"ユーザー", true},
+		{"_jane", false},
+		{".jane", false},
+		{"\u0301jane", false},
+		{"jane doe", false},
+		{"jane@doe", false},
+		{"jane!", false},
+	}
+	for _, tt := range tests {
+		if got := username(reflect.ValueOf(tt.in), "") == nil; got != tt.want {
+			t.Errorf("username(%q) valid = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
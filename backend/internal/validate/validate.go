@@ -0,0 +1,173 @@
+// Package validate checks request structs against rules declared in struct
+// tags and reports every violation at once.
+//
+// Rules are listed in the validate tag, separated by commas, and fields are
+// reported under their JSON name:
+//
+//	type Request struct {
+//		Username string `json:"username" validate:"required,min=3,max=50,username"`
+//		Password string `json:"password" validate:"omitempty,min=8,max=128"`
+//	}
+//
+// Built-in rules are required, omitempty, min, max, email and username.
+// Further rules can be added with Register.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+)
+
+// Rule checks value, the field a rule applies to, against param, the text
+// after "=" in the tag. It returns nil if the value is valid.
+type Rule func(value reflect.Value, param string) *Violation
+
+// Violation is a failed rule: a field-level error code such as too_short
+// and a message for the user
+type Violation struct {
+	Code    string
+	Message string
+}
+
+// Normalizer is implemented by requests that clean up their fields, e.g.
+// trimming whitespace, before they are validated
+type Normalizer interface {
+	Normalize()
+}
+
+var (
+	mu    sync.RWMutex
+	rules = map[string]Rule{
+		"required": required,
+		"min":      minLength,
+		"max":      maxLength,
+		"email":    email,
+		"username": username,
+	}
+)
+
+// Register adds a rule usable in validate tags, replacing any rule of the
+// same name
+func Register(name string, rule Rule) {
+	mu.Lock()
+	defer mu.Unlock()
+	rules[name] = rule
+}
+
+// Struct normalizes v if it is a Normalizer and checks the exported fields
+// of the struct v points to. It returns an *apierror.Error listing every
+// violation, or nil. It panics if a tag names an unknown rule.
+func Struct(v any) error {
+	if n, ok := v.(Normalizer); ok {
+		n.Normalize()
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: %T is not a struct", v))
+	}
+
+	var errs []apierror.FieldError
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag, ok := f.Tag.Lookup("validate")
+		if !ok || !f.IsExported() {
+			continue
+		}
+		if violation := check(rv.Field(i), tag); violation != nil {
+			errs = append(errs, apierror.FieldError{Field: fieldName(f), Code: violation.Code, Message: violation.Message})
+		}
+	}
+
+	if len(errs) > 0 {
+		return apierror.Validation(errs...)
+	}
+	return nil
+}
+
+// check applies the rules in tag to value and returns the first violation
+func check(value reflect.Value, tag string) *Violation {
+	for _, spec := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(spec), "=")
+		if name == "omitempty" {
+			if value.IsZero() {
+				return nil
+			}
+			continue
+		}
+
+		mu.RLock()
+		rule, ok := rules[name]
+		mu.RUnlock()
+		if !ok {
+			panic("validate: unknown rule " + name)
+		}
+		if violation := rule(value, param); violation != nil {
+			return violation
+		}
+	}
+	return nil
+}
+
+// fieldName returns the JSON name of f
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+func required(value reflect.Value, _ string) *Violation {
+	if value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0) {
+		return &Violation{apierror.FieldRequired, "This field is required."}
+	}
+	return nil
+}
+
+// length is the number of characters of a string, which is what VARCHAR(n)
+// limits, or the number of elements of a slice
+func length(value reflect.Value) int {
+	if value.Kind() == reflect.String {
+		return utf8.RuneCountInString(value.String())
+	}
+	return value.Len()
+}
+
+func unit(value reflect.Value) string {
+	if value.Kind() == reflect.String {
+		return "characters"
+	}
+	return "items"
+}
+
+func minLength(value reflect.Value, param string) *Violation {
+	n := intParam("min", param)
+	if length(value) < n {
+		return &Violation{apierror.FieldTooShort, fmt.Sprintf("Must be at least %d %s.", n, unit(value))}
+	}
+	return nil
+}
+
+func maxLength(value reflect.Value, param string) *Violation {
+	n := intParam("max", param)
+	if length(value) > n {
+		return &Violation{apierror.FieldTooLong, fmt.Sprintf("Must be at most %d %s.", n, unit(value))}
+	}
+	return nil
+}
+
+func intParam(rule, param string) int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		panic(fmt.Sprintf("validate: %s needs an integer, got %q", rule, param))
+	}
+	return n
+}
@@ -0,0 +1,153 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/ums/backend/internal/apierror"
+)
+
+type signup struct {
+	Username string   `json:"username" validate:"required,min=3,max=10,username"`
+	Email    string   `json:"email,omitempty" validate:"required,email"`
+	Password string   `json:"password" validate:"omitempty,min=8"`
+	Roles    []string `json:"roles" validate:"omitempty,max=2"`
+	Nickname string   `validate:"max=5"`
+	Ignored  string   `json:"-" validate:"max=1"`
+	Untagged string   `json:"untagged"`
+	hidden   string   `validate:"required"`
+}
+
+func (s *signup) Normalize() {
+	s.Username = strings.TrimSpace(s.Username)
+	s.Email = strings.ToLower(strings.TrimSpace(s.Email))
+}
+
+// fields returns the field errors of err, which must be a validation
+// *apierror.Error
+func fields(t *testing.T, err error) []apierror.FieldError {
+	t.Helper()
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != apierror.CodeValidationFailed {
+		t.Fatalf("error = %v, want a validation error", err)
+	}
+	return apiErr.Fields
+}
+
+func TestStructValid(t *testing.T) {
+	req := &signup{Username: "  jane ", Email: " Jane@Example.com", Untagged: strings.Repeat("x", 100)}
+	if err := Struct(req); err != nil {
+		t.Fatalf("Struct() = %v", err)
+	}
+	if req.Username != "jane" || req.Email != "jane@example.com" {
+		t.Errorf("not normalized: %q, %q", req.Username, req.Email)
+	}
+}
+
+func TestStructReportsEveryField(t *testing.T) {
+	req := &signup{
+		Username: "j",
+		Password: "short",
+		Roles:    []string{"a", "b", "c"},
+		Nickname: "toolong",
+		Ignored:  "too long",
+	}
+	want := []apierror.FieldError{
+		{Field: "username", Code: apierror.FieldTooShort, Message: "Must be at least 3 characters."},
+		{Field: "email", Code: apierror.FieldRequired, Message: "This field is required."},
+		{Field: "password", Code: apierror.FieldTooShort, Message: "Must be at least 8 characters."},
+		{Field: "roles", Code: apierror.FieldTooLong, Message: "Must be at most 2 items."},
+		{Field: "Nickname", Code: apierror.FieldTooLong, Message: "Must be at most 5 characters."},
+		{Field: "Ignored", Code: apierror.FieldTooLong, Message: "Must be at most 1 characters."},
+	}
+	if got := fields(t, Struct(req)); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestStructFirstViolationPerField(t *testing.T) {
+	got := fields(t, Struct(&signup{Username: "", Email: "jane@example.com"}))
+	if len(got) != 1 || got[0].Code != apierror.FieldRequired {
+		t.Errorf("fields = %+v, want only required for username", got)
+	}
+}
+
+func TestStructCountsCharacters(t *testing.T) {
+	// 10 characters, 20 bytes
+	if err := Struct(&signup{Username: "ÿÿÿÿÿÿÿÿÿÿ", Email: "jane@example.com"}); err != nil {
+		t.Errorf("Struct() = %v", err)
+	}
+	got := fields(t, Struct(&signup{Username: "ÿÿÿÿÿÿÿÿÿÿÿ", Email: "jane@example.com"}))
+	if len(got) != 1 || got[0].Code != apierror.FieldTooLong {
+		t.Errorf("fields = %+v, want too_long for username", got)
+	}
+}
+
+func TestStructRequiredSlice(t *testing.T) {
+	type req struct {
+		IDs []int `json:"ids" validate:"required"`
+	}
+	for _, ids := range [][]int{nil, {}} {
+		got := fields(t, Struct(&req{IDs: ids}))
+		if len(got) != 1 || got[0].Code != apierror.FieldRequired {
+			t.Errorf("Struct(%#v) fields = %+v, want required", ids, got)
+		}
+	}
+	if err := Struct(&req{IDs: []int{1}}); err != nil {
+		t.Errorf("Struct() = %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("even", func(value reflect.Value, _ string) *Violation {
+		if value.Int()%2 != 0 {
+			return &Violation{apierror.FieldInvalid, "Must be even."}
+		}
+		return nil
+	})
+	t.Cleanup(func() {
+		mu.Lock()
+		delete(rules, "even")
+		mu.Unlock()
+	})
+
+	type req struct {
+		N int `json:"n" validate:"even"`
+	}
+	if err := Struct(&req{N: 2}); err != nil {
+		t.Errorf("Struct() = %v", err)
+	}
+	got := fields(t, Struct(&req{N: 3}))
+	if want := []apierror.FieldError{{Field: "n", Code: apierror.FieldInvalid, Message: "Must be even."}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %+v, want %+v", got, want)
+	}
+}
+
+func TestStructPanics(t *testing.T) {
+	type unknownRule struct {
+		A string `validate:"shiny"`
+	}
+	type badParam struct {
+		A string `validate:"min=three"`
+	}
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"not a struct", new(string)},
+		{"unknown rule", &unknownRule{A: "x"}},
+		{"non-integer parameter", &badParam{A: "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Struct did not panic")
+				}
+			}()
+			Struct(tt.v)
+		})
+	}
+}
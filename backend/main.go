@@ -1,347 +0,0 @@
-package main
-
-import (
-	"database/sql"
-	"encoding/json"
-	"fmt"
-	"log"
-	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"github.com/gorilla/mux"
-	_ "github.com/lib/pq"
-)
-
-var db *sql.DB
-
-// SetDB sets the database connection for the models package
-func SetDB(database *sql.DB) {
-	db = database
-}
-
-// corsMiddleware adds CORS headers to all responses
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		// Check the origin of the request
-		origin := r.Header.Get("Origin")
-		allowedOrigins := []string{"http://localhost:5173", "http://localhost:5174"}
-
-		// Set the Access-Control-Allow-Origin header if the origin is allowed
-		for _, allowedOrigin := range allowedOrigins {
-			if origin == allowedOrigin {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				break
-			}
-		}
-
-		// If no origin matched, allow all origins as a fallback
-		if w.Header().Get("Access-Control-Allow-Origin") == "" {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-		}
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		// Call the next handler
-		next.ServeHTTP(w, r)
-	})
-}
-
-// User represents a user in the system
-type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Password  string    `json:"password,omitempty"`
-	Email     string    `json:"email"`
-	IsAdmin   bool      `json:"is_admin"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-}
-
-// AuthRequest represents a login or register request
-type AuthRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Email    string `json:"email,omitempty"`
-}
-
-func main() {
-	// Initialize database connection
-	connectionString := "host=localhost port=5432 user=postgres password=postgres dbname=ums_db sslmode=disable"
-	var err error
-	db, err = sql.Open("postgres", connectionString)
-	if err != nil {
-		log.Fatalf("Error opening database: %v", err)
-	}
-	defer db.Close()
-
-	err = db.Ping()
-	if err != nil {
-		log.Fatalf("Error connecting to the database: %v", err)
-	}
-
-	// Set the global db variable for the models package
-	SetDB(db)
-
-	fmt.Println("Database connection established")
-
-	// Create a new router
-	r := mux.NewRouter()
-
-	// Add CORS middleware
-	r.Use(corsMiddleware)
-
-	apiRouter := r.PathPrefix("/api").Subrouter()
-
-	// Auth routes
-	apiRouter.HandleFunc("/register", registerHandler).Methods("POST")
-	apiRouter.HandleFunc("/login", loginHandler).Methods("POST")
-
-	// User routes
-	apiRouter.HandleFunc("/users", getUsersHandler).Methods("GET")
-	apiRouter.HandleFunc("/users/{id}", getUserHandler).Methods("GET")
-	apiRouter.HandleFunc("/users/{id}", updateUserHandler).Methods("PUT")
-	apiRouter.HandleFunc("/users/{id}", deleteUserHandler).Methods("DELETE")
-
-	// Set up CORS middleware
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-
-			next.ServeHTTP(w, r)
-		})
-	})
-
-	// Create a server
-	server := &http.Server{
-		Addr:    ":8080",
-		Handler: r,
-	}
-
-	// Start the server in a goroutine
-	go func() {
-		log.Println("Server is running on :8080")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
-		}
-	}()
-
-	// Set up graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	log.Println("Server is shutting down...")
-}
-
-// registerHandler handles user registration
-func registerHandler(w http.ResponseWriter, r *http.Request) {
-	var req AuthRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	// Check if user already exists
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM users WHERE username = $1", req.Username).Scan(&count)
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
-	if count > 0 {
-		http.Error(w, "Username already exists", http.StatusBadRequest)
-		return
-	}
-
-	// Create new user
-	now := time.Now()
-	var userID string
-	err = db.QueryRow(
-		"INSERT INTO users (username, password, email, is_admin, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
-		req.Username, req.Password, req.Email, false, now, now,
-	).Scan(&userID)
-
-	if err != nil {
-		http.Error(w, "Failed to create user", http.StatusInternalServerError)
-		return
-	}
-
-	// Return the created user
-	user := User{
-		ID:        userID,
-		Username:  req.Username,
-		Email:     req.Email,
-		IsAdmin:   false,
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(user)
-}
-
-// loginHandler handles user login
-func loginHandler(w http.ResponseWriter, r *http.Request) {
-	var req AuthRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	// Find user by username
-	var user User
-	err := db.QueryRow(
-		"SELECT id, username, password, email, is_admin, created_at, updated_at FROM users WHERE username = $1",
-		req.Username,
-	).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Password,
-		&user.Email,
-		&user.IsAdmin,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
-	if err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
-		return
-	}
-
-	// Check password
-	if user.Password != req.Password {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
-		return
-	}
-
-	// Don't return the password
-	user.Password = ""
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
-}
-
-// getUsersHandler returns all users
-func getUsersHandler(w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query("SELECT id, username, email, is_admin, created_at, updated_at FROM users ORDER BY id")
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var users []User
-	for rows.Next() {
-		var user User
-		err := rows.Scan(
-			&user.ID,
-			&user.Username,
-			&user.Email,
-			&user.IsAdmin,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
-		if err != nil {
-			http.Error(w, "Database error", http.StatusInternalServerError)
-			return
-		}
-		users = append(users, user)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
-}
-
-
-// getUserHandler returns a specific user
-func getUserHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID := vars["id"]
-
-	var user User
-	err := db.QueryRow(
-		"SELECT id, username, email, is_admin, created_at, updated_at FROM users WHERE id = $1",
-		userID,
-	).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.IsAdmin,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
-}
-
-// updateUserHandler updates a user
-func updateUserHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID := vars["id"]
-
-	var user User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
-		return
-	}
-
-	now := time.Now()
-	err := db.QueryRow(
-		"UPDATE users SET username = $1, email = $2, updated_at = $3 WHERE id = $4 RETURNING id, username, email, is_admin, created_at, updated_at",
-		user.Username, user.Email, now, userID,
-	).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.IsAdmin,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
-	if err != nil {
-		http.Error(w, "Failed to update user", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
-}
-
-// deleteUserHandler deletes a user
-func deleteUserHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID := vars["id"]
-
-	_, err := db.Exec("DELETE FROM users WHERE id = $1", userID)
-	if err != nil {
-		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusNoContent)
-}
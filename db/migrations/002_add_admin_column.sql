@@ -1 +0,0 @@
-ALTER TABLE users ADD COLUMN is_admin BOOLEAN DEFAULT FALSE;
\ No newline at end of file
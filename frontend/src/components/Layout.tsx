@@ -24,6 +24,7 @@ import {
   Logout as LogoutIcon,
   Photo as PhotoIcon,
 } from '@mui/icons-material';
+import axios from 'axios';
 
 const drawerWidth = 240;
 
@@ -40,7 +41,12 @@ const Layout = () => {
     setMobileOpen(false);
   };
 
-  const handleLogout = () => {
+  const handleLogout = async () => {
+    try {
+      await axios.post('/api/logout');
+    } catch {
+      // The session is gone server-side or unreachable; log out locally anyway
+    }
     localStorage.removeItem('token');
     navigate('/login');
   };
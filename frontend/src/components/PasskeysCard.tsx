@@ -0,0 +1,110 @@
+import { useEffect, useState } from 'react';
+import { Alert, Box, Button, List, ListItem, ListItemText, Paper, TextField, Typography } from '@mui/material';
+import axios from 'axios';
+import { createPasskey } from '../passkey';
+
+interface Passkey {
+  id: string;
+  name: string;
+  backed_up: boolean;
+  created_at: string;
+  last_used_at: string | null;
+}
+
+// Registers and removes passkeys of the signed-in user
+const PasskeysCard = () => {
+  const [passkeys, setPasskeys] = useState<Passkey[]>([]);
+  const [password, setPassword] = useState('');
+  const [name, setName] = useState('');
+  const [error, setError] = useState('');
+
+  const loadPasskeys = async () => {
+    try {
+      const response = await axios.get<Passkey[]>('/api/profile/passkeys');
+      setPasskeys(response.data);
+    } catch (err: any) {
+      setError(err.response?.data?.detail || 'Failed to load passkeys.');
+    }
+  };
+
+  useEffect(() => {
+    loadPasskeys();
+  }, []);
+
+  const run = async (action: () => Promise<void>) => {
+    setError('');
+    try {
+      await action();
+    } catch (err: any) {
+      setError(err.response?.data?.errors?.[0]?.message || err.response?.data?.detail || err.message || 'Request failed.');
+    }
+  };
+
+  const handleAdd = () => run(async () => {
+    const options = await axios.post('/api/profile/passkeys/options', { password });
+    const credential = await createPasskey(options.data.options);
+    await axios.post('/api/profile/passkeys', { token: options.data.token, name, credential });
+    setPassword('');
+    setName('');
+    await loadPasskeys();
+  });
+
+  const handleRemove = (id: string) => run(async () => {
+    await axios.delete(`/api/profile/passkeys/${id}`);
+    await loadPasskeys();
+  });
+
+  return (
+    <Paper sx={{ p: 3 }}>
+      <Typography variant="h6" gutterBottom>
+        Passkeys
+      </Typography>
+      {error && <Alert severity="error" sx={{ mb: 2 }}>{error}</Alert>}
+      {passkeys.length > 0 ? (
+        <List dense>
+          {passkeys.map((p) => (
+            <ListItem
+              key={p.id}
+              secondaryAction={
+                <Button size="small" color="error" onClick={() => handleRemove(p.id)}>
+                  Remove
+                </Button>
+              }
+            >
+              <ListItemText
+                primary={p.name}
+                secondary={p.last_used_at ? `Last used ${new Date(p.last_used_at).toLocaleString()}` : 'Never used'}
+              />
+            </ListItem>
+          ))}
+        </List>
+      ) : (
+        <Typography variant="body2">
+          Sign in with your fingerprint, face or security key instead of a password.
+        </Typography>
+      )}
+      <TextField
+        fullWidth
+        label="Name"
+        margin="normal"
+        value={name}
+        onChange={(e) => setName(e.target.value)}
+      />
+      <TextField
+        fullWidth
+        label="Current Password"
+        margin="normal"
+        type="password"
+        value={password}
+        onChange={(e) => setPassword(e.target.value)}
+      />
+      <Box sx={{ mt: 2 }}>
+        <Button variant="contained" onClick={handleAdd} disabled={!password}>
+          Add Passkey
+        </Button>
+      </Box>
+    </Paper>
+  );
+};
+
+export default PasskeysCard;
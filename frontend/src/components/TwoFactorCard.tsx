@@ -0,0 +1,153 @@
+import { useEffect, useState } from 'react';
+import { Alert, Box, Button, Paper, TextField, Typography } from '@mui/material';
+import axios from 'axios';
+
+interface MFAStatus {
+  enabled: boolean;
+  recovery_codes_left: number;
+}
+
+interface Enrollment {
+  secret: string;
+  otpauth_url: string;
+  qr_code: string;
+}
+
+// Enrolls, confirms and disables TOTP two-factor authentication for the
+// signed-in user
+const TwoFactorCard = () => {
+  const [status, setStatus] = useState<MFAStatus | null>(null);
+  const [enrollment, setEnrollment] = useState<Enrollment | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+  const [password, setPassword] = useState('');
+  const [code, setCode] = useState('');
+  const [error, setError] = useState('');
+
+  const loadStatus = async () => {
+    try {
+      const response = await axios.get<MFAStatus>('/api/profile/mfa');
+      setStatus(response.data);
+    } catch (err: any) {
+      setError(err.response?.data?.detail || 'Failed to load two-factor status.');
+    }
+  };
+
+  useEffect(() => {
+    loadStatus();
+  }, []);
+
+  const run = async (action: () => Promise<void>) => {
+    setError('');
+    try {
+      await action();
+    } catch (err: any) {
+      setError(err.response?.data?.errors?.[0]?.message || err.response?.data?.detail || 'Request failed.');
+    }
+  };
+
+  const handleEnroll = () => run(async () => {
+    const response = await axios.post<Enrollment>('/api/profile/mfa/enroll', { password });
+    setEnrollment(response.data);
+    setPassword('');
+  });
+
+  const handleConfirm = () => run(async () => {
+    const response = await axios.post<{ recovery_codes: string[] }>('/api/profile/mfa/confirm', { code });
+    setRecoveryCodes(response.data.recovery_codes);
+    setEnrollment(null);
+    setCode('');
+    await loadStatus();
+  });
+
+  const handleRegenerate = () => run(async () => {
+    const response = await axios.post<{ recovery_codes: string[] }>('/api/profile/mfa/recovery-codes', { code });
+    setRecoveryCodes(response.data.recovery_codes);
+    setCode('');
+    await loadStatus();
+  });
+
+  const handleDisable = () => run(async () => {
+    await axios.delete('/api/profile/mfa', { data: { code } });
+    setRecoveryCodes([]);
+    setCode('');
+    await loadStatus();
+  });
+
+  return (
+    <Paper sx={{ p: 3 }}>
+      <Typography variant="h6" gutterBottom>
+        Two-Factor Authentication
+      </Typography>
+      {error && <Alert severity="error" sx={{ mb: 2 }}>{error}</Alert>}
+      {recoveryCodes.length > 0 && (
+        <Alert severity="warning" sx={{ mb: 2 }}>
+          Store these recovery codes somewhere safe. Each works once, and they are not shown again.
+          <Box component="pre" sx={{ mt: 1, mb: 0, fontFamily: 'monospace' }}>{recoveryCodes.join('\n')}</Box>
+        </Alert>
+      )}
+      {status?.enabled ? (
+        <>
+          <Typography variant="body2" sx={{ mb: 1 }}>
+            Enabled. {status.recovery_codes_left} recovery codes left.
+          </Typography>
+          <TextField
+            fullWidth
+            label="Authenticator or recovery code"
+            margin="normal"
+            value={code}
+            onChange={(e) => setCode(e.target.value)}
+          />
+          <Box sx={{ mt: 2, display: 'flex', gap: 2 }}>
+            <Button variant="outlined" onClick={handleRegenerate} disabled={!code}>
+              New Recovery Codes
+            </Button>
+            <Button variant="outlined" color="error" onClick={handleDisable} disabled={!code}>
+              Disable
+            </Button>
+          </Box>
+        </>
+      ) : enrollment ? (
+        <>
+          <Typography variant="body2">
+            Scan the QR code with your authenticator app, or enter the key manually, then type the code it shows.
+          </Typography>
+          <Box sx={{ my: 2, display: 'flex', justifyContent: 'center' }}>
+            <img src={enrollment.qr_code} alt="Authenticator QR code" width={200} height={200} />
+          </Box>
+          <Typography variant="body2" sx={{ fontFamily: 'monospace', wordBreak: 'break-all' }}>
+            {enrollment.secret}
+          </Typography>
+          <TextField
+            fullWidth
+            label="Code"
+            margin="normal"
+            value={code}
+            onChange={(e) => setCode(e.target.value)}
+          />
+          <Button variant="contained" sx={{ mt: 2 }} onClick={handleConfirm} disabled={!code}>
+            Enable
+          </Button>
+        </>
+      ) : (
+        <>
+          <Typography variant="body2">
+            Require a code from an authenticator app in addition to your password.
+          </Typography>
+          <TextField
+            fullWidth
+            label="Current Password"
+            margin="normal"
+            type="password"
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+          />
+          <Button variant="contained" sx={{ mt: 2 }} onClick={handleEnroll} disabled={!password}>
+            Set Up
+          </Button>
+        </>
+      )}
+    </Paper>
+  );
+};
+
+export default TwoFactorCard;
@@ -2,22 +2,85 @@ import { useState } from 'react';
 import { Box, Button, Card, CardContent, TextField, Typography, Alert } from '@mui/material';
 import axios from 'axios';
 import { useNavigate } from 'react-router-dom';
+import { createPasskey, getPasskey } from '../passkey';
 
 const Login = () => {
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
+  const [mfaToken, setMfaToken] = useState('');
+  const [code, setCode] = useState('');
+  const [enrollmentToken, setEnrollmentToken] = useState('');
   const [error, setError] = useState('');
   const navigate = useNavigate();
 
+  // Admins who must use a passkey but have none register one to finish
+  // signing in
+  const signedIn = (data: any) => {
+    if (data.passkey_enrollment_required) {
+      setEnrollmentToken(data.enrollment_token);
+      return;
+    }
+    localStorage.setItem('token', data.token);
+    navigate('/');
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setError('');
     try {
       const response = await axios.post('/api/login', { username, password });
+      if (response.data.mfa_required) {
+        setMfaToken(response.data.mfa_token);
+        return;
+      }
+      signedIn(response.data);
+    } catch (err: any) {
+      setError(err?.response?.data?.detail || 'Login failed');
+    }
+  };
+
+  const handleCode = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+    try {
+      const response = await axios.post('/api/login/mfa', { mfa_token: mfaToken, code });
+      setMfaToken('');
+      signedIn(response.data);
+    } catch (err: any) {
+      // An expired challenge needs the password again
+      if (err?.response?.data?.code === 'invalid_token') {
+        setMfaToken('');
+        setCode('');
+      }
+      setError(err?.response?.data?.detail || 'Verification failed');
+    }
+  };
+
+  const handlePasskey = async () => {
+    setError('');
+    try {
+      const options = await axios.post('/api/login/passkey/options');
+      const credential = await getPasskey(options.data.options);
+      const response = await axios.post('/api/login/passkey', { token: options.data.token, credential });
+      localStorage.setItem('token', response.data.token);
+      navigate('/');
+    } catch (err: any) {
+      setError(err?.response?.data?.detail || 'Passkey sign-in failed');
+    }
+  };
+
+  const handleEnroll = async () => {
+    setError('');
+    try {
+      const options = await axios.post('/api/login/passkey/enroll/options', { enrollment_token: enrollmentToken });
+      const credential = await createPasskey(options.data.options);
+      const response = await axios.post('/api/login/passkey/enroll', { token: options.data.token, credential });
       localStorage.setItem('token', response.data.token);
       navigate('/');
     } catch (err: any) {
-      setError(err?.response?.data?.message || 'Login failed');
+      // The enrollment token is used up by the first attempt
+      setEnrollmentToken('');
+      setError(err?.response?.data?.detail || 'Passkey registration failed; sign in again.');
     }
   };
 
@@ -27,31 +90,65 @@ const Login = () => {
         <CardContent>
           <Typography variant="h5" sx={{ mb: 2, fontWeight: 700, color: 'primary.main' }}>Login</Typography>
           {error && <Alert severity="error" sx={{ mb: 2 }}>{error}</Alert>}
-          <form onSubmit={handleSubmit}>
-            <TextField
-              label="Username"
-              variant="outlined"
-              fullWidth
-              sx={{ mb: 2 }}
-              value={username}
-              onChange={e => setUsername(e.target.value)}
-              autoFocus
-              required
-            />
-            <TextField
-              label="Password"
-              variant="outlined"
-              type="password"
-              fullWidth
-              sx={{ mb: 3 }}
-              value={password}
-              onChange={e => setPassword(e.target.value)}
-              required
-            />
-            <Button type="submit" variant="contained" color="primary" fullWidth size="large" sx={{ fontWeight: 700 }}>
-              Login
-            </Button>
-          </form>
+          {enrollmentToken ? (
+            <>
+              <Typography variant="body2" sx={{ mb: 2 }}>
+                Administrators must sign in with a passkey. Register one on this device to finish signing in.
+              </Typography>
+              <Button variant="contained" color="primary" fullWidth size="large" sx={{ fontWeight: 700 }} onClick={handleEnroll}>
+                Register a passkey
+              </Button>
+            </>
+          ) : mfaToken ? (
+            <form onSubmit={handleCode}>
+              <Typography variant="body2" sx={{ mb: 2 }}>
+                Enter the code from your authenticator app, or one of your recovery codes.
+              </Typography>
+              <TextField
+                label="Code"
+                variant="outlined"
+                fullWidth
+                sx={{ mb: 3 }}
+                value={code}
+                onChange={e => setCode(e.target.value)}
+                inputProps={{ autoComplete: 'one-time-code' }}
+                autoFocus
+                required
+              />
+              <Button type="submit" variant="contained" color="primary" fullWidth size="large" sx={{ fontWeight: 700 }}>
+                Verify
+              </Button>
+            </form>
+          ) : (
+            <form onSubmit={handleSubmit}>
+              <TextField
+                label="Username"
+                variant="outlined"
+                fullWidth
+                sx={{ mb: 2 }}
+                value={username}
+                onChange={e => setUsername(e.target.value)}
+                autoFocus
+                required
+              />
+              <TextField
+                label="Password"
+                variant="outlined"
+                type="password"
+                fullWidth
+                sx={{ mb: 3 }}
+                value={password}
+                onChange={e => setPassword(e.target.value)}
+                required
+              />
+              <Button type="submit" variant="contained" color="primary" fullWidth size="large" sx={{ fontWeight: 700 }}>
+                Login
+              </Button>
+              <Button variant="outlined" color="primary" fullWidth size="large" sx={{ mt: 2 }} onClick={handlePasskey}>
+                Sign in with a passkey
+              </Button>
+            </form>
+          )}
         </CardContent>
       </Card>
     </Box>
@@ -12,13 +12,16 @@ import {
 } from '@mui/material';
 import { Person as PersonIcon } from '@mui/icons-material';
 import axios from 'axios';
+import TwoFactorCard from '../components/TwoFactorCard';
+import PasskeysCard from '../components/PasskeysCard';
 
 interface UserProfile {
   id: string;
   username: string;
   email: string;
-  isAdmin: boolean;
-  createdAt: string;
+  is_admin: boolean;
+  roles: string[];
+  created_at: string;
 }
 
 const Profile = () => {
@@ -39,31 +42,12 @@ const Profile = () => {
   useEffect(() => {
     const fetchProfile = async () => {
       try {
-        // In a real application, you would fetch this data from your API
-        // For now, we'll simulate it with a timeout
-        setTimeout(() => {
-          const mockProfile: UserProfile = {
-            id: '1',
-            username: 'admin',
-            email: 'admin@example.com',
-            isAdmin: true,
-            createdAt: '2023-01-01T00:00:00Z',
-          };
-          setProfile(mockProfile);
-          setEditedProfile({
-            username: mockProfile.username,
-            email: mockProfile.email,
-          });
-          setLoading(false);
-        }, 1000);
-        
-        // Example of how you would fetch from a real API:
-        // const response = await axios.get('http://localhost:8080/api/profile');
-        // setProfile(response.data);
-        // setEditedProfile({
-        //   username: response.data.username,
-        //   email: response.data.email,
-        // });
+        const response = await axios.get<UserProfile>('/api/profile');
+        setProfile(response.data);
+        setEditedProfile({
+          username: response.data.username,
+          email: response.data.email,
+        });
       } catch (error) {
         console.error('Error fetching profile:', error);
       } finally {
@@ -87,17 +71,8 @@ const Profile = () => {
 
   const handleProfileUpdate = async () => {
     try {
-      // In a real application, you would update the profile via your API
-      // await axios.put('http://localhost:8080/api/profile', editedProfile);
-      
-      // Update the local state
-      if (profile) {
-        setProfile({
-          ...profile,
-          username: editedProfile.username,
-          email: editedProfile.email,
-        });
-      }
+      const response = await axios.put<UserProfile>('/api/profile', editedProfile);
+      setProfile(response.data);
       
       setEditing(false);
       setMessage({ type: 'success', text: 'Profile updated successfully!' });
@@ -141,7 +116,7 @@ const Profile = () => {
       }, 3000);
     } catch (error: any) {
       console.error('Error changing password:', error);
-      setMessage({ type: 'error', text: error.response?.data || 'Failed to change password.' });
+      setMessage({ type: 'error', text: error.response?.data?.detail || 'Failed to change password.' });
     }
   };
 
@@ -187,14 +162,14 @@ const Profile = () => {
               <Box>
                 <Typography variant="h6">{profile.username}</Typography>
                 <Typography variant="body2" color="textSecondary">
-                  {profile.isAdmin ? 'Administrator' : 'Regular User'}
+                  {profile.is_admin ? 'Administrator' : 'Regular User'}
                 </Typography>
               </Box>
             </Box>
 
             <Box sx={{ mb: 2 }}>
               <Typography variant="body2" color="textSecondary">
-                Member since: {new Date(profile.createdAt).toLocaleDateString()}
+                Member since: {new Date(profile.created_at).toLocaleDateString()}
               </Typography>
             </Box>
 
@@ -283,6 +258,14 @@ const Profile = () => {
             </Button>
           </Paper>
         </Grid>
+
+        <Grid item xs={12} md={6}>
+          <TwoFactorCard />
+        </Grid>
+
+        <Grid item xs={12} md={6}>
+          <PasskeysCard />
+        </Grid>
       </Grid>
     </Box>
   );
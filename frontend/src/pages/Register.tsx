@@ -16,11 +16,15 @@ const Register = () => {
     setError('');
     setSuccess('');
     try {
-      await axios.post('/api/register', { username, password, email });
+      const res = await axios.post('/api/register', { username, password, email });
+      if (res.data?.verification_required) {
+        setSuccess('Registration successful! Check your inbox to verify your email address, then log in.');
+        return;
+      }
       setSuccess('Registration successful! You can now log in.');
       setTimeout(() => navigate('/login'), 1200);
     } catch (err: any) {
-      setError(err?.response?.data?.message || 'Registration failed');
+      setError(err?.response?.data?.detail || 'Registration failed');
     }
   };
 
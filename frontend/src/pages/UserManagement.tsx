@@ -93,7 +93,7 @@ const UserManagement = () => {
         
         // Example of how you would fetch from a real API:
         // const response = await axios.get('http://localhost:8080/api/users');
-        // setUsers(response.data);
+        // setUsers(response.data.users);
       } catch (error) {
         console.error('Error fetching users:', error);
       } finally {
@@ -0,0 +1,68 @@
+// Helpers for the WebAuthn ceremonies. The API sends and expects binary
+// fields as base64url strings, while the browser works with ArrayBuffers.
+
+const toBuffer = (value: string): ArrayBuffer => {
+  const base64 = value.replace(/-/g, '+').replace(/_/g, '/');
+  const binary = atob(base64.padEnd(base64.length + ((4 - (base64.length % 4)) % 4), '='));
+  return Uint8Array.from(binary, (c) => c.charCodeAt(0)).buffer;
+};
+
+const toBase64URL = (buffer: ArrayBuffer): string =>
+  btoa(String.fromCharCode(...new Uint8Array(buffer)))
+    .replace(/\+/g, '-')
+    .replace(/\//g, '_')
+    .replace(/=+$/, '');
+
+const decodeDescriptors = (descriptors?: any[]) =>
+  descriptors?.map((d) => ({ ...d, id: toBuffer(d.id) }));
+
+// createPasskey runs the options of POST /api/profile/passkeys/options or
+// POST /api/login/passkey/enroll/options and returns the credential that
+// completes the registration
+export const createPasskey = async (options: any) => {
+  const publicKey = options.publicKey;
+  const credential = (await navigator.credentials.create({
+    publicKey: {
+      ...publicKey,
+      challenge: toBuffer(publicKey.challenge),
+      user: { ...publicKey.user, id: toBuffer(publicKey.user.id) },
+      excludeCredentials: decodeDescriptors(publicKey.excludeCredentials),
+    },
+  })) as PublicKeyCredential;
+  const response = credential.response as AuthenticatorAttestationResponse;
+  return {
+    id: credential.id,
+    rawId: toBase64URL(credential.rawId),
+    type: credential.type,
+    response: {
+      clientDataJSON: toBase64URL(response.clientDataJSON),
+      attestationObject: toBase64URL(response.attestationObject),
+      transports: response.getTransports?.() ?? [],
+    },
+  };
+};
+
+// getPasskey runs the options of POST /api/login/passkey/options and returns
+// the credential for POST /api/login/passkey
+export const getPasskey = async (options: any) => {
+  const publicKey = options.publicKey;
+  const credential = (await navigator.credentials.get({
+    publicKey: {
+      ...publicKey,
+      challenge: toBuffer(publicKey.challenge),
+      allowCredentials: decodeDescriptors(publicKey.allowCredentials),
+    },
+  })) as PublicKeyCredential;
+  const response = credential.response as AuthenticatorAssertionResponse;
+  return {
+    id: credential.id,
+    rawId: toBase64URL(credential.rawId),
+    type: credential.type,
+    response: {
+      clientDataJSON: toBase64URL(response.clientDataJSON),
+      authenticatorData: toBase64URL(response.authenticatorData),
+      signature: toBase64URL(response.signature),
+      userHandle: response.userHandle ? toBase64URL(response.userHandle) : undefined,
+    },
+  };
+};
@@ -1,7 +1,7 @@
 import React from 'react';
 import { Outlet, Link, useLocation } from 'react-router-dom';
 import { useAppSelector, useAppDispatch } from '../hooks';
-import { logout } from '../features/auth/authSlice';
+import { logoutUser } from '../features/auth/authSlice';
 
 const nav = [
   { name: 'Dashboard', path: '/' },
@@ -36,7 +36,7 @@ const Layout: React.FC = () => {
         {user && (
           <button
             className="rounded px-4 py-2 bg-red-500 hover:bg-red-600 text-white font-semibold"
-            onClick={() => dispatch(logout())}
+            onClick={() => dispatch(logoutUser())}
           >
             Logout
           </button>
@@ -10,6 +10,9 @@ interface User {
 interface AuthState {
   user: User | null;
   token: string | null;
+  refreshToken: string | null;
+  // Set between the password and the code step of a two-factor sign-in
+  mfaToken: string | null;
   loading: boolean;
   error: string | null;
 }
@@ -17,10 +20,16 @@ interface AuthState {
 const initialState: AuthState = {
   user: null,
   token: null,
+  refreshToken: null,
+  mfaToken: null,
   loading: false,
   error: null,
 };
 
+// Admins who must use a passkey but have none get an enrollment token
+// instead of a session. Passkeys cannot be registered in this app yet.
+const passkeyEnrollmentError = 'Administrators must register a passkey before signing in.';
+
 export const login = createAsyncThunk(
   'auth/login',
   async (data: { username: string; password: string }, { rejectWithValue }) => {
@@ -28,30 +37,75 @@ export const login = createAsyncThunk(
       const response = await axios.post('/api/login', data);
       return response.data;
     } catch (err: any) {
-      return rejectWithValue(err.response?.data?.message || 'Login failed');
+      return rejectWithValue(err.response?.data?.detail || 'Login failed');
+    }
+  }
+);
+
+// Completes a two-factor sign-in with a code from the authenticator app or a
+// recovery code
+export const loginMFA = createAsyncThunk(
+  'auth/loginMFA',
+  async (code: string, { getState, rejectWithValue }) => {
+    const { auth } = getState() as { auth: AuthState };
+    try {
+      const response = await axios.post('/api/login/mfa', { mfa_token: auth.mfaToken, code });
+      return response.data;
+    } catch (err: any) {
+      return rejectWithValue(err.response?.data?.detail || 'Verification failed');
     }
   }
 );
 
 export const register = createAsyncThunk(
   'auth/register',
-  async (data: { username: string; password: string }, { rejectWithValue }) => {
+  async (data: { username: string; email: string; password: string }, { rejectWithValue }) => {
     try {
       const response = await axios.post('/api/register', data);
       return response.data;
     } catch (err: any) {
-      return rejectWithValue(err.response?.data?.message || 'Register failed');
+      return rejectWithValue(err.response?.data?.detail || 'Register failed');
+    }
+  }
+);
+
+// Exchanges the current refresh token for a new access/refresh token pair.
+// The server rotates refresh tokens, so the old one is unusable afterwards.
+export const refreshSession = createAsyncThunk(
+  'auth/refresh',
+  async (_, { getState, rejectWithValue }) => {
+    const { auth } = getState() as { auth: AuthState };
+    if (!auth.refreshToken) {
+      return rejectWithValue('Session expired');
+    }
+    try {
+      const response = await axios.post('/api/token/refresh', { refresh_token: auth.refreshToken });
+      return response.data;
+    } catch (err: any) {
+      return rejectWithValue(err.response?.data?.detail || 'Session expired');
     }
   }
 );
 
+// Ends the server-side session. Local state is cleared even if the request
+// fails, since the user asked to be signed out.
+export const logoutUser = createAsyncThunk('auth/logoutUser', async (_, { dispatch }) => {
+  try {
+    await axios.post('/api/logout');
+  } finally {
+    dispatch(authSlice.actions.logout());
+  }
+});
+
 const authSlice = createSlice({
   name: 'auth',
   initialState,
   reducers: {
     logout(state) {
       state.user = null;
+      state.mfaToken = null;
       state.token = null;
+      state.refreshToken = null;
       state.error = null;
     },
   },
@@ -63,25 +117,66 @@ const authSlice = createSlice({
       })
       .addCase(login.fulfilled, (state, action) => {
         state.loading = false;
+        if (action.payload.mfa_required) {
+          state.mfaToken = action.payload.mfa_token;
+          return;
+        }
+        if (action.payload.passkey_enrollment_required) {
+          state.error = passkeyEnrollmentError;
+          return;
+        }
         state.user = action.payload.user;
         state.token = action.payload.token;
+        state.refreshToken = action.payload.refresh_token;
       })
       .addCase(login.rejected, (state, action) => {
         state.loading = false;
         state.error = action.payload as string;
       })
+      .addCase(loginMFA.pending, (state) => {
+        state.loading = true;
+        state.error = null;
+      })
+      .addCase(loginMFA.fulfilled, (state, action) => {
+        state.loading = false;
+        state.mfaToken = null;
+        if (action.payload.passkey_enrollment_required) {
+          state.error = passkeyEnrollmentError;
+          return;
+        }
+        state.user = action.payload.user;
+        state.token = action.payload.token;
+        state.refreshToken = action.payload.refresh_token;
+      })
+      .addCase(loginMFA.rejected, (state, action) => {
+        state.loading = false;
+        state.error = action.payload as string;
+      })
       .addCase(register.pending, (state) => {
         state.loading = true;
         state.error = null;
       })
       .addCase(register.fulfilled, (state, action) => {
         state.loading = false;
+        // No session is started until the email address is verified
+        if (action.payload.verification_required) return;
         state.user = action.payload.user;
         state.token = action.payload.token;
+        state.refreshToken = action.payload.refresh_token;
       })
       .addCase(register.rejected, (state, action) => {
         state.loading = false;
         state.error = action.payload as string;
+      })
+      .addCase(refreshSession.fulfilled, (state, action) => {
+        state.user = action.payload.user;
+        state.token = action.payload.token;
+        state.refreshToken = action.payload.refresh_token;
+      })
+      .addCase(refreshSession.rejected, (state) => {
+        state.user = null;
+        state.token = null;
+        state.refreshToken = null;
       });
   },
 });
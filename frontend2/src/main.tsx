@@ -2,10 +2,41 @@ import React from 'react';
 import ReactDOM from 'react-dom/client';
 import { Provider } from 'react-redux';
 import { BrowserRouter } from 'react-router-dom';
+import axios from 'axios';
 import App from './App';
 import { store } from './store';
+import { refreshSession } from './features/auth/authSlice';
 import './index.css';
 
+// Send the current access token with every API request
+axios.interceptors.request.use((config) => {
+  const { token } = store.getState().auth;
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`;
+  }
+  return config;
+});
+
+// Renew the access token once when a request is rejected as unauthorized.
+// Concurrent failures share one refresh so the rotated token is redeemed once.
+let pendingRefresh: Promise<unknown> | null = null;
+axios.interceptors.response.use(undefined, async (error) => {
+  const original = error.config;
+  if (!original || error.response?.status !== 401 || original._retried || original.url === '/api/token/refresh') {
+    return Promise.reject(error);
+  }
+  original._retried = true;
+  pendingRefresh ??= store.dispatch(refreshSession()).unwrap().finally(() => {
+    pendingRefresh = null;
+  });
+  try {
+    await pendingRefresh;
+  } catch {
+    return Promise.reject(error);
+  }
+  return axios(original);
+});
+
 ReactDOM.createRoot(document.getElementById('root')!).render(
   <React.StrictMode>
     <Provider store={store}>
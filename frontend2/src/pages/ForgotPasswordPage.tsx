@@ -0,0 +1,61 @@
+import React, { useState } from 'react';
+import { Link } from 'react-router-dom';
+import axios from 'axios';
+
+const ForgotPasswordPage: React.FC = () => {
+  const [email, setEmail] = useState('');
+  const [sent, setSent] = useState(false);
+  const [error, setError] = useState('');
+  const [loading, setLoading] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+    setLoading(true);
+    try {
+      await axios.post('/api/password/forgot', { email });
+      setSent(true);
+    } catch (err: any) {
+      setError(err.response?.data?.detail || 'Could not send a reset link');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="flex items-center justify-center min-h-screen bg-slate-900">
+      <form onSubmit={handleSubmit} className="bg-slate-800 rounded-xl shadow-lg p-8 w-full max-w-md flex flex-col gap-6">
+        <h2 className="text-2xl font-bold text-blue-400 mb-2">Forgot password</h2>
+        {sent ? (
+          <div className="text-green-400 font-medium">
+            If the address belongs to an account, a link to reset the password is on its way.
+          </div>
+        ) : (
+          <>
+            <input
+              className="rounded px-4 py-2 bg-slate-700 text-slate-100 border border-slate-600 focus:outline-none focus:ring-2 focus:ring-blue-400"
+              type="email"
+              placeholder="Email"
+              value={email}
+              onChange={e => setEmail(e.target.value)}
+              required
+            />
+            {error && <div className="text-red-400 font-medium">{error}</div>}
+            <button
+              type="submit"
+              className="rounded bg-blue-600 hover:bg-blue-700 text-white font-semibold py-2 mt-2 transition-colors"
+              disabled={loading}
+            >
+              {loading ? 'Sending...' : 'Send reset link'}
+            </button>
+          </>
+        )}
+        <div className="text-sm text-slate-300 mt-2">
+          Remembered it? <Link className="text-blue-400 hover:underline" to="/login">Login</Link>
+        </div>
+      </form>
+    </div>
+  );
+};
+
+export default ForgotPasswordPage;
@@ -7,14 +7,20 @@ const RegisterPage: React.FC = () => {
   const dispatch = useAppDispatch();
   const { loading, error } = useAppSelector((state) => state.auth);
   const [username, setUsername] = useState('');
+  const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
+  const [verifySent, setVerifySent] = useState(false);
   const navigate = useNavigate();
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
-    const result = await dispatch(register({ username, password }));
+    const result = await dispatch(register({ username, email, password }));
     if (register.fulfilled.match(result)) {
-      navigate('/');
+      if (result.payload.verification_required) {
+        setVerifySent(true);
+      } else {
+        navigate('/');
+      }
     }
   };
 
@@ -30,6 +36,14 @@ const RegisterPage: React.FC = () => {
           onChange={e => setUsername(e.target.value)}
           required
         />
+        <input
+          className="rounded px-4 py-2 bg-slate-700 text-slate-100 border border-slate-600 focus:outline-none focus:ring-2 focus:ring-blue-400"
+          type="email"
+          placeholder="Email"
+          value={email}
+          onChange={e => setEmail(e.target.value)}
+          required
+        />
         <input
           className="rounded px-4 py-2 bg-slate-700 text-slate-100 border border-slate-600 focus:outline-none focus:ring-2 focus:ring-blue-400"
           type="password"
@@ -39,6 +53,7 @@ const RegisterPage: React.FC = () => {
           required
         />
         {error && <div className="text-red-400 font-medium">{error}</div>}
+        {verifySent && <div className="text-green-400 font-medium">Check your inbox for a link to verify your email address, then log in.</div>}
         <button
           type="submit"
           className="rounded bg-blue-600 hover:bg-blue-700 text-white font-semibold py-2 mt-2 transition-colors"
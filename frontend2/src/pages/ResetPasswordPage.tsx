@@ -0,0 +1,76 @@
+import React, { useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import axios from 'axios';
+
+// Landing page of the link in password reset emails
+const ResetPasswordPage: React.FC = () => {
+  const [params] = useSearchParams();
+  const token = params.get('token') || '';
+  const [password, setPassword] = useState('');
+  const [confirm, setConfirm] = useState('');
+  const [done, setDone] = useState(false);
+  const [error, setError] = useState(token ? '' : 'The reset link is missing its token.');
+  const [loading, setLoading] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (password !== confirm) {
+      setError('The passwords do not match.');
+      return;
+    }
+    setError('');
+    setLoading(true);
+    try {
+      await axios.post('/api/password/reset', { token, password });
+      setDone(true);
+    } catch (err: any) {
+      setError(err.response?.data?.errors?.[0]?.message || err.response?.data?.detail || 'Password reset failed');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="flex items-center justify-center min-h-screen bg-slate-900">
+      <form onSubmit={handleSubmit} className="bg-slate-800 rounded-xl shadow-lg p-8 w-full max-w-md flex flex-col gap-6">
+        <h2 className="text-2xl font-bold text-blue-400 mb-2">Reset password</h2>
+        {done ? (
+          <div className="text-green-400 font-medium">
+            Your password has been changed and every session was signed out.{' '}
+            <Link className="text-blue-400 hover:underline" to="/login">Login</Link>
+          </div>
+        ) : (
+          <>
+            <input
+              className="rounded px-4 py-2 bg-slate-700 text-slate-100 border border-slate-600 focus:outline-none focus:ring-2 focus:ring-blue-400"
+              type="password"
+              placeholder="New password"
+              value={password}
+              onChange={e => setPassword(e.target.value)}
+              minLength={8}
+              required
+            />
+            <input
+              className="rounded px-4 py-2 bg-slate-700 text-slate-100 border border-slate-600 focus:outline-none focus:ring-2 focus:ring-blue-400"
+              type="password"
+              placeholder="Confirm new password"
+              value={confirm}
+              onChange={e => setConfirm(e.target.value)}
+              required
+            />
+            {error && <div className="text-red-400 font-medium">{error}</div>}
+            <button
+              type="submit"
+              className="rounded bg-blue-600 hover:bg-blue-700 text-white font-semibold py-2 mt-2 transition-colors"
+              disabled={loading || !token}
+            >
+              {loading ? 'Saving...' : 'Set new password'}
+            </button>
+          </>
+        )}
+      </form>
+    </div>
+  );
+};
+
+export default ResetPasswordPage;
@@ -0,0 +1,77 @@
+import React, { useEffect, useRef, useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import axios from 'axios';
+
+// Landing page of the link in verification emails. It redeems the token
+// once and offers to resend the link when it has expired.
+const VerifyEmailPage: React.FC = () => {
+  const [params] = useSearchParams();
+  const token = params.get('token') || '';
+  const [status, setStatus] = useState<'verifying' | 'verified' | 'failed'>(token ? 'verifying' : 'failed');
+  const [error, setError] = useState(token ? '' : 'The verification link is missing its token.');
+  const [email, setEmail] = useState('');
+  const [resent, setResent] = useState(false);
+  const started = useRef(false);
+
+  useEffect(() => {
+    if (!token || started.current) return;
+    started.current = true;
+    axios.post('/api/email/verify', { token })
+      .then(() => setStatus('verified'))
+      .catch((err: any) => {
+        setStatus('failed');
+        setError(err.response?.data?.errors?.[0]?.message || err.response?.data?.detail || 'Verification failed');
+      });
+  }, [token]);
+
+  const handleResend = async (e: React.FormEvent) => {
+    e.preventDefault();
+    try {
+      await axios.post('/api/email/resend', { email });
+      setResent(true);
+    } catch (err: any) {
+      setError(err.response?.data?.detail || 'Could not send a new link');
+    }
+  };
+
+  return (
+    <div className="flex items-center justify-center min-h-screen bg-slate-900">
+      <div className="bg-slate-800 rounded-xl shadow-lg p-8 w-full max-w-md flex flex-col gap-6">
+        <h2 className="text-2xl font-bold text-blue-400 mb-2">Verify email</h2>
+        {status === 'verifying' && <div className="text-slate-300">Verifying your email address...</div>}
+        {status === 'verified' && (
+          <div className="text-green-400 font-medium">
+            Your email address is verified. <Link className="text-blue-400 hover:underline" to="/login">Login</Link>
+          </div>
+        )}
+        {status === 'failed' && (
+          <>
+            <div className="text-red-400 font-medium">{error}</div>
+            {resent ? (
+              <div className="text-slate-300">If the address belongs to an unverified account, a new link is on its way.</div>
+            ) : (
+              <form onSubmit={handleResend} className="flex flex-col gap-4">
+                <input
+                  className="rounded px-4 py-2 bg-slate-700 text-slate-100 border border-slate-600 focus:outline-none focus:ring-2 focus:ring-blue-400"
+                  type="email"
+                  placeholder="Email"
+                  value={email}
+                  onChange={e => setEmail(e.target.value)}
+                  required
+                />
+                <button
+                  type="submit"
+                  className="rounded bg-blue-600 hover:bg-blue-700 text-white font-semibold py-2 transition-colors"
+                >
+                  Send a new link
+                </button>
+              </form>
+            )}
+          </>
+        )}
+      </div>
+    </div>
+  );
+};
+
+export default VerifyEmailPage;